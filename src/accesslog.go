@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"net/http"
+	"time"
+)
+
+// AccessLogMiddleware logs one line per request to the logger. Successful requests are sampled,
+// only 1 in sampleRate is logged, while error responses are always logged. A sample rate of 0 or
+// 1 logs every request.
+func AccessLogMiddleware(logger zerolog.Logger, sampleRate uint32) gin.HandlerFunc {
+	sampled := logger
+	if sampleRate > 1 {
+		sampled = logger.Sample(&zerolog.BasicSampler{N: sampleRate})
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		event := sampled.Info()
+		if status >= http.StatusBadRequest {
+			event = logger.Warn()
+		}
+		event.Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Str("clientIP", c.ClientIP()).
+			Msg("request")
+	}
+}
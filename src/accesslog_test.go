@@ -0,0 +1,34 @@
+package main_test
+
+import (
+	"bytes"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogSampling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var output bytes.Buffer
+	router := gin.New()
+	router.Use(src.AccessLogMiddleware(zerolog.New(&output), 10))
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	for i := 0; i < 100; i++ {
+		performRequest(router, "/ok")
+	}
+	if lines := strings.Count(output.String(), "\n"); lines != 10 {
+		t.Errorf("Expected 10 of 100 successful requests to be logged, but got %d", lines)
+	}
+	output.Reset()
+	for i := 0; i < 20; i++ {
+		performRequest(router, "/fail")
+	}
+	if lines := strings.Count(output.String(), `"status":500`); lines != 20 {
+		t.Errorf("Expected every failed request to be logged, but got %d", lines)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const AdminAPIKeyHeader = "X-API-Key"
+
+// AdminAuthMiddleware rejects requests that do not carry the api key in the X-API-Key header.
+func AdminAuthMiddleware(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given := c.GetHeader(AdminAPIKeyHeader)
+		if apiKey == "" || subtle.ConstantTimeCompare([]byte(given), []byte(apiKey)) != 1 {
+			abortWithError(c, http.StatusUnauthorized, "Invalid api key")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RegisterAdminRoutes adds the operator routes under /admin, guarded by the api key. The server
+// configuration and the clients of the networks are only reported by /admin/config.
+func RegisterAdminRoutes(router gin.IRouter, client *Web3Client, networks map[string]*Web3Client, server ServerConfig, apiKey string) {
+	admin := router.Group("/admin", AdminAuthMiddleware(apiKey))
+	admin.GET("/cache/stats", GetCacheStatsHandler(client))
+	admin.POST("/cache/flush", FlushCacheHandler(client))
+	admin.GET("/config", GetConfigHandler(client, networks, server))
+}
+
+// AdminConfig is the configuration reported to operators: the server, the client of the root
+// routes and the client of each network by name. The admin api key is only reported as set, never
+// in clear.
+type AdminConfig struct {
+	AdminAPIKey string                  `json:"adminApiKey"`
+	Server      ServerConfig            `json:"server"`
+	Client      ClientConfig            `json:"client"`
+	Networks    map[string]ClientConfig `json:"networks"`
+}
+
+// GetConfigHandler returns the effective configuration of the server and the clients with their
+// secrets redacted, so operators can verify how the env variables were parsed.
+func GetConfigHandler(client *Web3Client, networks map[string]*Web3Client, server ServerConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		config := AdminConfig{
+			AdminAPIKey: Redacted,
+			Server:      server.redacted(),
+			Client:      client.Config(),
+			Networks:    make(map[string]ClientConfig, len(networks)),
+		}
+		for name, networkClient := range networks {
+			config.Networks[name] = networkClient.Config()
+		}
+		c.JSON(http.StatusOK, config)
+	}
+}
+
+func GetCacheStatsHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, client.CacheStats(c.Request.Context()))
+	}
+}
+
+func FlushCacheHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := client.FlushCache(c.Request.Context()); err != nil {
+			if errors.Is(err, ErrCacheNotFlushable) {
+				respondError(c, http.StatusNotImplemented, err.Error())
+				return
+			}
+			c.JSON(http.StatusInternalServerError, nil)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
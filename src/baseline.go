@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+// DefaultBaselineWindow is the number of slots before a slot its reward is compared to, an epoch.
+const DefaultBaselineWindow = 32
+
+// MinBaselineBlocks is the fewest blocks a baseline window must hold for a median to be reported.
+const MinBaselineBlocks = 3
+
+// RewardBaseline compares the reward of a slot to the rewards of the blocks of the Window slots
+// before it. DeltaFromMedian is the reward minus the median in gwei, Percentile the share of the
+// baseline rewards below the reward, ties counting half. Median, delta and percentile are null
+// when fewer than MinBaselineBlocks blocks make up the baseline.
+type RewardBaseline struct {
+	Window          int     `json:"window"`
+	BlockCount      int     `json:"blockCount"`
+	Median          *string `json:"median"`
+	DeltaFromMedian *string `json:"deltaFromMedian"`
+	Percentile      *string `json:"percentile"`
+}
+
+// GetRewardBaseline computes the rewards of the window slots before the slot like a slot range and
+// compares reward to their median. The window is cut at the merge slot, slots without a block are
+// skipped.
+func (c *Web3Client) GetRewardBaseline(ctx context.Context, slotId string, reward *big.Int, window int) (*RewardBaseline, error) {
+	if window < 1 || window > MaxSlotRange {
+		return nil, &InvalidSlotError{msg: fmt.Sprintf("Window must be between 1 and %d", MaxSlotRange)}
+	}
+	slot, err := c.validateRewardSlot(slotId)
+	if err != nil {
+		return nil, err
+	}
+	baseline := &RewardBaseline{Window: window}
+	if slot.Uint64() <= c.mergeSlot {
+		return baseline, nil
+	}
+	to := slot.Uint64() - 1
+	from := max(to-min(uint64(window), to)+1, c.mergeSlot)
+	rewards := make([]*big.Int, to-from+1)
+	err = c.forEachSlot(ctx, from, to, func(ctx context.Context, offset uint64, slotId string) error {
+		reward, _, err := c.GetBlockRewardWei(ctx, slotId)
+		if err != nil {
+			return err
+		}
+		rewards[offset] = reward
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	rewards = slices.DeleteFunc(rewards, func(reward *big.Int) bool { return reward == nil })
+	baseline.BlockCount = len(rewards)
+	if len(rewards) < MinBaselineBlocks {
+		return baseline, nil
+	}
+	slices.SortFunc(rewards, func(a, b *big.Int) int { return a.Cmp(b) })
+	middle := len(rewards) / 2
+	median := new(big.Rat).SetInt(rewards[middle])
+	if len(rewards)%2 == 0 {
+		median.Add(median, new(big.Rat).SetInt(rewards[middle-1])).Quo(median, big.NewRat(2, 1))
+	}
+	medianAsText := new(big.Rat).Quo(median, new(big.Rat).SetInt(GWEI)).FloatString(9)
+	delta := new(big.Rat).Sub(new(big.Rat).SetInt(reward), median)
+	deltaAsText := delta.Quo(delta, new(big.Rat).SetInt(GWEI)).FloatString(9)
+	// ties count half, so a reward equal to all of the baseline sits at the 50th percentile
+	var below, equal int64
+	for _, baselineReward := range rewards {
+		switch baselineReward.Cmp(reward) {
+		case -1:
+			below++
+		case 0:
+			equal++
+		}
+	}
+	percentile := big.NewRat(200*below+100*equal, 2*int64(len(rewards))).FloatString(2)
+	baseline.Median, baseline.DeltaFromMedian, baseline.Percentile = &medianAsText, &deltaAsText, &percentile
+	return baseline, nil
+}
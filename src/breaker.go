@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const DefaultCircuitThreshold = 5
+const DefaultCircuitCooldown = 30 * time.Second
+
+// Categories of upstream requests with a circuit breaker each, so a struggling endpoint only
+// fails fast the requests of its own category.
+const (
+	CircuitBlocks     = "blocks"
+	CircuitValidators = "validators"
+	CircuitHeaders    = "headers"
+)
+
+var ErrCircuitOpen = errors.New("upstream circuit is open")
+
+// CircuitOpenError is returned without contacting the upstream while the circuit of the request
+// category is open.
+type CircuitOpenError struct {
+	msg string
+}
+
+func (e *CircuitOpenError) Error() string {
+	return e.msg
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// circuitCategory returns the circuit breaker category of a request kind. Sync committees are
+// state queries as heavy as validator lookups and share their category. Kinds without a category
+// are never cut off.
+func circuitCategory(kind string) (string, bool) {
+	switch kind {
+	case RequestKindBlocks:
+		return CircuitBlocks, true
+	case RequestKindValidators, RequestKindSyncCommittees:
+		return CircuitValidators, true
+	case RequestKindHeaders:
+		return CircuitHeaders, true
+	}
+	return "", false
+}
+
+// circuitBreaker opens after threshold consecutive failures and rejects requests for the cooldown.
+// After the cooldown a single trial request is let through: its success closes the circuit, its
+// failure opens it for another cooldown.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// allow reports whether a request may be sent at now.
+func (b *circuitBreaker) allow(threshold int, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// skip ends a request whose outcome says nothing about the upstream, letting the next trial
+// through without touching the failures.
+func (b *circuitBreaker) skip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// record counts the outcome of a request that finished at now and reports whether it opened the
+// circuit.
+func (b *circuitBreaker) record(failed bool, threshold int, cooldown time.Duration, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if !failed {
+		b.failures = 0
+		return false
+	}
+	b.failures++
+	if b.failures < threshold {
+		return false
+	}
+	b.openUntil = now.Add(cooldown)
+	return true
+}
+
+// CircuitBreakers keeps one circuit breaker per request category.
+type CircuitBreakers struct {
+	threshold  int
+	cooldown   time.Duration
+	categories map[string]*circuitBreaker
+}
+
+// NewCircuitBreakers creates the breakers of every category. A threshold of 0 or less disables
+// them.
+func NewCircuitBreakers(threshold int, cooldown time.Duration) *CircuitBreakers {
+	return &CircuitBreakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		categories: map[string]*circuitBreaker{
+			CircuitBlocks:     {},
+			CircuitValidators: {},
+			CircuitHeaders:    {},
+		},
+	}
+}
+
+// isUpstreamFailure reports whether the outcome of a request hints at a struggling upstream:
+// transport errors other than a cancelled request, server errors and rate limiting.
+func isUpstreamFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+type circuitBreakerTransport struct {
+	breakers  *CircuitBreakers
+	clock     Clock
+	transport http.RoundTripper
+}
+
+// RoundTrip fails fast while the circuit of the request category is open and records the outcome
+// of the requests it lets through. Requests that gave up waiting on the local rate limiter never
+// reached the upstream and are not recorded, local load must not open the circuit of a healthy
+// node.
+func (cbt *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	category, ok := circuitCategory(requestKind(req))
+	if !ok || cbt.breakers.threshold <= 0 {
+		return cbt.transport.RoundTrip(req)
+	}
+	breaker := cbt.breakers.categories[category]
+	if !breaker.allow(cbt.breakers.threshold, cbt.clock.Now()) {
+		return nil, &CircuitOpenError{msg: "Upstream " + category + " requests are failing, try again later"}
+	}
+	resp, err := cbt.transport.RoundTrip(req)
+	var waitErr *limiterWaitError
+	if errors.As(err, &waitErr) {
+		breaker.skip()
+		return resp, err
+	}
+	if breaker.record(isUpstreamFailure(resp, err), cbt.breakers.threshold, cbt.breakers.cooldown, cbt.clock.Now()) {
+		log.Warn().Str("category", category).Dur("cooldown", cbt.breakers.cooldown).Msg("upstream circuit opened")
+	}
+	return resp, err
+}
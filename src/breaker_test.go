@@ -0,0 +1,99 @@
+package main_test
+
+import (
+	"context"
+	"errors"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTripsOnlyTheFailingCategory(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var committeesFailing atomic.Bool
+	var committeeRequests atomic.Int32
+	committeesFailing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, "/sync_committees") {
+			committeeRequests.Add(1)
+			if committeesFailing.Load() {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = rw.Write([]byte(`{"data":{"validators":[]}}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithCircuitBreaker(2, 100*time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetSyncCommitteeDuties(context.Background(), "4700013"); err == nil || errors.Is(err, src.ErrCircuitOpen) {
+			t.Fatalf("Expected the upstream failure, but got %v", err)
+		}
+	}
+	_, err := client.GetSyncCommitteeDuties(context.Background(), "4700013")
+	if !errors.Is(err, src.ErrCircuitOpen) || committeeRequests.Load() != 2 {
+		t.Errorf("Expected the open circuit to fail fast after 2 requests, but got %v after %d", err, committeeRequests.Load())
+	}
+	if _, _, err := client.GetBlockRewardWei(context.Background(), "4700013"); err != nil {
+		t.Errorf("Expected block rewards to stay available, but got %v", err)
+	}
+
+	committeesFailing.Store(false)
+	time.Sleep(150 * time.Millisecond)
+	if _, err := client.GetSyncCommitteeDuties(context.Background(), "4700013"); err != nil {
+		t.Errorf("Expected the trial request after the cooldown to close the circuit, but got %v", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresRateLimiterWaits(t *testing.T) {
+	server := setupServer("graffiti")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 10, src.WithCircuitBreaker(1, time.Hour))
+	ctx := src.WithCacheBypass(context.Background())
+
+	if _, err := client.GetGraffitiBySlot(ctx, "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	// the limiter is saturated, these requests give up before reaching the upstream
+	for i := 0; i < 3; i++ {
+		waitCtx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		if _, err := client.GetGraffitiBySlot(waitCtx, "4700013"); err == nil || errors.Is(err, src.ErrCircuitOpen) {
+			t.Fatalf("Expected the wait on the limiter to fail, but got %v", err)
+		}
+		cancel()
+	}
+	if _, err := client.GetGraffitiBySlot(ctx, "4700013"); err != nil {
+		t.Errorf("Expected the circuit of the healthy upstream to stay closed, but got %v", err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithCircuitBreaker(0, time.Hour))
+
+	for i := 0; i < 10; i++ {
+		if _, err := client.GetSyncCommitteeDuties(context.Background(), "4700013"); errors.Is(err, src.ErrCircuitOpen) {
+			t.Fatalf("Expected no circuit, but got %v", err)
+		}
+	}
+	if requests.Load() != 10 {
+		t.Errorf("Expected every request to reach the upstream, but got %d", requests.Load())
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+	"strings"
+)
+
+const BuilderSourceLocal = "local"
+const BuilderSourceMevBoost = "mev-boost"
+const BuilderSourceUnknown = "unknown"
+
+// knownBuilders are fee recipients used by large block builders.
+var knownBuilders = map[common.Address]string{
+	common.HexToAddress("0x95222290DD7278Aa3Ddd389Cc1E1d165CC4BAfe5"): "beaverbuild",
+	common.HexToAddress("0x4838B106FCe9647Bdf1E7877BF73cE8B0BAD5f97"): "titan",
+	common.HexToAddress("0x1f9090aaE28b8a3dCeaDf281B0F12828e676c326"): "rsync",
+	common.HexToAddress("0xDAFEA492D9c6733ae3d56b7Ed1ADB60692c98Bc5"): "flashbots",
+}
+
+// builderMarkers appear in the extra data that builders stamp into their blocks, or in graffiti
+// of proposers that advertise their relay setup.
+var builderMarkers = []string{"builder", "beaverbuild", "titan", "rsync", "flashbots", "bloxroute", "mev"}
+
+// localMarkers appear in the default extra data of execution clients building blocks themselves.
+var localMarkers = []string{"geth", "nethermind", "erigon", "besu", "reth"}
+
+// BlockBuilder is the best-effort guess of who built the block of a slot. Builder is the
+// address of the builder when the block was bought from one.
+type BlockBuilder struct {
+	Source  string          `json:"source"`
+	Builder *common.Address `json:"builder"`
+}
+
+func containsMarker(text string, markers []string) bool {
+	text = strings.ToLower(text)
+	for _, marker := range markers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// paysProposer reports whether the last transaction of the block is sent by the fee recipient of
+// the block to another address, which is how builders pay the proposer.
+func (c *Web3Client) paysProposer(ctx context.Context, block *types.Block, blockHash common.Hash) bool {
+	transactions := block.Transactions()
+	if len(transactions) == 0 {
+		return false
+	}
+	last := transactions[len(transactions)-1]
+	if last.To() == nil || *last.To() == block.Coinbase() {
+		return false
+	}
+	sender, err := c.w3Client.TransactionSender(withUpstreamRequestName(ctx, "eth_getTransactionByBlockHashAndIndex"), last, blockHash, uint(len(transactions)-1))
+	if err != nil {
+		log.Info().Err(err).Str("txHash", last.Hash().Hex()).Msg("can not get transaction sender")
+		return false
+	}
+	return sender == block.Coinbase()
+}
+
+// GetBlockBuilder guesses whether the block of the slot was built by the proposer's own execution
+// client or bought from a builder through mev-boost. A block counts as built by a builder when it
+// ends with a payment from the fee recipient, when the fee recipient is a known builder or when
+// the extra data or graffiti carries a builder marker. It counts as local when the extra data
+// carries the default marker of an execution client. Everything else is unknown.
+func (c *Web3Client) GetBlockBuilder(ctx context.Context, slotId string) (*BlockBuilder, error) {
+	if _, err := c.validateRewardSlot(slotId); err != nil {
+		return nil, err
+	}
+	blockDetail, err := c.getCachedBlockDetail(ctx, slotId)
+	if err != nil {
+		return nil, err
+	}
+	blockHash := common.HexToHash(blockDetail.Data.Message.Body.ExecutionPayload.BlockHash)
+	block, err := c.blockByHash(ctx, blockHash)
+	if err != nil {
+		log.Info().Err(err).Msg("can not get block by hash")
+		return nil, err
+	}
+	coinbase := block.Coinbase()
+	extraData := string(block.Extra())
+	graffiti := decodeGraffiti(blockDetail.Data.Message.Body.Graffiti)
+	_, isKnownBuilder := knownBuilders[coinbase]
+	switch {
+	case isKnownBuilder || c.paysProposer(ctx, block, blockHash) ||
+		containsMarker(extraData, builderMarkers) || containsMarker(graffiti, builderMarkers):
+		return &BlockBuilder{Source: BuilderSourceMevBoost, Builder: &coinbase}, nil
+	case containsMarker(extraData, localMarkers):
+		return &BlockBuilder{Source: BuilderSourceLocal}, nil
+	}
+	return &BlockBuilder{Source: BuilderSourceUnknown}, nil
+}
@@ -0,0 +1,69 @@
+package main_test
+
+import (
+	"context"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGetBlockBuilder(t *testing.T) {
+	tests := []struct {
+		testKey string
+		source  string
+		builder string
+	}{
+		{"builtByRelay", src.BuilderSourceMevBoost, "0x00000000000000000000000000000000000000b1"},
+		{"builtLocally", src.BuilderSourceLocal, ""},
+		{"vanilla", src.BuilderSourceUnknown, ""},
+	}
+	for _, tt := range tests {
+		server := setupServer(tt.testKey)
+		parsedUrl, _ := url.Parse(server.URL)
+		client := src.NewWeb3Client(parsedUrl, 1000)
+		builder, err := client.GetBlockBuilder(context.Background(), "4700013")
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.testKey, err)
+		}
+		if builder.Source != tt.source {
+			t.Errorf("%s: expected source %s, but got %s", tt.testKey, tt.source, builder.Source)
+		}
+		if tt.builder == "" && builder.Builder != nil {
+			t.Errorf("%s: expected no builder, but got %s", tt.testKey, builder.Builder.Hex())
+		}
+		if tt.builder != "" && (builder.Builder == nil || *builder.Builder != common.HexToAddress(tt.builder)) {
+			t.Errorf("%s: expected builder %s, but got %v", tt.testKey, tt.builder, builder.Builder)
+		}
+	}
+}
+
+func TestGetBlockBuilderHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		testKey    string
+		slotId     string
+		statusCode int
+		body       string
+	}{
+		{"builtByRelay", "4700013", http.StatusOK, `{"source":"mev-boost","builder":"0x00000000000000000000000000000000000000b1"}`},
+		{"builtLocally", "4700013", http.StatusOK, `{"source":"local","builder":null}`},
+		{"builtLocally", "4700012", http.StatusNotFound, `{"error":"Slot is before the merge slot 4700013"}`},
+	}
+	for _, tt := range tests {
+		server := setupServer(tt.testKey)
+		parsedUrl, _ := url.Parse(server.URL)
+		router := gin.New()
+		router.GET("/slot/:slotId/builder", src.GetBlockBuilderHandler(src.NewWeb3Client(parsedUrl, 1000)))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slot/"+tt.slotId+"/builder", nil))
+		server.Close()
+		if recorder.Code != tt.statusCode || recorder.Body.String() != tt.body {
+			t.Errorf("%s: expected %d %s, but got %d %s", tt.testKey, tt.statusCode, tt.body, recorder.Code, recorder.Body.String())
+		}
+	}
+}
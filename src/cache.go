@@ -0,0 +1,304 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultCacheTTL = 10 * time.Minute
+const DefaultFinalizedCacheTTL = 0 // finalized slots never change, the cache capacity bounds them
+const DefaultJustifiedCacheTTL = time.Hour
+const DefaultRecentCacheTTL = time.Minute
+const DefaultCacheMaxEntries = 100000
+const CacheSweepInterval = time.Minute
+
+// CacheTTLs are the lifetimes of cached rewards by the finality of their slot. Finalized slots
+// can not be reorged, justified slots only with slashable votes, and recent slots may still be
+// orphaned. A ttl of zero means no expiry and a negative ttl leaves the tier uncached.
+type CacheTTLs struct {
+	Finalized time.Duration
+	Justified time.Duration
+	Recent    time.Duration
+}
+
+// DefaultCacheTTLs keeps finalized rewards until they are evicted, justified ones for an hour and
+// recent ones for a minute.
+func DefaultCacheTTLs() CacheTTLs {
+	return CacheTTLs{
+		Finalized: DefaultFinalizedCacheTTL,
+		Justified: DefaultJustifiedCacheTTL,
+		Recent:    DefaultRecentCacheTTL,
+	}
+}
+
+// forStatus returns the ttl of the tier of the finality status.
+func (t CacheTTLs) forStatus(finalityStatus string) time.Duration {
+	switch finalityStatus {
+	case FinalityFinalized:
+		return t.Finalized
+	case FinalitySafe:
+		return t.Justified
+	}
+	return t.Recent
+}
+
+// Cache stores serialized values by key. Values are kept as bytes so that shared backends
+// like Redis can implement it next to the in-memory default. A ttl of zero means no expiry.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// SizedCache is implemented by caches that can report how many entries they hold.
+type SizedCache interface {
+	Len(ctx context.Context) int
+}
+
+// FlushableCache is implemented by caches that can drop all their entries.
+type FlushableCache interface {
+	Flush(ctx context.Context)
+}
+
+// CacheStats describes the cache usage of the client. Entries is -1 when the cache can not
+// report its size.
+type CacheStats struct {
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+var ErrCacheNotFlushable = errors.New("cache does not support flushing")
+
+// CacheNames are the caches whose reads are counted apart, named after the prefix of their keys.
+var CacheNames = [...]string{"block", "reward", "committee"}
+
+// cacheReads counts the hits and misses of the reads of one cache.
+type cacheReads struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass returns a context under which cached values are ignored. Fresh results are
+// still written to the cache.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// Sources of a response, reported in the X-Cache header as HIT and MISS.
+const (
+	SourceCache = "cache"
+	SourceLive  = "live"
+)
+
+type cacheOutcomeKey struct{}
+
+// cacheOutcome records whether the values a request looked up in the cache were served from it.
+type cacheOutcome struct {
+	mu       sync.Mutex
+	recorded bool
+	hit      bool
+}
+
+// withCacheOutcome returns a context in which cached lookups record their outcome into the
+// returned cacheOutcome.
+func withCacheOutcome(ctx context.Context) (context.Context, *cacheOutcome) {
+	outcome := &cacheOutcome{}
+	return context.WithValue(ctx, cacheOutcomeKey{}, outcome), outcome
+}
+
+// recordCacheOutcome records whether a value was served from the cache. A request is only a hit
+// when all its lookups were.
+func recordCacheOutcome(ctx context.Context, hit bool) {
+	if outcome, ok := ctx.Value(cacheOutcomeKey{}).(*cacheOutcome); ok {
+		outcome.mu.Lock()
+		defer outcome.mu.Unlock()
+		outcome.hit = hit && (outcome.hit || !outcome.recorded)
+		outcome.recorded = true
+	}
+}
+
+// source returns SourceCache or SourceLive, false when nothing was looked up.
+func (o *cacheOutcome) source() (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.recorded {
+		return "", false
+	}
+	if o.hit {
+		return SourceCache, true
+	}
+	return SourceLive, true
+}
+
+type memoryCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryCache keeps at most maxEntries values in memory, the least recently used one is dropped
+// to make room for a new one. Expired entries are dropped when read, and all of them once every
+// CacheSweepInterval by the clock on the next write, so entries that are never read again do not
+// pile up until the limit.
+type MemoryCache struct {
+	mu         sync.Mutex
+	clock      Clock
+	maxEntries int
+	entries    map[string]*list.Element
+	recency    *list.List
+	lastSweep  time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return NewMemoryCacheWithClock(SystemClock)
+}
+
+// NewMemoryCacheWithClock returns a memory cache of DefaultCacheMaxEntries whose entries expire by
+// the clock.
+func NewMemoryCacheWithClock(clock Clock) *MemoryCache {
+	return NewMemoryCacheWithLimit(DefaultCacheMaxEntries, clock)
+}
+
+// NewMemoryCacheWithLimit returns a memory cache holding at most maxEntries values, at least one.
+func NewMemoryCacheWithLimit(maxEntries int, clock Clock) *MemoryCache {
+	return &MemoryCache{
+		clock:      clock,
+		maxEntries: max(maxEntries, 1),
+		entries:    make(map[string]*list.Element),
+		recency:    list.New(),
+		lastSweep:  clock.Now(),
+	}
+}
+
+func (m *MemoryCache) Get(_ context.Context, key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if entry.expired(m.clock.Now()) {
+		m.remove(element)
+		return nil, false
+	}
+	m.recency.MoveToFront(element)
+	return entry.value, true
+}
+
+func (m *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	if now.Sub(m.lastSweep) >= CacheSweepInterval {
+		m.sweep(now)
+	}
+	entry := &memoryCacheEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+	if element, ok := m.entries[key]; ok {
+		element.Value = entry
+		m.recency.MoveToFront(element)
+		return
+	}
+	for m.recency.Len() >= m.maxEntries {
+		m.remove(m.recency.Back())
+	}
+	m.entries[key] = m.recency.PushFront(entry)
+}
+
+func (e *memoryCacheEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+func (m *MemoryCache) remove(element *list.Element) {
+	m.recency.Remove(element)
+	delete(m.entries, element.Value.(*memoryCacheEntry).key)
+}
+
+// sweep drops all expired entries.
+func (m *MemoryCache) sweep(now time.Time) {
+	for element := m.recency.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(*memoryCacheEntry).expired(now) {
+			m.remove(element)
+		}
+		element = next
+	}
+	m.lastSweep = now
+}
+
+// Len returns the number of entries, including expired ones that were not swept yet.
+func (m *MemoryCache) Len(_ context.Context) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.recency.Len()
+}
+
+func (m *MemoryCache) Flush(_ context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = make(map[string]*list.Element)
+	m.recency.Init()
+}
+
+// CacheStats returns the number of cached entries and the hits and misses of cache reads since
+// the client was created.
+func (c *Web3Client) CacheStats(ctx context.Context) CacheStats {
+	stats := CacheStats{Entries: -1, Hits: c.cacheHits.Load(), Misses: c.cacheMisses.Load()}
+	if sized, ok := c.cache.(SizedCache); ok {
+		stats.Entries = sized.Len(ctx)
+	}
+	return stats
+}
+
+// CacheReads returns the hits and misses of the reads of the named cache since the client was
+// created, zero for a name that is not in CacheNames.
+func (c *Web3Client) CacheReads(name string) (hits uint64, misses uint64) {
+	i := slices.Index(CacheNames[:], name)
+	if i < 0 {
+		return 0, 0
+	}
+	return c.cacheReads[i].hits.Load(), c.cacheReads[i].misses.Load()
+}
+
+// countCacheRead adds the read of the key to the totals and to the cache named by its prefix.
+func (c *Web3Client) countCacheRead(key string, hit bool) {
+	name, _, _ := strings.Cut(key, ":")
+	i := slices.Index(CacheNames[:], name)
+	if hit {
+		c.cacheHits.Add(1)
+		if i >= 0 {
+			c.cacheReads[i].hits.Add(1)
+		}
+		return
+	}
+	c.cacheMisses.Add(1)
+	if i >= 0 {
+		c.cacheReads[i].misses.Add(1)
+	}
+}
+
+// FlushCache drops all cached rewards and committees, e.g. after a reorg upstream.
+func (c *Web3Client) FlushCache(ctx context.Context) error {
+	flushable, ok := c.cache.(FlushableCache)
+	if !ok {
+		return ErrCacheNotFlushable
+	}
+	flushable.Flush(ctx)
+	return nil
+}
@@ -0,0 +1,397 @@
+package main_test
+
+import (
+	"context"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeCache struct {
+	mu      sync.Mutex
+	values  map[string][]byte
+	ttls    map[string]time.Duration
+	getKeys []string
+	setKeys []string
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{values: make(map[string][]byte), ttls: make(map[string]time.Duration)}
+}
+
+func (f *fakeCache) Get(_ context.Context, key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.getKeys = append(f.getKeys, key)
+	value, ok := f.values[key]
+	return value, ok
+}
+
+func (f *fakeCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setKeys = append(f.setKeys, key)
+	f.values[key] = value
+	f.ttls[key] = ttl
+}
+
+func TestMemoryCacheGetSet(t *testing.T) {
+	cache := src.NewMemoryCache()
+	ctx := context.Background()
+	if _, ok := cache.Get(ctx, "missing"); ok {
+		t.Error("Expected a miss for an unknown key")
+	}
+	cache.Set(ctx, "key", []byte("value"), 0)
+	value, ok := cache.Get(ctx, "key")
+	if !ok || string(value) != "value" {
+		t.Errorf("Expected value to be cached, but got %q", value)
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	cache := src.NewMemoryCache()
+	ctx := context.Background()
+	cache.Set(ctx, "key", []byte("value"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := cache.Get(ctx, "key"); ok {
+		t.Error("Expected the entry to be expired")
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := src.NewMemoryCacheWithLimit(2, src.SystemClock)
+	ctx := context.Background()
+	cache.Set(ctx, "a", []byte("a"), 0)
+	cache.Set(ctx, "b", []byte("b"), 0)
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "c", []byte("c"), 0)
+	// b was used least recently, so it makes room for c
+	for key, expected := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := cache.Get(ctx, key); ok != expected {
+			t.Errorf("Expected %s cached %t, but got %t", key, expected, ok)
+		}
+	}
+	if cache.Len(ctx) != 2 {
+		t.Errorf("Expected 2 entries, but got %d", cache.Len(ctx))
+	}
+}
+
+func TestMemoryCacheSweepsExpiredEntries(t *testing.T) {
+	clock := src.NewFakeClock(time.Now())
+	cache := src.NewMemoryCacheWithClock(clock)
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		cache.Set(ctx, strconv.Itoa(i), []byte("value"), time.Second)
+	}
+	cache.Set(ctx, "kept", []byte("value"), time.Hour)
+	clock.Advance(src.CacheSweepInterval)
+	// the expired entries are never read again, the next write after the interval drops them
+	cache.Set(ctx, "new", []byte("value"), time.Hour)
+	if cache.Len(ctx) != 2 {
+		t.Errorf("Expected the expired entries to be swept, but got %d entries", cache.Len(ctx))
+	}
+	if src.DefaultCacheTTLs().Finalized != 0 {
+		t.Errorf("Expected finalized rewards to never expire by default, but got %s", src.DefaultCacheTTLs().Finalized)
+	}
+}
+
+func TestClientPopulatesCache(t *testing.T) {
+	server := setupServer("vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	cache := newFakeCache()
+	client := src.NewWeb3Client(parsedUrl, 100, src.WithCache(cache))
+	_, _, err := client.GetBlockRewardAndStatusBySlot(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the beacon block of the slot is cached next to the reward
+	if !slices.Equal(cache.getKeys, []string{"reward:4700013", "block:4700013"}) {
+		t.Errorf("Expected lookups of the reward and the block, but got %v", cache.getKeys)
+	}
+	if !slices.Equal(cache.setKeys, []string{"block:4700013", "reward:4700013"}) {
+		t.Errorf("Expected stores of the block and the reward, but got %v", cache.setKeys)
+	}
+}
+
+func TestClientServesFromCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		t.Errorf("Expected no upstream call, but got %s", req.URL.Path)
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	cache := newFakeCache()
+	cache.values["reward:4700013"] = []byte(`{"reward":2000000000,"status":"mev","final":true}`)
+	cache.values["committee:4700013"] = []byte(`["0x01"]`)
+	client := src.NewWeb3Client(parsedUrl, 100, src.WithCache(cache))
+
+	reward, status, err := client.GetBlockRewardAndStatusBySlot(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *reward != "2.000000000" || *status != "mev" {
+		t.Errorf("Expected cached reward 2.000000000 and status mev, but got %s and %s", *reward, *status)
+	}
+	keys, err := client.GetSyncCommitteeDuties(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "0x01" {
+		t.Errorf("Expected cached committee, but got %v", keys)
+	}
+}
+
+func TestHandlersBypassCache(t *testing.T) {
+	tests := []struct {
+		testKey  string
+		path     string
+		header   string
+		cacheKey string
+		cached   string
+		fresh    string
+	}{
+		{"vanilla", "/blockreward/4700013?nocache=true", "", "reward:4700013",
+			`{"reward":2000000000,"status":"mev"}`, `{"reward":"0.000000001","status":"vanilla"}`},
+		{"syncDuties", "/syncduties/4700013", "no-cache", "committee:4700013",
+			`["0x01"]`, `["0x0000000000000000000000000000000000000000000000000000000000000001"]`},
+	}
+	for _, test := range tests {
+		server := setupServer(test.testKey)
+		parsedUrl, _ := url.Parse(server.URL)
+		cache := newFakeCache()
+		cache.values[test.cacheKey] = []byte(test.cached)
+		client := src.NewWeb3Client(parsedUrl, 100, src.WithCache(cache))
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(client))
+		router.GET("/syncduties/:slotId", src.GetSyncDutiesHandler(client))
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.header != "" {
+			req.Header.Set("Cache-Control", test.header)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		server.Close()
+		if recorder.Body.String() != test.fresh {
+			t.Errorf("%s: expected fresh response %s, but got %s", test.path, test.fresh, recorder.Body.String())
+		}
+		if string(cache.values[test.cacheKey]) == test.cached {
+			t.Errorf("%s: expected the fresh result to replace the cached value", test.path)
+		}
+	}
+}
+
+func TestHandlersReportCacheOutcome(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, test := range []struct {
+		testKey string
+		path    string
+	}{
+		{"vanilla", "/blockreward/4700013"},
+		{"syncDuties", "/syncduties/4700013"},
+	} {
+		server := setupServer(test.testKey)
+		parsedUrl, _ := url.Parse(server.URL)
+		client := src.NewWeb3Client(parsedUrl, 1000)
+		router := gin.New()
+		router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(client))
+		router.GET("/syncduties/:slotId", src.GetSyncDutiesHandler(client))
+
+		for _, expected := range []string{"MISS", "HIT"} {
+			recorder := performRequest(router, test.path)
+			if recorder.Code != http.StatusOK || recorder.Header().Get("X-Cache") != expected {
+				t.Errorf("%s: expected X-Cache %s, but got %d %q", test.path, expected, recorder.Code, recorder.Header().Get("X-Cache"))
+			}
+		}
+		if recorder := performRequest(router, test.path+"?nocache=true"); recorder.Header().Get("X-Cache") != "MISS" {
+			t.Errorf("%s: expected a bypassed cache to be a miss, but got %q", test.path, recorder.Header().Get("X-Cache"))
+		}
+		server.Close()
+	}
+}
+
+func TestBlockRewardReportsSourceInDebugMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupServer("vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+	router := gin.New()
+	src.RegisterRoutes(router, client, src.DefaultRouteTimeouts(), true)
+
+	for _, source := range []string{"live", "cache"} {
+		recorder := performRequest(router, "/blockreward/4700013")
+		expected := `{"reward":"0.000000001","source":"` + source + `","status":"vanilla"}`
+		if recorder.Body.String() != expected {
+			t.Errorf("Expected %s, but got %s", expected, recorder.Body.String())
+		}
+	}
+}
+
+func TestClientInvalidatesReorgedReward(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var mu sync.Mutex
+	blockHash := "1111"
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/eth/v2/beacon/blocks/4700013" {
+			mu.Lock()
+			defer mu.Unlock()
+			_, _ = rw.Write([]byte(`{"data":{"message":{"body":{"execution_payload":{"block_hash":"` + blockHash + `"}}}}}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	cache := newFakeCache()
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithCache(cache))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.GetBlockRewardAndStatusBySlot(ctx, "4700013"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(cache.setKeys) != 2 {
+		t.Fatalf("Expected the unchanged block to be served from cache, but got stores %v", cache.setKeys)
+	}
+
+	mu.Lock()
+	blockHash = "2222"
+	mu.Unlock()
+	if _, _, err := client.GetBlockRewardAndStatusBySlot(ctx, "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	// the reorg replaces the cached block of the slot, which the recomputation then reads
+	if !slices.Equal(cache.setKeys, []string{"block:4700013", "reward:4700013", "block:4700013", "reward:4700013"}) {
+		t.Fatalf("Expected the reorged slot to be recomputed, but got stores %v", cache.setKeys)
+	}
+	if !strings.Contains(string(cache.values["reward:4700013"]), "2222") {
+		t.Errorf("Expected the cached reward to carry the new block hash, but got %s", cache.values["reward:4700013"])
+	}
+}
+
+func TestClientCachesRewardsByFinality(t *testing.T) {
+	ttls := src.CacheTTLs{Finalized: 0, Justified: time.Hour, Recent: time.Minute}
+	// slot 4700013 is in epoch 146875, whose first slot is 4700000
+	tests := []struct {
+		name           string
+		checkpoints    string
+		expectedTTL    time.Duration
+		expectedCached bool
+		ttls           src.CacheTTLs
+	}{
+		{"finalized", `{"data":{"current_justified":{"epoch":"146876"},"finalized":{"epoch":"146876"}}}`, 0, true, ttls},
+		{"justified", `{"data":{"current_justified":{"epoch":"146876"},"finalized":{"epoch":"146875"}}}`, time.Hour, true, ttls},
+		{"recent", `{"data":{"current_justified":{"epoch":"146875"},"finalized":{"epoch":"146875"}}}`, time.Minute, true, ttls},
+		{"unknown finality", "", time.Minute, true, ttls},
+		{"recent uncached", `{"data":{"current_justified":{"epoch":"146875"},"finalized":{"epoch":"146875"}}}`, 0, false,
+			src.CacheTTLs{Justified: time.Hour, Recent: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := setupServer("vanilla")
+			defer upstream.Close()
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				if req.URL.Path == "/eth/v1/beacon/states/head/finality_checkpoints" && tt.checkpoints != "" {
+					_, _ = rw.Write([]byte(tt.checkpoints))
+					return
+				}
+				upstream.Config.Handler.ServeHTTP(rw, req)
+			}))
+			defer server.Close()
+			parsedUrl, _ := url.Parse(server.URL)
+			cache := newFakeCache()
+			client := src.NewWeb3Client(parsedUrl, 1000, src.WithCache(cache), src.WithRewardCacheTTLs(tt.ttls))
+			if _, _, err := client.GetBlockRewardWei(context.Background(), "4700013"); err != nil {
+				t.Fatal(err)
+			}
+			ttl, cached := cache.ttls["reward:4700013"]
+			if cached != tt.expectedCached || ttl != tt.expectedTTL {
+				t.Errorf("Expected cached %v with ttl %s, but got %v with %s", tt.expectedCached, tt.expectedTTL, cached, ttl)
+			}
+		})
+	}
+}
+
+func TestRecentRewardsExpireBeforeFinalizedOnes(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var finalizedEpoch atomic.Value
+	finalizedEpoch.Store("146876")
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/eth/v1/beacon/states/head/finality_checkpoints" {
+			epoch := finalizedEpoch.Load().(string)
+			_, _ = rw.Write([]byte(`{"data":{"current_justified":{"epoch":"` + epoch + `"},"finalized":{"epoch":"` + epoch + `"}}}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	cache := src.NewMemoryCache()
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithCache(cache),
+		src.WithRewardCacheTTLs(src.CacheTTLs{Justified: time.Hour, Recent: 10 * time.Millisecond}))
+	ctx := context.Background()
+	if _, _, err := client.GetBlockRewardWei(ctx, "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	// 4700015 is in epoch 146875 and not finalized yet
+	finalizedEpoch.Store("146875")
+	if _, _, err := client.GetBlockRewardWei(ctx, "4700015"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := cache.Get(ctx, "reward:4700013"); !ok {
+		t.Error("Expected the finalized reward to stay cached")
+	}
+	if _, ok := cache.Get(ctx, "reward:4700015"); ok {
+		t.Error("Expected the recent reward to expire")
+	}
+}
+
+func TestBlockHashIsSharedBetweenEndpoints(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var blockRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/eth/v2/beacon/blocks/4700013" {
+			blockRequests.Add(1)
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithCache(newFakeCache()))
+	ctx := context.Background()
+
+	if _, err := client.GetBlockFees(ctx, "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetBurntTotal(ctx, "4700013", "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.GetBlockRewardAndStatusBySlot(ctx, "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetGraffitiBySlot(ctx, "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetBlockBuilder(ctx, "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	if blockRequests.Load() != 1 {
+		t.Errorf("Expected the later endpoints to reuse the cached block, but got %d block requests", blockRequests.Load())
+	}
+}
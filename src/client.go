@@ -1,27 +1,82 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 	"golang.org/x/time/rate"
 	"io"
 	"math/big"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
 )
 
 const BlockDetailPath = "/eth/v2/beacon/blocks/"
 const StatePath = "/eth/v1/beacon/states/"
+const BlockRewardsPath = "/eth/v1/beacon/rewards/blocks/"
+const NodeSyncingPath = "/eth/v1/node/syncing"
 const MevFeeCalculationFactor = 3
+const SlotCeilingMargin = 24 * time.Hour // slots further than this past the clock slot are rejected locally
+const DefaultDialTimeout = 10 * time.Second
+const DefaultDialAttempts = 3
+const DialRetryDelay = 2 * time.Second
+const DefaultMaxRedirects = 3
+const DefaultPayloadRetries = 1
+const PayloadRetryDelay = 200 * time.Millisecond
+const DefaultReorgRetries = 2
+const ReorgRetryDelay = 200 * time.Millisecond
+const DefaultBeaconAccept = "application/json"
+
+// Version is the version of the service sent in the User-Agent, set at build time with
+// -ldflags "-X main.Version=...".
+var Version = "dev"
+
+// DefaultMergeSlot is the slot of the Paris merge on mainnet, the first slot whose block carries an
+// execution payload. The merge slot is inclusive: it has a reward, every earlier slot is pre-merge.
+const DefaultMergeSlot = 4700013
 
-var BlocksAvailableAfterSlot = big.NewInt(4700012) // Paris merge is on 4700013
 var GWEI = big.NewInt(1000000000)
 
+// Sentinels matched by the typed errors with errors.Is, so callers can tell error kinds apart
+// without comparing messages. The typed errors carry the detailed message.
+var (
+	ErrSlotMissing    = errors.New("slot is missing")
+	ErrPreMergeSlot   = errors.New("slot is before the merge")
+	ErrFutureSlot     = errors.New("slot is in the future")
+	ErrInvalidSlot    = errors.New("slot is invalid")
+	ErrPartialContent = errors.New("beacon node returned partial content")
+
+	ErrPayloadUnavailable = errors.New("execution payload is not available")
+	ErrNodeSyncing        = errors.New("beacon node is syncing")
+	ErrMismatchedSlot     = errors.New("beacon block is for another slot")
+)
+
+// Finality statuses of a slot, from the most to the least trustworthy.
+const (
+	FinalityFinalized = "finalized"
+	FinalitySafe      = "safe"
+	FinalityUnsafe    = "unsafe"
+)
+
+// ErrRedirectRejected is returned when an upstream redirect is not followed, because it points to
+// another host or the redirect limit was reached.
+var ErrRedirectRejected = errors.New("upstream redirect rejected")
+
 type SlotMissingError struct {
 	msg string
 }
@@ -30,6 +85,39 @@ func (e *SlotMissingError) Error() string {
 	return e.msg
 }
 
+func (e *SlotMissingError) Is(target error) bool {
+	return target == ErrSlotMissing
+}
+
+// PreMergeSlotError is returned for slots before the merge slot, whose blocks have no execution
+// payload. The slot has no reward, so it also matches ErrSlotMissing.
+type PreMergeSlotError struct {
+	msg string
+}
+
+func (e *PreMergeSlotError) Error() string {
+	return e.msg
+}
+
+func (e *PreMergeSlotError) Is(target error) bool {
+	return target == ErrPreMergeSlot || target == ErrSlotMissing
+}
+
+// NodeSyncingError is returned when the beacon node answers 503 because it is still syncing.
+// SyncDistance is the number of slots the node is behind, nil when it could not be looked up.
+type NodeSyncingError struct {
+	msg          string
+	SyncDistance *uint64
+}
+
+func (e *NodeSyncingError) Error() string {
+	return e.msg
+}
+
+func (e *NodeSyncingError) Is(target error) bool {
+	return target == ErrNodeSyncing
+}
+
 type FutureSlotError struct {
 	msg string
 }
@@ -38,35 +126,513 @@ func (e *FutureSlotError) Error() string {
 	return e.msg
 }
 
+func (e *FutureSlotError) Is(target error) bool {
+	return target == ErrFutureSlot
+}
+
+type InvalidSlotError struct {
+	msg string
+}
+
+func (e *InvalidSlotError) Error() string {
+	return e.msg
+}
+
+func (e *InvalidSlotError) Is(target error) bool {
+	return target == ErrInvalidSlot
+}
+
+// PartialContentError is returned when the beacon node answers with 206 Partial Content, so that a
+// truncated result such as an incomplete committee is never mistaken for the full set.
+type PartialContentError struct {
+	msg string
+}
+
+func (e *PartialContentError) Error() string {
+	return e.msg
+}
+
+func (e *PartialContentError) Is(target error) bool {
+	return target == ErrPartialContent
+}
+
+// PayloadUnavailableError is returned when the beacon block of a slot still carries an empty
+// execution payload hash after the retries, as it can while the node syncs optimistically.
+type PayloadUnavailableError struct {
+	msg string
+}
+
+func (e *PayloadUnavailableError) Error() string {
+	return e.msg
+}
+
+func (e *PayloadUnavailableError) Is(target error) bool {
+	return target == ErrPayloadUnavailable
+}
+
+// MismatchedSlotError is returned when the beacon node answers a block request with the block of
+// another slot, as a misconfigured proxy or cache in front of it could.
+type MismatchedSlotError struct {
+	msg string
+}
+
+func (e *MismatchedSlotError) Error() string {
+	return e.msg
+}
+
+func (e *MismatchedSlotError) Is(target error) bool {
+	return target == ErrMismatchedSlot
+}
+
+// tooManyIdsError is returned when the beacon node rejects a request because it lists more ids
+// than the node accepts at once.
+type tooManyIdsError struct {
+	msg string
+}
+
+func (e *tooManyIdsError) Error() string {
+	return e.msg
+}
+
+type beaconErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func isTooManyIdsMessage(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "too many") || strings.Contains(message, "exceeds")
+}
+
+// isSyncingMessage reports whether a 503 answer of the beacon node is due to syncing, e.g.
+// "Beacon node is currently syncing and not serving request on that endpoint".
+func isSyncingMessage(message string) bool {
+	return strings.Contains(strings.ToLower(message), "syncing")
+}
+
+type nodeSyncingResponse struct {
+	Data struct {
+		SyncDistance BeaconUint64 `json:"sync_distance"`
+		IsSyncing    bool         `json:"is_syncing"`
+	} `json:"data"`
+}
+
 type Web3Client struct {
 	BaseUrl    *url.URL
 	httpClient *http.Client
 	w3Client   *ethclient.Client
+	cache      Cache
+	cacheTTL   time.Duration
+	cacheTTLs  CacheTTLs
+	clock      Clock
+	spec       ChainSpec
+	rateBurst  int
+	limiter    *rate.Limiter
+
+	requestCosts         RequestCosts
+	cacheMaxEntries      int
+	validatorBatchSize   int
+	maxURLLength         int
+	mergeSlot            uint64
+	extendedStatuses     bool
+	batchConcurrency     int
+	receiptSlots         chan struct{}
+	maxReceiptCalls      int
+	maxRedirects         int
+	feeRecipientLabels   FeeRecipientLabels
+	mevThresholds        MevThresholds
+	payloadRetries       int
+	reorgRetries         int
+	rewardCheckTolerance uint64
+	circuitThreshold     int
+	circuitCooldown      time.Duration
+	userAgent            string
+	beaconAccept         string
+	dialTimeout          time.Duration
+	baseHTTPClient       *http.Client
+	wrapHTTPClient       bool
+
+	prefetch      bool
+	prefetching   atomic.Bool
+	cacheHits     atomic.Uint64
+	cacheMisses   atomic.Uint64
+	cacheReads    [len(CacheNames)]cacheReads
+	rewardFlights singleflight.Group
+}
+
+type Option func(*Web3Client)
+
+// WithClock replaces the wall clock of the time-dependent logic of the client, e.g. with a
+// FakeClock in tests. The default memory cache and the idempotency store follow it too.
+func WithClock(clock Clock) Option {
+	return func(c *Web3Client) {
+		c.clock = clock
+	}
+}
+
+// WithCache replaces the default in-memory cache used for rewards, blocks and sync committees.
+func WithCache(cache Cache) Option {
+	return func(c *Web3Client) {
+		c.cache = cache
+	}
 }
 
-func NewWeb3Client(baseUrl *url.URL, reqPerSec rate.Limit) *Web3Client {
-	limiter := rate.NewLimiter(reqPerSec, 1)
-	httpClient := &http.Client{
-		Transport: &rateLimitTransport{
-			rateLimiter: limiter,
-			transport:   http.DefaultTransport,
+// WithCacheMaxEntries bounds the entries of the default in-memory cache, see MemoryCache. It has no
+// effect on a cache set with WithCache.
+func WithCacheMaxEntries(entries int) Option {
+	return func(c *Web3Client) {
+		c.cacheMaxEntries = entries
+	}
+}
+
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Web3Client) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithRewardCacheTTLs sets the lifetimes of cached rewards by the finality of their slot.
+func WithRewardCacheTTLs(ttls CacheTTLs) Option {
+	return func(c *Web3Client) {
+		c.cacheTTLs = ttls
+	}
+}
+
+// WithPrefetch makes every block reward request compute the reward of the next slot in the
+// background, so clients polling sequential slots are served from the cache.
+func WithPrefetch(enabled bool) Option {
+	return func(c *Web3Client) {
+		c.prefetch = enabled
+	}
+}
+
+// WithValidatorBatchSize sets how many validator ids are requested at once. The batch is halved
+// automatically when the beacon node rejects it for listing too many ids.
+func WithValidatorBatchSize(size int) Option {
+	return func(c *Web3Client) {
+		c.validatorBatchSize = size
+	}
+}
+
+// WithMaxURLLength sets the longest url of a query listing ids, longer batches are split. Values
+// below 1 disable the limit.
+func WithMaxURLLength(length int) Option {
+	return func(c *Web3Client) {
+		c.maxURLLength = length
+	}
+}
+
+// WithMergeSlot sets the first slot with an execution payload, for networks that did not merge
+// on the mainnet slot. Networks that started after the merge use 0.
+func WithMergeSlot(slot uint64) Option {
+	return func(c *Web3Client) {
+		c.mergeSlot = slot
+	}
+}
+
+// WithRateBurst sets how many upstream requests may be sent at once before the rate limit
+// applies. Values below 1 are treated as 1.
+func WithRateBurst(burst int) Option {
+	return func(c *Web3Client) {
+		c.rateBurst = burst
+	}
+}
+
+// WithRequestCosts sets how many rate limiter tokens each kind of upstream request consumes.
+func WithRequestCosts(costs RequestCosts) Option {
+	return func(c *Web3Client) {
+		c.requestCosts = costs
+	}
+}
+
+// WithExtendedStatuses enables the empty, low-activity and unknown block statuses next to
+// vanilla and mev.
+func WithExtendedStatuses(enabled bool) Option {
+	return func(c *Web3Client) {
+		c.extendedStatuses = enabled
+	}
+}
+
+// WithBatchConcurrency sets how many slots of a range request are processed at the same time.
+// Values below 1 are treated as 1.
+func WithBatchConcurrency(concurrency int) Option {
+	return func(c *Web3Client) {
+		c.batchConcurrency = max(concurrency, 1)
+	}
+}
+
+// WithReceiptConcurrency sets how many transaction receipts are fetched at the same time. The
+// bound is shared by all reward computations of the client, so it also holds while a range
+// request computes several rewards at once. Values below 1 are treated as 1.
+func WithReceiptConcurrency(concurrency int) Option {
+	return func(c *Web3Client) {
+		c.receiptSlots = make(chan struct{}, max(concurrency, 1))
+	}
+}
+
+// WithMaxReceiptCalls caps the receipts fetched for one block, the remaining transactions are
+// estimated and the result is flagged as truncated.
+func WithMaxReceiptCalls(calls int) Option {
+	return func(c *Web3Client) {
+		c.maxReceiptCalls = max(calls, 0)
+	}
+}
+
+// WithMaxRedirects sets how many redirects of the upstream are followed for one request. Zero
+// rejects every redirect.
+func WithMaxRedirects(redirects int) Option {
+	return func(c *Web3Client) {
+		c.maxRedirects = max(redirects, 0)
+	}
+}
+
+// WithFeeRecipientLabels tags the detailed rewards of blocks paying one of the fee recipients
+// with its label.
+func WithFeeRecipientLabels(labels FeeRecipientLabels) Option {
+	return func(c *Web3Client) {
+		c.feeRecipientLabels = labels
+	}
+}
+
+// WithPayloadRetries sets how often a beacon block with an empty execution payload hash is fetched
+// again before the payload is reported unavailable. Negative values are treated as 0.
+func WithPayloadRetries(retries int) Option {
+	return func(c *Web3Client) {
+		c.payloadRetries = max(retries, 0)
+	}
+}
+
+// WithReorgRetries sets how often the block hash of a slot near the head is looked up again when
+// the execution client does not know the block, as the slot may have been reorged in between.
+// Negative values are treated as 0.
+func WithReorgRetries(retries int) Option {
+	return func(c *Web3Client) {
+		c.reorgRetries = max(retries, 0)
+	}
+}
+
+// WithCircuitBreaker sets after how many consecutive failures the upstream requests of a category
+// (blocks, validators or headers) are failed fast, and for how long, see CircuitBreakers. A
+// threshold of 0 or less disables the breakers.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Web3Client) {
+		c.circuitThreshold = threshold
+		c.circuitCooldown = cooldown
+	}
+}
+
+// WithRewardCheckTolerance sets by how many gwei the figures of the beacon node may differ before
+// the reward check reports a discrepancy.
+func WithRewardCheckTolerance(gwei uint64) Option {
+	return func(c *Web3Client) {
+		c.rewardCheckTolerance = gwei
+	}
+}
+
+// WithMevThresholds sets when a transaction marks its block as mev, e.g. the thresholds of the
+// network from MevThresholdsFor.
+func WithMevThresholds(thresholds MevThresholds) Option {
+	return func(c *Web3Client) {
+		c.mevThresholds = thresholds
+	}
+}
+
+// DefaultUserAgent identifies this service and its version to the upstream nodes.
+func DefaultUserAgent() string {
+	return "staking-facilities-assignment/" + Version
+}
+
+// WithUserAgent replaces the User-Agent sent with all upstream requests.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Web3Client) {
+		if userAgent != "" {
+			c.userAgent = userAgent
+		}
+	}
+}
+
+// WithBeaconAccept replaces the Accept header of beacon API requests, for nodes that require a
+// specific media type. The execution client requests always accept JSON.
+func WithBeaconAccept(accept string) Option {
+	return func(c *Web3Client) {
+		if accept != "" {
+			c.beaconAccept = accept
+		}
+	}
+}
+
+// WithDialTimeout bounds how long dialing the execution client may take. Only websocket and ipc
+// endpoints connect while dialing, http endpoints connect on the first request.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(c *Web3Client) {
+		if timeout > 0 {
+			c.dialTimeout = timeout
+		}
+	}
+}
+
+// WithHTTPClient sends the upstream requests, including the ones of the rpc client, through a copy
+// of client, e.g. one with a proxy or a recording transport. Its transport is still wrapped with the
+// rate limiter, the circuit breaker and the timing logs, and the redirect policy of the client
+// applies unless client has its own.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Web3Client) {
+		c.baseHTTPClient, c.wrapHTTPClient = client, true
+	}
+}
+
+// WithUnwrappedHTTPClient sends the upstream requests through client as it is. The rate limit, the
+// circuit breaker, the redirect policy and the timing logs do not apply to its requests.
+func WithUnwrappedHTTPClient(client *http.Client) Option {
+	return func(c *Web3Client) {
+		c.baseHTTPClient, c.wrapHTTPClient = client, false
+	}
+}
+
+// newHTTPClient returns the client of the upstream requests, see WithHTTPClient.
+func (c *Web3Client) newHTTPClient() *http.Client {
+	if c.baseHTTPClient != nil && !c.wrapHTTPClient {
+		return c.baseHTTPClient
+	}
+	httpClient := &http.Client{CheckRedirect: c.checkRedirect}
+	transport := http.DefaultTransport
+	if c.baseHTTPClient != nil {
+		*httpClient = *c.baseHTTPClient
+		if httpClient.Transport != nil {
+			transport = httpClient.Transport
+		}
+		if httpClient.CheckRedirect == nil {
+			httpClient.CheckRedirect = c.checkRedirect
+		}
+	}
+	httpClient.Transport = &circuitBreakerTransport{
+		breakers: NewCircuitBreakers(c.circuitThreshold, c.circuitCooldown),
+		clock:    c.clock,
+		transport: &rateLimitTransport{
+			rateLimiter: c.limiter,
+			costs:       c.requestCosts,
+			transport:   &timingLogTransport{transport: transport},
 		},
 	}
-	rpcClient, err := rpc.DialOptions(context.Background(), baseUrl.String(), rpc.WithHTTPClient(httpClient))
+	return httpClient
+}
+
+func NewWeb3Client(baseUrl *url.URL, reqPerSec rate.Limit, opts ...Option) *Web3Client {
+	w3Client := &Web3Client{
+		BaseUrl:   baseUrl,
+		cacheTTL:  DefaultCacheTTL,
+		cacheTTLs: DefaultCacheTTLs(),
+		spec:      MainnetChainSpec(),
+		clock:     SystemClock,
+		rateBurst: 1,
+
+		cacheMaxEntries:    DefaultCacheMaxEntries,
+		validatorBatchSize: DefaultValidatorBatchSize,
+		maxURLLength:       DefaultMaxURLLength,
+		mergeSlot:          DefaultMergeSlot,
+		requestCosts:       DefaultRequestCosts(),
+		batchConcurrency:   DefaultBatchConcurrency,
+		receiptSlots:       make(chan struct{}, DefaultReceiptConcurrency),
+		maxReceiptCalls:    DefaultMaxReceiptCalls,
+		maxRedirects:       DefaultMaxRedirects,
+		userAgent:          DefaultUserAgent(),
+		beaconAccept:       DefaultBeaconAccept,
+		dialTimeout:        DefaultDialTimeout,
+		mevThresholds:      MevThresholdsFor("mainnet"),
+		payloadRetries:     DefaultPayloadRetries,
+		reorgRetries:       DefaultReorgRetries,
+		circuitThreshold:   DefaultCircuitThreshold,
+		circuitCooldown:    DefaultCircuitCooldown,
+	}
+	for _, opt := range opts {
+		opt(w3Client)
+	}
+	if w3Client.cache == nil {
+		w3Client.cache = NewMemoryCacheWithLimit(w3Client.cacheMaxEntries, w3Client.clock)
+	}
+	w3Client.limiter = rate.NewLimiter(reqPerSec, max(w3Client.rateBurst, 1))
+	w3Client.httpClient = w3Client.newHTTPClient()
+	ctx, cancel := context.WithTimeout(context.Background(), w3Client.dialTimeout)
+	defer cancel()
+	rpcClient, err := rpc.DialOptions(ctx, baseUrl.String(), rpc.WithHTTPClient(w3Client.httpClient),
+		rpc.WithHeader("User-Agent", w3Client.userAgent))
 	if err != nil {
-		log.Info().Err(err).Msg("can not dial ethereum client")
+		log.Info().Err(err).Dur("timeout", w3Client.dialTimeout).Msg("can not dial ethereum client")
 		return nil
 	}
-	client := ethclient.NewClient(rpcClient)
-	return &Web3Client{BaseUrl: baseUrl, httpClient: httpClient, w3Client: client}
+	w3Client.w3Client = ethclient.NewClient(rpcClient)
+	return w3Client
+}
+
+// cachedReward is the cached result of a reward computation. The block hash is kept so that a
+// reward of a slot that was not final yet can be checked against reorgs when it is served.
+type cachedReward struct {
+	Reward    *big.Int    `json:"reward"`
+	Status    string      `json:"status"`
+	Truncated bool        `json:"truncated,omitempty"`
+	BlockHash common.Hash `json:"blockHash"`
+	Final     bool        `json:"final"`
+}
+
+func rewardCacheKey(slotId string) string {
+	return "reward:" + slotId
+}
+
+func blockDetailCacheKey(slotId string) string {
+	return "block:" + slotId
+}
+
+func committeeCacheKey(slotId string) string {
+	return "committee:" + slotId
+}
+
+func (c *Web3Client) getCached(ctx context.Context, key string, v interface{}) bool {
+	if cacheBypassed(ctx) {
+		return false
+	}
+	data, ok := c.cache.Get(ctx, key)
+	if !ok {
+		c.countCacheRead(key, false)
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		log.Info().Err(err).Str("key", key).Msg("can not decode cached value")
+		c.countCacheRead(key, false)
+		return false
+	}
+	c.countCacheRead(key, true)
+	return true
+}
+
+func (c *Web3Client) setCached(ctx context.Context, key string, v interface{}) {
+	c.setCachedFor(ctx, key, v, c.cacheTTL)
+}
+
+// setCachedFor caches the value for the ttl. A negative ttl leaves it uncached.
+func (c *Web3Client) setCachedFor(ctx context.Context, key string, v interface{}, ttl time.Duration) {
+	if ttl < 0 {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Info().Err(err).Str("key", key).Msg("can not encode value for cache")
+		return
+	}
+	c.cache.Set(ctx, key, data, ttl)
 }
 
 type beaconBlockDetailResponse struct {
 	Data struct {
 		Message struct {
-			Body struct {
-				ExecutionPayload struct {
-					BlockHash string `json:"block_hash"`
+			Slot          *BeaconUint64 `json:"slot"`
+			ProposerIndex BeaconUint64  `json:"proposer_index"`
+			Body          struct {
+				Graffiti          string            `json:"graffiti"`
+				ProposerSlashings []json.RawMessage `json:"proposer_slashings"`
+				AttesterSlashings []json.RawMessage `json:"attester_slashings"`
+				ExecutionPayload  struct {
+					BlockHash   string       `json:"block_hash"`
+					BlockNumber BeaconUint64 `json:"block_number"`
 				} `json:"execution_payload"`
 			} `json:"body"`
 		} `json:"message"`
@@ -75,53 +641,113 @@ type beaconBlockDetailResponse struct {
 
 type syncCommitteesResponse struct {
 	Data struct {
-		Validators []string `json:"validators"`
+		Validators []BeaconUint64 `json:"validators"`
 	} `json:"data"`
 }
 
-type validatorsDetailResponse struct {
-	Data []struct {
-		Validator struct {
-			Pubkey string `json:"pubkey"`
-		} `json:"validator"`
-	} `json:"data"`
+// BeaconUint64 is an unsigned integer field of a beacon API response. The API encodes numbers
+// as strings, but plain JSON numbers are accepted as well so that a node changing the
+// representation does not fail the request.
+type BeaconUint64 uint64
+
+func (b *BeaconUint64) UnmarshalJSON(data []byte) error {
+	value := string(bytes.Trim(data, `"`))
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("can not parse beacon number %s: %w", data, err)
+	}
+	*b = BeaconUint64(parsed)
+	return nil
+}
+
+func (b BeaconUint64) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + b.String() + `"`), nil
+}
+
+func (b BeaconUint64) String() string {
+	return strconv.FormatUint(uint64(b), 10)
 }
 
 type BeaconHeader struct {
 	Data []struct {
 		Header struct {
 			Message struct {
-				Slot string `json:"slot"`
+				Slot BeaconUint64 `json:"slot"`
 			} `json:"message"`
 		} `json:"header"`
 	} `json:"data"`
 }
 
+type finalityCheckpoint struct {
+	Epoch BeaconUint64 `json:"epoch"`
+	Root  string       `json:"root"`
+}
+
+type finalityCheckpointsResponse struct {
+	Data struct {
+		PreviousJustified finalityCheckpoint `json:"previous_justified"`
+		CurrentJustified  finalityCheckpoint `json:"current_justified"`
+		Finalized         finalityCheckpoint `json:"finalized"`
+	} `json:"data"`
+}
+
 type rateLimitTransport struct {
 	rateLimiter *rate.Limiter
+	costs       RequestCosts
 	transport   http.RoundTripper
 }
 
+// RoundTrip waits for the tokens of the request. Tokens reserved up front for the request context
+// are used first, only the rest is taken from the limiter.
 func (rlt *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	err := rlt.rateLimiter.Wait(context.Background())
+	cost := rlt.costs.cost(req)
+	if reservation, ok := req.Context().Value(tokenReservationKey{}).(*tokenReservation); ok {
+		cost -= reservation.take(cost)
+	}
+	err := waitTokens(req.Context(), rlt.rateLimiter, cost)
 	if err != nil {
-		return nil, err
+		return nil, &limiterWaitError{err: err}
 	}
 	return rlt.transport.RoundTrip(req)
 }
 
-func (c *Web3Client) sendAPIRequest(requestUrl string, requestName string, v interface{}) error {
-	req, err := http.NewRequest("GET", requestUrl, nil)
+// checkRedirect follows up to maxRedirects redirects that stay on the host of the original
+// request. A redirect to another host would silently move the upstream, so it is rejected. The
+// redirected requests go through the rate limited transport like any other request.
+func (c *Web3Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > c.maxRedirects {
+		return fmt.Errorf("%w: more than %d redirects", ErrRedirectRejected, c.maxRedirects)
+	}
+	if req.URL.Host != via[0].URL.Host {
+		return fmt.Errorf("%w: redirect from %s to %s", ErrRedirectRejected, via[0].URL.Host, req.URL.Host)
+	}
+	return nil
+}
+
+// setBeaconHeaders sets the Accept and User-Agent headers of a beacon API request.
+func (c *Web3Client) setBeaconHeaders(req *http.Request) {
+	req.Header.Set("Accept", c.beaconAccept)
+	req.Header.Set("User-Agent", c.userAgent)
+}
+
+func (c *Web3Client) sendAPIRequest(ctx context.Context, requestUrl string, requestName string, v interface{}) (err error) {
+	ctx, span := tracer().Start(ctx, requestName, trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		endSpan(span, err)
+	}()
+	req, err := http.NewRequestWithContext(withUpstreamRequestName(ctx, requestName), "GET", requestUrl, nil)
 	if err != nil {
 		log.Info().Err(err).Str("requestName", requestName).Msg("can not create request")
 		return err
 	}
-	req.Header.Set("Accept", "application/json")
+	span.SetAttributes(attribute.String("upstream.endpoint", req.URL.Path))
+	c.setBeaconHeaders(req)
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		log.Info().Err(err).Str("requestName", requestName).Msg("can not send request")
 		return err
 	}
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
@@ -140,9 +766,27 @@ func (c *Web3Client) sendAPIRequest(requestUrl string, requestName string, v int
 	}
 
 	if resp.StatusCode == http.StatusBadRequest {
+		var errorResponse beaconErrorResponse
+		if json.Unmarshal(body, &errorResponse) == nil && isTooManyIdsMessage(errorResponse.Message) {
+			return &tooManyIdsError{msg: errorResponse.Message}
+		}
 		return &SlotMissingError{msg: "Slot is not found"}
 	}
 
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		var errorResponse beaconErrorResponse
+		if json.Unmarshal(body, &errorResponse) == nil && isSyncingMessage(errorResponse.Message) {
+			log.Info().Str("requestName", requestName).Msg("beacon node is syncing")
+			return c.nodeSyncingError(ctx, requestName)
+		}
+		return fmt.Errorf("beacon node is unavailable for %s", requestName)
+	}
+
+	if resp.StatusCode == http.StatusPartialContent {
+		log.Info().Str("requestName", requestName).Msg("beacon node returned partial content")
+		return &PartialContentError{msg: "Beacon node returned partial content for " + requestName}
+	}
+
 	if err = json.Unmarshal(body, v); err != nil {
 		return err
 	}
@@ -150,118 +794,472 @@ func (c *Web3Client) sendAPIRequest(requestUrl string, requestName string, v int
 	return nil
 }
 
-func (c *Web3Client) getBlockHash(slotId string) (common.Hash, error) {
+// nodeSyncingError returns the error of a request refused by a syncing node. The sync distance is
+// looked up on a best effort basis, the error is returned without it when the lookup fails.
+func (c *Web3Client) nodeSyncingError(ctx context.Context, requestName string) error {
+	syncingError := &NodeSyncingError{msg: "Beacon node is syncing"}
+	if requestName == "node syncing" {
+		return syncingError
+	}
+	var syncing nodeSyncingResponse
+	if err := c.sendAPIRequest(ctx, c.BaseUrl.String()+NodeSyncingPath, "node syncing", &syncing); err != nil {
+		log.Info().Err(err).Msg("can not get sync distance of the beacon node")
+		return syncingError
+	}
+	distance := uint64(syncing.Data.SyncDistance)
+	syncingError.SyncDistance = &distance
+	syncingError.msg = fmt.Sprintf("Beacon node is syncing, %d slots behind the head", distance)
+	return syncingError
+}
+
+func (c *Web3Client) getBlockDetail(ctx context.Context, slotId string) (*beaconBlockDetailResponse, error) {
 	endpoint := c.BaseUrl.String() + BlockDetailPath + slotId
 	var blockDetail beaconBlockDetailResponse
-	err := c.sendAPIRequest(endpoint, "beacon block detail", &blockDetail)
+	err := c.sendAPIRequest(ctx, endpoint, "beacon block detail", &blockDetail)
 	if err != nil {
-		return common.Hash{}, err
+		return nil, err
+	}
+	if err := checkBlockSlot(&blockDetail, slotId); err != nil {
+		log.Info().Err(err).Str("slotId", slotId).Msg("beacon node returned the block of another slot")
+		return nil, err
 	}
-	blockHash := blockDetail.Data.Message.Body.ExecutionPayload.BlockHash
-	return common.HexToHash(blockHash), nil
+	return &blockDetail, nil
 }
 
-func (c *Web3Client) getSyncCommitteesValidatorIndexes(slotId string) ([]string, error) {
-	endpoint := c.BaseUrl.String() + StatePath + slotId + "/sync_committees"
-	var response syncCommitteesResponse
-	err := c.sendAPIRequest(endpoint, "sync committees", &response)
+// checkBlockSlot makes sure the block returned for a numeric slot id is the block of that slot.
+// Named ids such as head resolve to a slot only on the node, and a block without a slot field is
+// trusted.
+func checkBlockSlot(blockDetail *beaconBlockDetailResponse, slotId string) error {
+	requested, err := strconv.ParseUint(slotId, 10, 64)
+	returned := blockDetail.Data.Message.Slot
+	if err != nil || returned == nil || uint64(*returned) == requested {
+		return nil
+	}
+	return &MismatchedSlotError{msg: fmt.Sprintf("Beacon node returned the block of slot %s for slot %s", returned, slotId)}
+}
+
+// getBlockDetailWithPayload returns the beacon block of the slot and its execution block hash. A
+// node syncing optimistically may transiently report an empty payload hash, the block is then
+// fetched again up to payloadRetries times, PayloadRetryDelay apart.
+func (c *Web3Client) getBlockDetailWithPayload(ctx context.Context, slotId string) (*beaconBlockDetailResponse, common.Hash, error) {
+	for attempt := 0; ; attempt++ {
+		blockDetail, err := c.getBlockDetail(ctx, slotId)
+		if err != nil {
+			return nil, common.Hash{}, err
+		}
+		blockHash := common.HexToHash(blockDetail.Data.Message.Body.ExecutionPayload.BlockHash)
+		if blockHash != (common.Hash{}) {
+			return blockDetail, blockHash, nil
+		}
+		if attempt >= c.payloadRetries {
+			return nil, common.Hash{}, &PayloadUnavailableError{msg: "Execution payload is not available yet"}
+		}
+		log.Info().Str("slotId", slotId).Int("attempt", attempt+1).Msg("beacon block has an empty payload hash, retrying")
+		select {
+		case <-c.clock.After(PayloadRetryDelay):
+		case <-ctx.Done():
+			return nil, common.Hash{}, ctx.Err()
+		}
+	}
+}
+
+// getCachedBlockDetail returns the beacon block of the slot like getBlockDetail. Blocks are cached
+// by slot, so the reward, fees, graffiti and builder of the same slot share one beacon block lookup.
+// Named slot ids like head resolve on the node and are never cached.
+func (c *Web3Client) getCachedBlockDetail(ctx context.Context, slotId string) (*beaconBlockDetailResponse, error) {
+	if blockDetail, ok := c.cachedBlockDetail(ctx, slotId); ok {
+		return blockDetail, nil
+	}
+	blockDetail, err := c.getBlockDetail(ctx, slotId)
 	if err != nil {
 		return nil, err
 	}
-	return response.Data.Validators, nil
+	c.cacheBlockDetail(ctx, slotId, blockDetail)
+	return blockDetail, nil
 }
 
-func (c *Web3Client) getPubKeysOfSyncCommittees(slotId string, validatorIndexes []string) ([]string, error) {
-	endpoint := c.BaseUrl.String() + StatePath + slotId + "/validators"
-	for index, validatorIndex := range validatorIndexes {
-		if index == 0 {
-			endpoint += "?"
-		}
-		endpoint += "id=" + validatorIndex
-		if index != len(validatorIndexes)-1 {
-			endpoint += "&"
+// getCachedBlockDetailWithPayload returns the beacon block of the slot and its execution block
+// hash like getBlockDetailWithPayload, from the cache when a block with a payload was cached.
+func (c *Web3Client) getCachedBlockDetailWithPayload(ctx context.Context, slotId string) (*beaconBlockDetailResponse, common.Hash, error) {
+	if blockDetail, ok := c.cachedBlockDetail(ctx, slotId); ok {
+		if blockHash := common.HexToHash(blockDetail.Data.Message.Body.ExecutionPayload.BlockHash); blockHash != (common.Hash{}) {
+			return blockDetail, blockHash, nil
 		}
 	}
-	var response validatorsDetailResponse
-	err := c.sendAPIRequest(endpoint, "receive pubkeys of validators", &response)
+	blockDetail, blockHash, err := c.getBlockDetailWithPayload(ctx, slotId)
 	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	c.cacheBlockDetail(ctx, slotId, blockDetail)
+	return blockDetail, blockHash, nil
+}
+
+// getBlockHash returns the execution block hash of the slot, see getCachedBlockDetailWithPayload.
+func (c *Web3Client) getBlockHash(ctx context.Context, slotId string) (common.Hash, error) {
+	_, blockHash, err := c.getCachedBlockDetailWithPayload(ctx, slotId)
+	return blockHash, err
+}
+
+func (c *Web3Client) cachedBlockDetail(ctx context.Context, slotId string) (*beaconBlockDetailResponse, bool) {
+	if _, err := strconv.ParseUint(slotId, 10, 64); err != nil {
+		return nil, false
+	}
+	var blockDetail beaconBlockDetailResponse
+	if !c.getCached(ctx, blockDetailCacheKey(slotId), &blockDetail) {
+		return nil, false
+	}
+	return &blockDetail, true
+}
+
+// cacheBlockDetail caches the beacon block of the slot for the recent ttl, as the slot may still be
+// reorged. Looking up the finality of the slot here would cost an upstream call on every miss, the
+// callers that already know the slot is finalized keep the block longer with promoteBlockDetail.
+func (c *Web3Client) cacheBlockDetail(ctx context.Context, slotId string, blockDetail *beaconBlockDetailResponse) {
+	if _, err := strconv.ParseUint(slotId, 10, 64); err != nil {
+		return
+	}
+	c.setCachedFor(ctx, blockDetailCacheKey(slotId), blockDetail, c.cacheTTLs.Recent)
+}
+
+// promoteBlockDetail keeps the cached beacon block of a finalized slot for the finalized ttl, the
+// block of the slot can no longer change.
+func (c *Web3Client) promoteBlockDetail(ctx context.Context, slotId string) {
+	if c.cacheTTLs.Finalized < 0 {
+		return
+	}
+	if data, ok := c.cache.Get(ctx, blockDetailCacheKey(slotId)); ok {
+		c.cache.Set(ctx, blockDetailCacheKey(slotId), data, c.cacheTTLs.Finalized)
+	}
+}
+
+// GetBlockNumberBySlot returns the number of the execution block of the slot. It is read from
+// the execution payload of the beacon block, so no execution layer call is needed.
+func (c *Web3Client) GetBlockNumberBySlot(ctx context.Context, slotId string) (uint64, error) {
+	if _, err := c.validateRewardSlot(slotId); err != nil {
+		return 0, err
+	}
+	blockDetail, err := c.getCachedBlockDetail(ctx, slotId)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(blockDetail.Data.Message.Body.ExecutionPayload.BlockNumber), nil
+}
+
+// GetSlotByBlockNumber returns the slot whose beacon block carries the execution block with the
+// given number. The beacon API does not index blocks by execution number, but since the merge
+// every execution block is produced in its slot, so the slot follows from the block timestamp.
+// The beacon block of that slot is checked to carry the same block number.
+func (c *Web3Client) GetSlotByBlockNumber(ctx context.Context, number string) (uint64, error) {
+	blockNumber, ok := new(big.Int).SetString(number, 10)
+	if !ok || blockNumber.Sign() < 0 {
+		return 0, &InvalidSlotError{msg: "Block number is invalid"}
+	}
+	header, err := c.w3Client.HeaderByNumber(withUpstreamRequestName(ctx, "eth_getBlockByNumber"), blockNumber)
+	if errors.Is(err, ethereum.NotFound) {
+		return 0, &FutureSlotError{msg: "Block is in the future"}
+	}
+	if err != nil {
+		log.Info().Err(err).Str("blockNumber", number).Msg("can not get block header by number")
+		return 0, err
+	}
+	slot, err := c.SlotAtTime(time.Unix(int64(header.Time), 0))
+	if err != nil {
+		return 0, err
+	}
+	slotId := strconv.FormatUint(slot, 10)
+	beaconBlockNumber, err := c.GetBlockNumberBySlot(ctx, slotId)
+	if err != nil {
+		return 0, err
+	}
+	if beaconBlockNumber != blockNumber.Uint64() {
+		return 0, fmt.Errorf("beacon block of slot %s carries block %d instead of %s", slotId, beaconBlockNumber, number)
+	}
+	return slot, nil
+}
+
+// blockRewardsData is the consensus layer reward of the proposer of a block in gwei and its parts.
+type blockRewardsData struct {
+	ProposerIndex     BeaconUint64 `json:"proposer_index"`
+	Total             BeaconUint64 `json:"total"`
+	Attestations      BeaconUint64 `json:"attestations"`
+	SyncAggregate     BeaconUint64 `json:"sync_aggregate"`
+	ProposerSlashings BeaconUint64 `json:"proposer_slashings"`
+	AttesterSlashings BeaconUint64 `json:"attester_slashings"`
+}
+
+type blockRewardsResponse struct {
+	Data blockRewardsData `json:"data"`
+}
+
+// getBlockRewards returns the consensus layer reward of the proposer of the slot as the beacon
+// node reports it, in gwei, summed over attestation inclusion, sync aggregate and slashing
+// rewards.
+func (c *Web3Client) getBlockRewards(ctx context.Context, slotId string) (*blockRewardsData, error) {
+	endpoint := c.BaseUrl.String() + BlockRewardsPath + slotId
+	var response blockRewardsResponse
+	if err := c.sendAPIRequest(ctx, endpoint, "block rewards", &response); err != nil {
 		return nil, err
 	}
-	var pubKeys []string
-	for _, info := range response.Data {
-		pubKeys = append(pubKeys, info.Validator.Pubkey)
+	return &response.Data, nil
+}
+
+func (c *Web3Client) getSyncCommitteesValidatorIndexes(ctx context.Context, slotId string) ([]string, error) {
+	endpoint := c.BaseUrl.String() + StatePath + slotId + "/sync_committees"
+	var response syncCommitteesResponse
+	err := c.sendAPIRequest(ctx, endpoint, "sync committees", &response)
+	if err != nil {
+		return nil, err
+	}
+	validatorIndexes := make([]string, len(response.Data.Validators))
+	for i, validatorIndex := range response.Data.Validators {
+		validatorIndexes[i] = validatorIndex.String()
 	}
-	return pubKeys, nil
+	return validatorIndexes, nil
 }
 
-func (c *Web3Client) getCurrentSlotId() *big.Int {
+func (c *Web3Client) getFinalityCheckpoints(ctx context.Context) (*finalityCheckpointsResponse, error) {
+	endpoint := c.BaseUrl.String() + StatePath + "head/finality_checkpoints"
+	var response finalityCheckpointsResponse
+	err := c.sendAPIRequest(ctx, endpoint, "finality checkpoints", &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// finalityStatus classifies the slot against the checkpoints. Slots up to the finalized
+// checkpoint can not be reorged, slots up to the justified checkpoint only with slashable votes,
+// later slots may still be orphaned.
+func (c *Web3Client) finalityStatus(slot *big.Int, checkpoints *finalityCheckpointsResponse) string {
+	if c.isSlotFinalized(slot, checkpoints) {
+		return FinalityFinalized
+	}
+	justifiedEpoch := new(big.Int).SetUint64(uint64(checkpoints.Data.CurrentJustified.Epoch))
+	justifiedSlot := new(big.Int).Mul(justifiedEpoch, new(big.Int).SetUint64(c.spec.SlotsPerEpoch))
+	if slot.Cmp(justifiedSlot) != 1 {
+		return FinalitySafe
+	}
+	return FinalityUnsafe
+}
+
+// isSlotFinalized reports whether the slot is at or before the first slot of the finalized epoch.
+func (c *Web3Client) isSlotFinalized(slot *big.Int, checkpoints *finalityCheckpointsResponse) bool {
+	finalizedEpoch := new(big.Int).SetUint64(uint64(checkpoints.Data.Finalized.Epoch))
+	finalizedSlot := new(big.Int).Mul(finalizedEpoch, new(big.Int).SetUint64(c.spec.SlotsPerEpoch))
+	return slot.Cmp(finalizedSlot) != 1
+}
+
+func (c *Web3Client) getCurrentSlotId(ctx context.Context) (*big.Int, error) {
 	slotIdEndpoint := c.BaseUrl.String() + "/eth/v1/beacon/headers"
 	var header BeaconHeader
-	err := c.sendAPIRequest(slotIdEndpoint, "current slot id", &header)
+	err := c.sendAPIRequest(ctx, slotIdEndpoint, "current slot id", &header)
 	if err != nil {
-		return big.NewInt(0)
+		return nil, err
 	}
-	slotAsInt, ok := new(big.Int).SetString(header.Data[0].Header.Message.Slot, 10)
-	if !ok {
-		return big.NewInt(0)
+	if len(header.Data) == 0 {
+		return nil, errors.New("beacon node returned no head header")
 	}
-	return slotAsInt
+	return new(big.Int).SetUint64(uint64(header.Data[0].Header.Message.Slot)), nil
 }
 
-func (c *Web3Client) GetBlockRewardAndStatusBySlot(ctx context.Context, slotId string) (*string, *string, error) {
-	slotIdAsInt, ok := new(big.Int).SetString(slotId, 10)
-	if !ok {
-		return nil, nil, errors.New("can not convert slotId to bigInt")
+// resolveSlotId maps the symbolic slot "head", and a missing slot, to the number of the head slot.
+// Other slot ids are returned as they are.
+func (c *Web3Client) resolveSlotId(ctx context.Context, slotId string) (string, error) {
+	if slotId != "" && slotId != "head" {
+		return slotId, nil
+	}
+	head, err := c.getCurrentSlotId(ctx)
+	if err != nil {
+		return "", err
+	}
+	return head.String(), nil
+}
+
+// clockSlot returns the slot derived from the genesis time and the given time.
+func (c *Web3Client) clockSlot(now time.Time) uint64 {
+	elapsed := now.Sub(c.spec.GenesisTime)
+	if elapsed < 0 {
+		return 0
+	}
+	return uint64(elapsed / c.spec.SlotDuration())
+}
+
+// slotCeiling returns the highest slot that can plausibly exist at the given time, which is the
+// slot derived from the genesis time and the clock plus SlotCeilingMargin.
+func (c *Web3Client) slotCeiling(now time.Time) *big.Int {
+	return new(big.Int).SetUint64(c.clockSlot(now.Add(SlotCeilingMargin)))
+}
+
+// isBeyondClockEpoch reports whether the epoch of the slot is more than FutureEpochMargin epochs
+// past the epoch of the clock slot. No head can have reached such a slot yet.
+func (c *Web3Client) isBeyondClockEpoch(slot *big.Int, now time.Time) bool {
+	slotsPerEpoch := new(big.Int).SetUint64(c.spec.SlotsPerEpoch)
+	slotEpoch := new(big.Int).Div(slot, slotsPerEpoch)
+	clockEpoch := c.clockSlot(now) / c.spec.SlotsPerEpoch
+	return slotEpoch.Cmp(new(big.Int).SetUint64(clockEpoch+FutureEpochMargin)) == 1
+}
+
+// SlotAtTime returns the slot that was the head slot at t, derived from the genesis time and the
+// slot duration. Times before genesis or beyond SlotCeilingMargin past the clock are rejected.
+func (c *Web3Client) SlotAtTime(t time.Time) (uint64, error) {
+	if t.Before(c.spec.GenesisTime) {
+		return 0, &InvalidSlotError{msg: "Timestamp is before genesis"}
 	}
-	if slotIdAsInt.Cmp(BlocksAvailableAfterSlot) != 1 {
-		return nil, nil, &SlotMissingError{msg: "Slot is missing"}
+	if t.After(c.clock.Now().Add(SlotCeilingMargin)) {
+		return 0, &InvalidSlotError{msg: "Timestamp is too far in the future"}
 	}
-	currentSlotId := c.getCurrentSlotId()
-	if slotIdAsInt.Cmp(currentSlotId) == 1 {
-		return nil, nil, &FutureSlotError{msg: "Slot is in the future"}
+	return uint64(t.Sub(c.spec.GenesisTime) / c.spec.SlotDuration()), nil
+}
+
+// GetSyncPeriod returns the sync committee period boundaries of the slot, or of the clock slot
+// when slotId is empty. Only slot arithmetic is involved, the beacon node is not called.
+func (c *Web3Client) GetSyncPeriod(slotId string) (*SyncPeriod, error) {
+	slot := c.clockSlot(c.clock.Now())
+	if slotId != "" {
+		slotIdAsInt, err := c.parseSlotId(slotId)
+		if err != nil {
+			return nil, err
+		}
+		slot = slotIdAsInt.Uint64()
 	}
-	blockHash, err := c.getBlockHash(slotId)
+	period := c.spec.SyncPeriodOf(slot)
+	return &period, nil
+}
+
+// MergeStats counts the slots and seconds from the merge slot to the head of the beacon node.
+type MergeStats struct {
+	MergeSlot         string `json:"mergeSlot"`
+	CurrentSlot       string `json:"currentSlot"`
+	SlotsSinceMerge   string `json:"slotsSinceMerge"`
+	SecondsSinceMerge string `json:"secondsSinceMerge"`
+}
+
+// GetMergeStats returns the distance of the head slot to the merge slot, the first slot with an
+// execution payload. The seconds follow from the slot duration, so missed slots count.
+func (c *Web3Client) GetMergeStats(ctx context.Context) (*MergeStats, error) {
+	currentSlot, err := c.getCurrentSlotId(ctx)
 	if err != nil {
-		return nil, nil, err
+		log.Info().Err(err).Msg("can not get current slot id")
+		return nil, err
 	}
+	mergeSlot := new(big.Int).SetUint64(c.mergeSlot)
+	slots := new(big.Int).Sub(currentSlot, mergeSlot)
+	if slots.Sign() < 0 {
+		slots.SetInt64(0)
+	}
+	seconds := new(big.Int).Mul(slots, new(big.Int).SetUint64(c.spec.SecondsPerSlot))
+	return &MergeStats{
+		MergeSlot:         mergeSlot.String(),
+		CurrentSlot:       currentSlot.String(),
+		SlotsSinceMerge:   slots.String(),
+		SecondsSinceMerge: seconds.String(),
+	}, nil
+}
 
-	block, err := c.w3Client.BlockByHash(ctx, blockHash)
+// GetForkAtSlot returns the name of the fork active at the slot, derived from the fork schedule of
+// the chain spec without calling the beacon node.
+func (c *Web3Client) GetForkAtSlot(slotId string) (string, error) {
+	slotIdAsInt, err := c.parseSlotId(slotId)
 	if err != nil {
-		log.Info().Err(err).Msg("can not get block by hash")
-		return nil, nil, err
-	}
-	burntFees := new(big.Int).Mul(block.BaseFee(), big.NewInt(int64(block.GasUsed())))
-	txCosts := new(big.Int).SetInt64(0)
-	status := "vanilla"
-	for _, tx := range block.Transactions() {
-		receipt, err := c.w3Client.TransactionReceipt(ctx, tx.Hash())
-		cost := tx.Cost()
-		gasPrice := tx.GasPrice()
-		if err == nil {
-			cost = new(big.Int).Mul(receipt.EffectiveGasPrice, big.NewInt(int64(receipt.GasUsed)))
-			gasPrice = receipt.EffectiveGasPrice
-		}
-		if gasPrice.Cmp(new(big.Int).Mul(block.BaseFee(), big.NewInt(MevFeeCalculationFactor))) == 1 {
-			status = "mev"
-		}
-		txCosts = new(big.Int).Add(txCosts, cost)
+		return "", err
 	}
+	return c.spec.ForkAtSlot(slotIdAsInt.Uint64()), nil
+}
 
-	reward := new(big.Int).Sub(txCosts, burntFees)
-	rewardAsFloat := new(big.Float).Quo(new(big.Float).SetInt(reward), new(big.Float).SetInt(GWEI))
-	rewardAsText := rewardAsFloat.Text('f', 9)
-	return &rewardAsText, &status, nil
+// parseSlotId converts the slot id to an integer and rejects ids that are not numbers or are
+// beyond the slot ceiling, so they fail fast without a call to the beacon node.
+func (c *Web3Client) parseSlotId(slotId string) (*big.Int, error) {
+	slotIdAsInt, ok := new(big.Int).SetString(slotId, 10)
+	if !ok || slotIdAsInt.Sign() < 0 {
+		return nil, &InvalidSlotError{msg: "Slot is invalid"}
+	}
+	if slotIdAsInt.Cmp(c.slotCeiling(c.clock.Now())) == 1 {
+		return nil, &InvalidSlotError{msg: "Slot is beyond the plausible range"}
+	}
+	return slotIdAsInt, nil
 }
 
-func (c *Web3Client) GetSyncCommitteeDuties(slotId string) ([]string, error) {
-	validatorIndexes, err := c.getSyncCommitteesValidatorIndexes(slotId)
+func (c *Web3Client) GetSyncCommitteeDuties(ctx context.Context, slotId string) (pubKeys []string, err error) {
+	ctx, span := tracer().Start(ctx, "sync committee duties", trace.WithAttributes(attribute.String("slot.id", slotId)))
+	defer func() {
+		endSpan(span, err)
+	}()
+	var cached []string
+	if c.getCached(ctx, committeeCacheKey(slotId), &cached) {
+		recordCacheOutcome(ctx, true)
+		return cached, nil
+	}
+	validatorIndexes, err := c.getSyncCommitteesValidatorIndexes(ctx, slotId)
 	if err != nil {
 		return nil, err
 	}
 
-	pubKeys, err := c.getPubKeysOfSyncCommittees(slotId, validatorIndexes)
+	duties, err := c.getPubKeysOfSyncCommittees(ctx, slotId, validatorIndexes, false)
+	if err != nil {
+		return nil, err
+	}
+	c.setCached(ctx, committeeCacheKey(slotId), duties.Pubkeys)
+	recordCacheOutcome(ctx, false)
+	return duties.Pubkeys, nil
+}
+
+// GetSyncCommitteeDutiesBestEffort is GetSyncCommitteeDuties for callers preferring a partial list
+// over an error. Validator batches that fail are skipped and their indexes are reported as
+// missing, together with indexes the beacon node does not know. Only complete lists are cached.
+func (c *Web3Client) GetSyncCommitteeDutiesBestEffort(ctx context.Context, slotId string) (duties *SyncDuties, err error) {
+	ctx, span := tracer().Start(ctx, "sync committee duties", trace.WithAttributes(attribute.String("slot.id", slotId)))
+	defer func() {
+		endSpan(span, err)
+	}()
+	var cached []string
+	if c.getCached(ctx, committeeCacheKey(slotId), &cached) {
+		recordCacheOutcome(ctx, true)
+		return &SyncDuties{Pubkeys: cached, Missing: []string{}}, nil
+	}
+	validatorIndexes, err := c.getSyncCommitteesValidatorIndexes(ctx, slotId)
+	if err != nil {
+		return nil, err
+	}
+	duties, err = c.getPubKeysOfSyncCommittees(ctx, slotId, validatorIndexes, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(duties.Missing) == 0 {
+		c.setCached(ctx, committeeCacheKey(slotId), duties.Pubkeys)
+	}
+	recordCacheOutcome(ctx, false)
+	return duties, nil
+}
+
+type Graffiti struct {
+	Text string `json:"text"`
+	Hex  string `json:"hex"`
+}
+
+// decodeGraffiti turns the 32 byte graffiti into a printable string, dropping the zero padding
+// and any invalid or non-printable characters.
+func decodeGraffiti(graffitiHex string) string {
+	graffitiBytes := bytes.TrimRight(common.FromHex(graffitiHex), "\x00")
+	return strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(string(graffitiBytes), ""))
+}
+
+func (c *Web3Client) GetGraffitiBySlot(ctx context.Context, slotId string) (*Graffiti, error) {
+	slotIdAsInt, err := c.parseSlotId(slotId)
+	if err != nil {
+		return nil, err
+	}
+	if c.isBeyondClockEpoch(slotIdAsInt, c.clock.Now()) {
+		return nil, &FutureSlotError{msg: "Slot is in the future"}
+	}
+	blockDetail, err := c.getCachedBlockDetail(ctx, slotId)
 	if err != nil {
 		return nil, err
 	}
-	return pubKeys, nil
+	graffitiHex := blockDetail.Data.Message.Body.Graffiti
+	return &Graffiti{Text: decodeGraffiti(graffitiHex), Hex: graffitiHex}, nil
 }
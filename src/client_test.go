@@ -1,15 +1,31 @@
 package main_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gin-gonic/gin"
 	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
 	"io"
+	"math/big"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type RequestBody struct {
@@ -24,6 +40,22 @@ func setupServer(testKey string) *httptest.Server {
 		rw.WriteHeader(testData.HeadersStatusCode)
 		_, _ = rw.Write([]byte(testData.HeadersResponse))
 	})
+	r.HandleFunc("/eth/v1/beacon/states/head/finality_checkpoints", func(rw http.ResponseWriter, req *http.Request) {
+		if testData.FinalityCheckpointsStatusCode == 0 {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(testData.FinalityCheckpointsStatusCode)
+		_, _ = rw.Write([]byte(testData.FinalityCheckpointsResponse))
+	})
+	r.HandleFunc("/eth/v1/beacon/rewards/blocks/{slotId}", func(rw http.ResponseWriter, req *http.Request) {
+		if testData.BlockRewardsStatusCode == 0 {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(testData.BlockRewardsStatusCode)
+		_, _ = rw.Write([]byte(testData.BlockRewardsResponse))
+	})
 	r.HandleFunc("/eth/v2/beacon/blocks/{slotId}", func(rw http.ResponseWriter, req *http.Request) {
 		rw.WriteHeader(testData.BlocksStatusCode)
 		_, _ = rw.Write([]byte(testData.BlocksResponse))
@@ -106,7 +138,7 @@ func TestGetBlockRewardAndStatusMissingSlot(t *testing.T) {
 	if status != nil || reward != nil {
 		t.Fail()
 	}
-	if err.Error() != "Slot is missing" {
+	if err.Error() != "Slot is before the merge slot 4700013" {
 		t.Fail()
 	}
 }
@@ -117,13 +149,36 @@ func TestGetBlockRewardAndStatusFutureSlot(t *testing.T) {
 	parsedUrl, _ := url.Parse(server.URL)
 	client := src.NewWeb3Client(parsedUrl, 1)
 	ctx := context.Background()
-	reward, status, err := client.GetBlockRewardAndStatusBySlot(ctx, "100000000000")
+	reward, status, err := client.GetBlockRewardAndStatusBySlot(ctx, "4700100")
 	if status != nil || reward != nil {
 		t.Fail()
 	}
-	if err.Error() != "Slot is in the future" {
+	var futureSlotError *src.FutureSlotError
+	if !errors.As(err, &futureSlotError) {
+		t.Errorf("Expected FutureSlotError, but got %v", err)
+	}
+}
+
+func TestGetBlockRewardAndStatusAbsurdSlot(t *testing.T) {
+	headerRequested := false
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		headerRequested = true
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1)
+	reward, status, err := client.GetBlockRewardAndStatusBySlot(context.Background(), "100000000000")
+	if status != nil || reward != nil {
 		t.Fail()
 	}
+	var invalidSlotError *src.InvalidSlotError
+	if !errors.As(err, &invalidSlotError) {
+		t.Errorf("Expected InvalidSlotError, but got %v", err)
+	}
+	if headerRequested {
+		t.Error("Expected the slot to be rejected without an upstream call")
+	}
 }
 
 func TestSyncDuties(t *testing.T) {
@@ -131,7 +186,7 @@ func TestSyncDuties(t *testing.T) {
 	defer server.Close()
 	parsedUrl, _ := url.Parse(server.URL)
 	client := src.NewWeb3Client(parsedUrl, 1)
-	keys, err := client.GetSyncCommitteeDuties("100000000000")
+	keys, err := client.GetSyncCommitteeDuties(context.Background(), "100000000000")
 	if len(keys) == 1 && keys[0] != "0x0000000000000000000000000000000000000000000000000000000000000001" {
 		t.Fail()
 	}
@@ -145,7 +200,7 @@ func TestSyncDutiesMissingSlot(t *testing.T) {
 	defer server.Close()
 	parsedUrl, _ := url.Parse(server.URL)
 	client := src.NewWeb3Client(parsedUrl, 1)
-	keys, err := client.GetSyncCommitteeDuties("10")
+	keys, err := client.GetSyncCommitteeDuties(context.Background(), "10")
 	if keys != nil {
 		t.Fail()
 	}
@@ -159,7 +214,7 @@ func TestSyncDutiesFutureSlot(t *testing.T) {
 	defer server.Close()
 	parsedUrl, _ := url.Parse(server.URL)
 	client := src.NewWeb3Client(parsedUrl, 1)
-	keys, err := client.GetSyncCommitteeDuties("100000000000")
+	keys, err := client.GetSyncCommitteeDuties(context.Background(), "100000000000")
 	if keys != nil {
 		t.Fail()
 	}
@@ -167,3 +222,1048 @@ func TestSyncDutiesFutureSlot(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestGetGraffitiBySlot(t *testing.T) {
+	server := setupServer("graffiti")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1)
+	graffiti, err := client.GetGraffitiBySlot(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graffiti.Text != "Lighthouse/v4.5.0" {
+		t.Errorf("Expected graffiti text to be Lighthouse/v4.5.0, but got %q", graffiti.Text)
+	}
+	if graffiti.Hex != "0x4c69676874686f757365072f76342e352e300000000000000000000000000000" {
+		t.Errorf("Expected graffiti hex to be passed through, but got %s", graffiti.Hex)
+	}
+}
+
+func TestGetGraffitiBySlotValidatesSlot(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	spec := src.MainnetChainSpec()
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithClock(src.NewFakeClock(spec.GenesisTime.Add(4700013*spec.SlotDuration()))))
+
+	for _, slotId := range []string{"head", "../4700013", "-1"} {
+		var invalidSlotError *src.InvalidSlotError
+		if _, err := client.GetGraffitiBySlot(context.Background(), slotId); !errors.As(err, &invalidSlotError) {
+			t.Errorf("Expected an invalid slot error for %q, but got %v", slotId, err)
+		}
+	}
+	if _, err := client.GetGraffitiBySlot(context.Background(), "4700113"); !errors.Is(err, src.ErrFutureSlot) {
+		t.Errorf("Expected a slot beyond the clock to be in the future, but got %v", err)
+	}
+	if requests.Load() != 0 {
+		t.Errorf("Expected no upstream request, but got %d", requests.Load())
+	}
+}
+
+func TestGetBlockRewardAndStatusMissingReceipts(t *testing.T) {
+	server := setupServer("missingReceipts")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100)
+	reward, status, err := client.GetBlockRewardAndStatusBySlot(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// legacy: (1+2)*2, access list: (1+1)*1, dynamic fee: (1+min(4-1, 1))*2, minus burnt 1*2
+	if *reward != "0.000000010" {
+		t.Errorf("Expected reward to be 0.000000010, but got %s", *reward)
+	}
+	if *status != "vanilla" {
+		t.Errorf("Expected status to be vanilla, but got %s", *status)
+	}
+}
+
+func TestGetBlockRewardAndStatusExtendedStatuses(t *testing.T) {
+	tests := []struct {
+		testKey string
+		status  string
+	}{
+		{"emptyBlock", "empty"},
+		{"missingReceipts", "unknown"},
+		{"vanilla", "vanilla"},
+		{"mev", "mev"},
+	}
+	for _, test := range tests {
+		server := setupServer(test.testKey)
+		parsedUrl, _ := url.Parse(server.URL)
+		client := src.NewWeb3Client(parsedUrl, 100, src.WithExtendedStatuses(true))
+		_, status, err := client.GetBlockRewardAndStatusBySlot(context.Background(), "4700013")
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", test.testKey, err)
+		}
+		if *status != test.status {
+			t.Errorf("%s: expected status to be %s, but got %s", test.testKey, test.status, *status)
+		}
+	}
+}
+
+func TestGetBlockRewardDetailsDeepSlot(t *testing.T) {
+	server := setupServer("detailedDeep")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100)
+	details, err := client.GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Depth != 2 {
+		t.Errorf("Expected depth to be 2, but got %d", details.Depth)
+	}
+	if !details.Finalized {
+		t.Error("Expected slot to be finalized")
+	}
+}
+
+func TestGetBlockRewardDetailsHeadSlot(t *testing.T) {
+	server := setupServer("detailedHead")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100)
+	details, err := client.GetBlockRewardDetails(context.Background(), "4700015")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Depth != 0 {
+		t.Errorf("Expected depth to be 0, but got %d", details.Depth)
+	}
+	if details.Finalized {
+		t.Error("Expected head slot not to be finalized")
+	}
+}
+
+func TestGetBlockRewardDetailsFinalityStatus(t *testing.T) {
+	upstream := setupServer("detailedDeep")
+	defer upstream.Close()
+	// slot 4700013 is in epoch 146875, which starts at slot 4700000
+	tests := []struct {
+		finalizedEpoch string
+		justifiedEpoch string
+		status         string
+	}{
+		{"146876", "146877", src.FinalityFinalized},
+		{"146875", "146876", src.FinalitySafe},
+		{"146874", "146875", src.FinalityUnsafe},
+	}
+	for _, tt := range tests {
+		checkpoints := `{"data":{"current_justified":{"epoch":"` + tt.justifiedEpoch + `","root":"0x02"},` +
+			`"finalized":{"epoch":"` + tt.finalizedEpoch + `","root":"0x01"}}}`
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/eth/v1/beacon/states/head/finality_checkpoints" {
+				_, _ = rw.Write([]byte(checkpoints))
+				return
+			}
+			upstream.Config.Handler.ServeHTTP(rw, req)
+		}))
+		parsedUrl, _ := url.Parse(server.URL)
+		details, err := src.NewWeb3Client(parsedUrl, 1000).GetBlockRewardDetails(context.Background(), "4700013")
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details.FinalityStatus != tt.status {
+			t.Errorf("Expected %s with finalized epoch %s and justified epoch %s, but got %s",
+				tt.status, tt.finalizedEpoch, tt.justifiedEpoch, details.FinalityStatus)
+		}
+	}
+}
+
+func TestRateBurst(t *testing.T) {
+	server := setupServer("graffiti")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	sendBurst := func(client *src.Web3Client) time.Duration {
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			// the cached block would spare the upstream requests the limiter is measured on
+			if _, err := client.GetGraffitiBySlot(src.WithCacheBypass(context.Background()), "4700013"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return time.Since(start)
+	}
+	if elapsed := sendBurst(src.NewWeb3Client(parsedUrl, 5, src.WithRateBurst(3))); elapsed > 150*time.Millisecond {
+		t.Errorf("Expected burst of 3 requests not to be serialized, but it took %s", elapsed)
+	}
+	if elapsed := sendBurst(src.NewWeb3Client(parsedUrl, 5)); elapsed < 350*time.Millisecond {
+		t.Errorf("Expected requests to be serialized with the default burst, but it took %s", elapsed)
+	}
+}
+
+func TestBeaconUint64AcceptsQuotedAndUnquotedNumbers(t *testing.T) {
+	for _, data := range []string{`"4700013"`, `4700013`} {
+		var value src.BeaconUint64
+		if err := json.Unmarshal([]byte(data), &value); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		if value != 4700013 {
+			t.Errorf("%s: expected 4700013, but got %d", data, value)
+		}
+	}
+	var value src.BeaconUint64
+	if err := json.Unmarshal([]byte(`"0x10"`), &value); err == nil {
+		t.Error("Expected a non decimal value to be rejected")
+	}
+}
+
+func TestGetCurrentSlotAcceptsUnquotedSlot(t *testing.T) {
+	for _, slot := range []string{`"4700015"`, `4700015`} {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, _ = rw.Write([]byte(`{"data":[{"header":{"message":{"slot":` + slot + `}}}]}`))
+		}))
+		parsedUrl, _ := url.Parse(server.URL)
+		head, err := src.CheckUpstream(context.Background(), src.NewWeb3Client(parsedUrl, 100))
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", slot, err)
+		}
+		if head.Int64() != 4700015 {
+			t.Errorf("%s: expected head slot 4700015, but got %s", slot, head)
+		}
+	}
+}
+
+func TestGetBlockRewardDetailsIsDeterministic(t *testing.T) {
+	upstream := setupServer("manyTransactions")
+	defer upstream.Close()
+	// receipts arrive in a different order on every run
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 10000, src.WithRateBurst(100))
+	var expected []byte
+	for i := 0; i < 30; i++ {
+		details, err := client.GetBlockRewardDetails(context.Background(), "4700013")
+		if err != nil {
+			t.Fatal(err)
+		}
+		details.Timings = src.RewardTimings{}
+		output, _ := json.Marshal(details)
+		if expected == nil {
+			expected = output
+			continue
+		}
+		if string(output) != string(expected) {
+			t.Fatalf("Expected identical output on every run, but got %s and %s", expected, output)
+		}
+	}
+	var details src.BlockRewardDetails
+	_ = json.Unmarshal(expected, &details)
+	if details.Status != "mev" || details.Reward.Int64() != 14 || len(details.Transactions) != 4 {
+		t.Errorf("Unexpected status %s, reward %s or transaction count %d", details.Status, details.Reward, len(details.Transactions))
+	}
+}
+
+func TestErrorsMatchSentinels(t *testing.T) {
+	tests := []struct {
+		testKey  string
+		slotId   string
+		sentinel error
+	}{
+		{"rewardMissingSlot", "4700012", src.ErrSlotMissing},
+		{"rewardFutureSlot", "4700100", src.ErrFutureSlot},
+		{"rewardFutureSlot", "abc", src.ErrInvalidSlot},
+	}
+	for _, test := range tests {
+		server := setupServer(test.testKey)
+		parsedUrl, _ := url.Parse(server.URL)
+		_, _, err := src.NewWeb3Client(parsedUrl, 100).GetBlockRewardAndStatusBySlot(context.Background(), test.slotId)
+		server.Close()
+		if !errors.Is(err, test.sentinel) {
+			t.Errorf("Expected error of slot %s to match %v, but got %v", test.slotId, test.sentinel, err)
+		}
+		for _, other := range []error{src.ErrSlotMissing, src.ErrFutureSlot, src.ErrInvalidSlot} {
+			if other != test.sentinel && errors.Is(err, other) {
+				t.Errorf("Expected error of slot %s not to match %v", test.slotId, other)
+			}
+		}
+	}
+	wrapped := fmt.Errorf("lookup failed: %w", &src.FutureSlotError{})
+	var futureSlotError *src.FutureSlotError
+	if !errors.Is(wrapped, src.ErrFutureSlot) || !errors.As(wrapped, &futureSlotError) {
+		t.Error("Expected a wrapped FutureSlotError to match both the sentinel and the type")
+	}
+}
+
+func TestRequestCostsWeighRateLimit(t *testing.T) {
+	r := mux.NewRouter()
+	r.HandleFunc("/eth/v1/beacon/headers", func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`{"data":[{"header":{"message":{"slot":"4700015"}}}]}`))
+	})
+	r.HandleFunc("/eth/v1/beacon/states/{slotId}/validators", func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`{"data": []}`))
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	measure := func(call func(client *src.Web3Client) error) time.Duration {
+		client := src.NewWeb3Client(parsedUrl, 20, src.WithRequestCosts(src.RequestCosts{"validators": 4}))
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			if err := call(client); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return time.Since(start)
+	}
+	light := measure(func(client *src.Web3Client) error {
+		_, err := src.CheckUpstream(context.Background(), client)
+		return err
+	})
+	heavy := measure(func(client *src.Web3Client) error {
+		_, _, err := client.ResolveValidatorIndexes(context.Background(), "head", []string{"0x01"})
+		return err
+	})
+	// at 20 tokens per second, 3 headers calls wait for 2 tokens and 3 validators calls for 11
+	if light > 250*time.Millisecond || heavy < 500*time.Millisecond {
+		t.Errorf("Expected validators calls to consume more tokens, but headers took %s and validators %s", light, heavy)
+	}
+}
+
+func TestClientRejectsSlotsBeyondClockEpochWithoutHeadLookup(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var headLookups atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/eth/v1/beacon/headers" {
+			headLookups.Add(1)
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+	// the clock is in the middle of slot 4700048, the 17th slot of epoch 146876
+	genesis := time.Now().Unix() - 4700048*12 - 6
+	_ = client.LoadSpec(context.Background(), src.SpecOverrides{GenesisTime: genesis})
+
+	tests := []struct {
+		slotId      string
+		headLookups int32
+	}{
+		{"4700127", 1}, // last slot of epoch 146878, within the margin
+		{"4700128", 0}, // first slot of epoch 146879
+	}
+	for _, tt := range tests {
+		headLookups.Store(0)
+		_, _, err := client.GetBlockRewardAndStatusBySlot(context.Background(), tt.slotId)
+		if !errors.Is(err, src.ErrFutureSlot) {
+			t.Errorf("Expected slot %s to be in the future, but got %v", tt.slotId, err)
+		}
+		if headLookups.Load() != tt.headLookups {
+			t.Errorf("Expected %d head lookups for slot %s, but got %d", tt.headLookups, tt.slotId, headLookups.Load())
+		}
+	}
+}
+
+func TestNewWeb3ClientDialTimesOut(t *testing.T) {
+	// the listener accepts connections but never answers the websocket handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	parsedUrl, _ := url.Parse("ws://" + listener.Addr().String())
+	start := time.Now()
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithDialTimeout(100*time.Millisecond))
+	if client != nil {
+		t.Error("Expected the dial to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the dial to time out, but it took %s", elapsed)
+	}
+}
+
+func TestBlockRewardDetailsAreTruncatedPastReceiptCap(t *testing.T) {
+	upstream := setupServer("hugeBlock")
+	defer upstream.Close()
+	var receiptCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		if bytes.Contains(body, []byte("eth_getTransactionReceipt")) {
+			receiptCalls.Add(1)
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100000, src.WithRateBurst(1000), src.WithMaxReceiptCalls(10))
+
+	details, err := client.GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receiptCalls.Load() != 10 {
+		t.Errorf("Expected 10 receipt calls, but got %d", receiptCalls.Load())
+	}
+	if !details.Truncated || details.TransactionCount != 50 {
+		t.Errorf("Expected a truncated result over 50 transactions, but got %+v", details)
+	}
+	// 10 receipts pay 4 wei each, the 40 estimated transactions pay the base fee of 1 wei each
+	// and 2 wei are burnt
+	if details.Reward.Cmp(big.NewInt(78)) != 0 {
+		t.Errorf("Expected reward to be 78 wei, but got %s", details.Reward)
+	}
+}
+
+func TestGetBlockRewardWei(t *testing.T) {
+	server := setupServer("rewardOverflow")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+	ctx := context.Background()
+	reward, status, err := client.GetBlockRewardWei(ctx, "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the receipt pays 2^64 wei per gas against a base fee of 1 wei and 2 wei are burnt
+	expected, _ := new(big.Int).SetString("18446744073709551614", 10)
+	if reward.Cmp(expected) != 0 || status != "mev" {
+		t.Errorf("Expected %s wei and mev, but got %s wei and %s", expected, reward, status)
+	}
+	formatted, formattedStatus, err := client.GetBlockRewardAndStatusBySlot(ctx, "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *formatted != "18446744073.709551614" || *formattedStatus != status {
+		t.Errorf("Expected the formatted reward to match, but got %s and %s", *formatted, *formattedStatus)
+	}
+}
+
+func TestClientRedirectPolicy(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	other := httptest.NewServer(upstream.Config.Handler)
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/eth/v2/beacon/blocks/4700013":
+			http.Redirect(rw, req, "/moved/4700013", http.StatusFound)
+		case "/moved/4700013":
+			http.Redirect(rw, req, "/eth/v2/beacon/blocks/moved", http.StatusMovedPermanently)
+		case "/eth/v2/beacon/blocks/4700014":
+			http.Redirect(rw, req, other.URL+"/eth/v2/beacon/blocks/4700014", http.StatusFound)
+		default:
+			upstream.Config.Handler.ServeHTTP(rw, req)
+		}
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	ctx := context.Background()
+
+	tests := []struct {
+		maxRedirects int
+		slotId       string
+		rejected     bool
+	}{
+		{2, "4700013", false},
+		{1, "4700013", true},
+		{0, "4700013", true},
+		{2, "4700014", true},
+	}
+	for _, tt := range tests {
+		client := src.NewWeb3Client(parsedUrl, 1000, src.WithMaxRedirects(tt.maxRedirects))
+		_, err := client.GetBlockNumberBySlot(ctx, tt.slotId)
+		if tt.rejected != errors.Is(err, src.ErrRedirectRejected) || (!tt.rejected && err != nil) {
+			t.Errorf("%d redirects to slot %s: expected rejected %t, but got %v", tt.maxRedirects, tt.slotId, tt.rejected, err)
+		}
+	}
+
+	// two redirects make three requests, at 20 per second with a burst of 1 they take 100ms
+	client := src.NewWeb3Client(parsedUrl, 20, src.WithMaxRedirects(2))
+	start := time.Now()
+	if _, err := client.GetBlockNumberBySlot(ctx, "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected redirected requests to wait for the rate limiter, but took %s", elapsed)
+	}
+}
+
+func TestClientSendsConfiguredHeaders(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var mu sync.Mutex
+	headers := map[string]http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		headers[req.Method] = req.Header.Clone()
+		mu.Unlock()
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	ctx := context.Background()
+
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithRateBurst(10))
+	if _, _, err := client.GetBlockRewardAndStatusBySlot(ctx, "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if userAgent := headers[method].Get("User-Agent"); userAgent != src.DefaultUserAgent() {
+			t.Errorf("Expected %s requests to send User-Agent %s, but got %s", method, src.DefaultUserAgent(), userAgent)
+		}
+	}
+	if accept := headers[http.MethodGet].Get("Accept"); accept != "application/json" {
+		t.Errorf("Expected beacon requests to accept application/json, but got %s", accept)
+	}
+
+	client = src.NewWeb3Client(parsedUrl, 1000, src.WithRateBurst(10), src.WithUserAgent("operator/1.0"),
+		src.WithBeaconAccept("application/json;version=2"))
+	if _, _, err := client.GetBlockRewardAndStatusBySlot(ctx, "4700014"); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if userAgent := headers[method].Get("User-Agent"); userAgent != "operator/1.0" {
+			t.Errorf("Expected %s requests to send the configured User-Agent, but got %s", method, userAgent)
+		}
+	}
+	if accept := headers[http.MethodGet].Get("Accept"); accept != "application/json;version=2" {
+		t.Errorf("Expected beacon requests to send the configured Accept, but got %s", accept)
+	}
+	if accept := headers[http.MethodPost].Get("Accept"); accept != "application/json" {
+		t.Errorf("Expected rpc requests to keep accepting application/json, but got %s", accept)
+	}
+}
+
+func TestBlockRewardDetailsAreApproximateWithoutReceipts(t *testing.T) {
+	upstream := setupServer("hugeBlock")
+	defer upstream.Close()
+	var receiptCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		var call struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if json.Unmarshal(body, &call) == nil && call.Method == "eth_getTransactionReceipt" {
+			receiptCalls.Add(1)
+			_, _ = rw.Write([]byte(`{"jsonrpc":"2.0","id":` + string(call.ID) +
+				`,"error":{"code":-32601,"message":"the method eth_getTransactionReceipt does not exist/is not available"}}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100000, src.WithRateBurst(1000), src.WithReceiptConcurrency(1))
+
+	details, err := client.GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receiptCalls.Load() != 1 {
+		t.Errorf("Expected receipt calls to stop after the first rejection, but got %d", receiptCalls.Load())
+	}
+	if !details.Approximate {
+		t.Error("Expected the result to be flagged as approximate")
+	}
+	// all 50 transactions are estimated at the base fee of 1 wei and 2 wei are burnt
+	if details.Reward.Cmp(big.NewInt(48)) != 0 {
+		t.Errorf("Expected reward to be 48 wei, but got %s", details.Reward)
+	}
+}
+
+func TestBlockRewardDetailsTellPrunedReceiptsFromFailedOnes(t *testing.T) {
+	upstream := setupServer("hugeBlock")
+	defer upstream.Close()
+	var receiptCalls atomic.Int32
+	var failEvery atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		var call struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if json.Unmarshal(body, &call) == nil && call.Method == "eth_getTransactionReceipt" &&
+			(receiptCalls.Add(1) == 1 || failEvery.Load()) {
+			_, _ = rw.Write([]byte(`{"jsonrpc":"2.0","id":` + string(call.ID) +
+				`,"error":{"code":-32000,"message":"receipt does not exist, it may have been pruned"}}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+
+	// a single failed receipt is an error, not a node without receipts
+	_, err := src.NewWeb3Client(parsedUrl, 100000, src.WithRateBurst(1000)).GetBlockRewardDetails(context.Background(), "4700013")
+	if err == nil || !strings.Contains(err.Error(), "pruned") {
+		t.Errorf("Expected the failed receipt to be reported, but got %v", err)
+	}
+
+	failEvery.Store(true)
+	details, err := src.NewWeb3Client(parsedUrl, 100000, src.WithRateBurst(1000)).GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !details.Approximate || details.ReceiptFallbacks != 50 {
+		t.Errorf("Expected all 50 transactions to be estimated, but got approximate %t with %d fallbacks", details.Approximate, details.ReceiptFallbacks)
+	}
+}
+
+func TestCoinbaseTransfersLeaveOutWithdrawals(t *testing.T) {
+	server := setupServer("coinbaseTransfer")
+	defer server.Close()
+	ethClient, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ethClient.BlockByHash(context.Background(), common.HexToHash("0x1111"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block.Withdrawals()) != 1 || block.Withdrawals()[0].Address != block.Coinbase() {
+		t.Fatalf("Expected a withdrawal to the fee recipient, but got %v", block.Withdrawals())
+	}
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+
+	details, err := client.GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.CoinbaseTransfers.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("Expected only the 7 wei of the transaction, but got %s", details.CoinbaseTransfers)
+	}
+}
+
+func TestBlockRewardDetailsCountSlashings(t *testing.T) {
+	server := setupServer("slashings")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+
+	details, err := client.GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.ProposerIndex != "4242" || details.ProposerSlashings != 1 || details.AttesterSlashings != 2 {
+		t.Errorf("Expected proposer 4242 with 1 proposer and 2 attester slashings, but got %s %d %d",
+			details.ProposerIndex, details.ProposerSlashings, details.AttesterSlashings)
+	}
+
+	plain := setupServer("coinbaseTransfer")
+	defer plain.Close()
+	parsedUrl, _ = url.Parse(plain.URL)
+	details, err = src.NewWeb3Client(parsedUrl, 1000).GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.ProposerSlashings != 0 || details.AttesterSlashings != 0 {
+		t.Errorf("Expected no slashings, but got %d %d", details.ProposerSlashings, details.AttesterSlashings)
+	}
+}
+
+func TestBlockRewardDetailsReportNoOmmers(t *testing.T) {
+	server := setupServer("coinbaseTransfer")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+
+	details, err := src.NewWeb3Client(parsedUrl, 1000).GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Ommers != 0 || !bytes.Contains(encoded, []byte(`"ommers":0`)) {
+		t.Errorf("Expected 0 ommers to be reported for a merge-era block, but got %d in %s", details.Ommers, encoded)
+	}
+}
+
+func TestBlockRewardDetailsCountReceiptFallbacks(t *testing.T) {
+	upstream := setupServer("hugeBlock")
+	defer upstream.Close()
+	var receiptCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		var call struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		// the node does not know the receipt of the first transaction asked for
+		if json.Unmarshal(body, &call) == nil && call.Method == "eth_getTransactionReceipt" && receiptCalls.Add(1) == 1 {
+			_, _ = rw.Write([]byte(`{"jsonrpc":"2.0","id":` + string(call.ID) + `,"result":null}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100000, src.WithRateBurst(1000))
+
+	details, err := client.GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.ReceiptsComplete || details.ReceiptFallbacks != 1 {
+		t.Errorf("Expected one receipt fallback, but got complete %t with %d fallbacks", details.ReceiptsComplete, details.ReceiptFallbacks)
+	}
+
+	details, err = src.NewWeb3Client(parsedUrl, 100000, src.WithRateBurst(1000)).GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !details.ReceiptsComplete || details.ReceiptFallbacks != 0 {
+		t.Errorf("Expected complete receipts, but got complete %t with %d fallbacks", details.ReceiptsComplete, details.ReceiptFallbacks)
+	}
+}
+
+func TestBlockRewardAbandonsReceiptsOnCancel(t *testing.T) {
+	upstream := setupServer("hugeBlock")
+	defer upstream.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var receiptCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		// the request is cancelled while the third of the 50 receipts is fetched
+		if bytes.Contains(body, []byte("eth_getTransactionReceipt")) && receiptCalls.Add(1) == 3 {
+			cancel()
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	cache := src.NewMemoryCache()
+	client := src.NewWeb3Client(parsedUrl, 100000, src.WithRateBurst(1000), src.WithReceiptConcurrency(1), src.WithCache(cache))
+
+	_, _, err := client.GetBlockRewardAndStatusBySlot(ctx, "4700013")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the request to be cancelled, but got %v", err)
+	}
+	if receiptCalls.Load() != 3 {
+		t.Errorf("Expected the remaining receipts not to be fetched, but got %d receipt calls", receiptCalls.Load())
+	}
+	if _, ok := cache.Get(context.Background(), "reward:4700013"); ok {
+		t.Error("Expected no reward to be cached")
+	}
+}
+
+func TestGetBlockRewardRetriesEmptyPayloadHash(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var blockRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// the first beacon block is served before the payload is known
+		if req.URL.Path == "/eth/v2/beacon/blocks/4700013" && blockRequests.Add(1) == 1 {
+			_, _ = rw.Write([]byte(`{"data":{"message":{"body":{"execution_payload":{"block_hash":"0x0000000000000000000000000000000000000000000000000000000000000000"}}}}}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+
+	reward, _, err := src.NewWeb3Client(parsedUrl, 1000).GetBlockRewardWei(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reward.Int64() != 1 || blockRequests.Load() != 2 {
+		t.Errorf("Expected reward 1 after 2 block requests, but got %s after %d", reward, blockRequests.Load())
+	}
+
+	blockRequests.Store(0)
+	_, _, err = src.NewWeb3Client(parsedUrl, 1000, src.WithPayloadRetries(0)).GetBlockRewardWei(context.Background(), "4700013")
+	if !errors.Is(err, src.ErrPayloadUnavailable) || blockRequests.Load() != 1 {
+		t.Errorf("Expected the payload to be unavailable after 1 block request, but got %v after %d", err, blockRequests.Load())
+	}
+}
+
+func TestGetBlockRewardRejectsBlockOfAnotherSlot(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// a proxy in front of the node answers with the block of the next slot
+		if strings.HasPrefix(req.URL.Path, "/eth/v2/beacon/blocks/") {
+			_, _ = rw.Write([]byte(`{"data":{"message":{"slot":"4700014","body":{"execution_payload":{"block_hash":"1111"}}}}}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+
+	_, _, err := client.GetBlockRewardWei(context.Background(), "4700013")
+	var mismatched *src.MismatchedSlotError
+	if !errors.Is(err, src.ErrMismatchedSlot) || !errors.As(err, &mismatched) {
+		t.Errorf("Expected a mismatched slot error, but got %v", err)
+	}
+	if _, _, err := client.GetBlockRewardWei(context.Background(), "4700014"); err != nil {
+		t.Errorf("Expected the block of the requested slot to be accepted, but got %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(client))
+	if recorder := performRequest(router, "/blockreward/4700013"); recorder.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+// setupLargeBlockServer serves a block of 1000 transactions whose receipts carry 8 KiB of logs
+// each, and records the most receipt calls in flight at once.
+func setupLargeBlockServer(tb testing.TB, maxInFlight *atomic.Int32) *httptest.Server {
+	upstream := setupServer("largeBlock")
+	tb.Cleanup(upstream.Close)
+	var inFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		if bytes.Contains(body, []byte("eth_getTransactionReceipt")) {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				seen := maxInFlight.Load()
+				if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+					break
+				}
+			}
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	tb.Cleanup(server.Close)
+	return server
+}
+
+func TestLargeBlockRewardKeepsReceiptsBounded(t *testing.T) {
+	var maxInFlight atomic.Int32
+	server := setupLargeBlockServer(t, &maxInFlight)
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000000, src.WithRateBurst(1000), src.WithReceiptConcurrency(4),
+		src.WithMaxReceiptCalls(1000))
+
+	reward, status, err := client.GetBlockRewardWei(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1000 receipts pay 4 wei each and 2 wei are burnt
+	if reward.Cmp(big.NewInt(3998)) != 0 || status != "mev" {
+		t.Errorf("Expected a mev reward of 3998 wei, but got %s %s", reward, status)
+	}
+	if maxInFlight.Load() > 4 {
+		t.Errorf("Expected at most 4 receipts in flight, but got %d", maxInFlight.Load())
+	}
+}
+
+func BenchmarkLargeBlockReward(b *testing.B) {
+	var maxInFlight atomic.Int32
+	server := setupLargeBlockServer(b, &maxInFlight)
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000000, src.WithRateBurst(1000), src.WithMaxReceiptCalls(1000))
+	ctx := src.WithCacheBypass(context.Background())
+	// the debug timing logs of thousands of receipt calls would drown the results
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	defer zerolog.SetGlobalLevel(level)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := client.GetBlockRewardWei(ctx, "4700013"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestBlockRewardWithoutEffectiveGasPrice(t *testing.T) {
+	server := setupServer("receiptWithoutGasPrice")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+
+	details, err := src.NewWeb3Client(parsedUrl, 1000).GetBlockRewardDetails(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// with a base fee of 1, the legacy transaction pays its gas price of 4 and the dynamic fee
+	// transaction min(5, 1+2) = 3, each for 2 gas, and 4 wei are burnt
+	if details.Fees.Int64() != 14 || details.Reward.Int64() != 10 || !details.ReceiptsComplete {
+		t.Errorf("Expected fees of 14 and a reward of 10 wei from complete receipts, but got %s and %s", details.Fees, details.Reward)
+	}
+	if len(details.Transactions) != 2 || details.Transactions[0].GasPrice.Int64() != 4 || details.Transactions[1].GasPrice.Int64() != 3 {
+		t.Errorf("Expected gas prices of 4 and 3, but got %+v", details.Transactions)
+	}
+}
+
+func TestMergeSlotBoundary(t *testing.T) {
+	server := setupServer("vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	tests := []struct {
+		mergeSlot uint64
+		slotId    string
+		preMerge  bool
+	}{
+		// the merge slot is inclusive, the slot before it is the last pre-merge slot
+		{src.DefaultMergeSlot, "4700012", true},
+		{src.DefaultMergeSlot, "4700013", false},
+		{src.DefaultMergeSlot, "4700014", false},
+		{4700014, "4700013", true},
+		{4700014, "4700014", false},
+	}
+	for _, test := range tests {
+		client := src.NewWeb3Client(parsedUrl, 1000, src.WithMergeSlot(test.mergeSlot))
+		reward, _, err := client.GetBlockRewardWei(context.Background(), test.slotId)
+		if test.preMerge {
+			if !errors.Is(err, src.ErrPreMergeSlot) || !errors.Is(err, src.ErrSlotMissing) {
+				t.Errorf("Expected slot %s to be before merge slot %d, but got %v", test.slotId, test.mergeSlot, err)
+			}
+			expected := fmt.Sprintf("Slot is before the merge slot %d", test.mergeSlot)
+			if err != nil && err.Error() != expected {
+				t.Errorf("Expected error %q, but got %q", expected, err.Error())
+			}
+			continue
+		}
+		if err != nil || reward.Int64() != 1 {
+			t.Errorf("Expected slot %s to have a reward of 1 wei with merge slot %d, but got %v %v", test.slotId, test.mergeSlot, reward, err)
+		}
+	}
+}
+
+type recordingTransport struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.methods = append(rt.methods, req.Method)
+	rt.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClientUsesInjectedHTTPClient(t *testing.T) {
+	server := setupServer("vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	options := map[string]func(*http.Client) src.Option{
+		"wrapped":   src.WithHTTPClient,
+		"unwrapped": src.WithUnwrappedHTTPClient,
+	}
+	for name, option := range options {
+		transport := &recordingTransport{}
+		client := src.NewWeb3Client(parsedUrl, 1000, option(&http.Client{Transport: transport}))
+		if _, _, err := client.GetBlockRewardWei(context.Background(), "4700013"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// the beacon API is read with GET, the rpc client posts its calls
+		transport.mu.Lock()
+		if !slices.Contains(transport.methods, http.MethodGet) || !slices.Contains(transport.methods, http.MethodPost) {
+			t.Errorf("%s: Expected beacon and rpc requests through the injected client, but got %v", name, transport.methods)
+		}
+		transport.mu.Unlock()
+	}
+}
+
+func TestSyncingNodeAnswersServiceUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == src.NodeSyncingPath {
+			_, _ = rw.Write([]byte(`{"data":{"head_slot":"4698779","sync_distance":"1234","is_syncing":true}}`))
+			return
+		}
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = rw.Write([]byte(`{"code":503,"message":"Beacon node is currently syncing and not serving request on that endpoint"}`))
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+
+	_, _, err := client.GetBlockRewardWei(context.Background(), "4700013")
+	var syncingError *src.NodeSyncingError
+	if !errors.As(err, &syncingError) || syncingError.SyncDistance == nil || *syncingError.SyncDistance != 1234 {
+		t.Fatalf("Expected a syncing error with a sync distance of 1234, but got %v", err)
+	}
+	router := gin.New()
+	router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(client))
+	recorder := performRequest(router, "/blockreward/4700013")
+	expected := `{"error":"Beacon node is syncing, 1234 slots behind the head"}`
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Body.String() != expected {
+		t.Errorf("Expected 503 %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestBlockRewardRetriesReorgedBlockHash(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	staleHash := "0x" + strings.Repeat("11", 32)
+	canonicalHash := "0x" + strings.Repeat("22", 32)
+	var mu sync.Mutex
+	var staleLookups int
+	blockHashes := []string{staleHash}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/eth/v2/beacon/blocks/4700013" {
+			mu.Lock()
+			blockHash := blockHashes[0]
+			if len(blockHashes) > 1 {
+				blockHashes = blockHashes[1:]
+			}
+			mu.Unlock()
+			_, _ = rw.Write([]byte(`{"data":{"message":{"body":{"execution_payload":{"block_hash":"` + blockHash + `"}}}}}`))
+			return
+		}
+		body, _ := io.ReadAll(req.Body)
+		// the execution client no longer knows the orphaned block
+		if bytes.Contains(body, []byte(staleHash)) {
+			mu.Lock()
+			staleLookups++
+			mu.Unlock()
+			_, _ = rw.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+
+	// the slot resolves to the stale hash once, then to the canonical block
+	mu.Lock()
+	blockHashes = []string{staleHash, canonicalHash}
+	mu.Unlock()
+	reward, _, err := src.NewWeb3Client(parsedUrl, 1000).GetBlockRewardWei(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reward.Int64() != 1 || staleLookups != 1 {
+		t.Errorf("Expected the reward of the canonical block after one stale lookup, but got %s after %d", reward, staleLookups)
+	}
+
+	// a hash that stays stale fails after the retries
+	mu.Lock()
+	blockHashes, staleLookups = []string{staleHash}, 0
+	mu.Unlock()
+	_, _, err = src.NewWeb3Client(parsedUrl, 1000, src.WithReorgRetries(1)).GetBlockRewardWei(context.Background(), "4700013")
+	if !errors.Is(err, ethereum.NotFound) || staleLookups != 2 {
+		t.Errorf("Expected not found after 2 lookups, but got %v after %d", err, staleLookups)
+	}
+}
@@ -0,0 +1,149 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheExpiresByClock(t *testing.T) {
+	clock := src.NewFakeClock(time.Now())
+	cache := src.NewMemoryCacheWithClock(clock)
+	ctx := context.Background()
+	cache.Set(ctx, "key", []byte("value"), time.Minute)
+	clock.Advance(time.Minute - time.Nanosecond)
+	if _, ok := cache.Get(ctx, "key"); !ok {
+		t.Error("Expected the entry to be cached until its ttl passed")
+	}
+	clock.Advance(time.Nanosecond)
+	if _, ok := cache.Get(ctx, "key"); ok {
+		t.Error("Expected the entry to expire once its ttl passed")
+	}
+}
+
+func TestRecentRewardExpiresByClock(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var receiptRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		if bytes.Contains(body, []byte("eth_getTransactionReceipt")) {
+			receiptRequests.Add(1)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	clock := src.NewFakeClock(time.Now())
+	// the finality of the slot is unknown, so its reward is cached for the recent ttl of a minute
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithClock(clock))
+	computeReward := func() {
+		if _, _, err := client.GetBlockRewardWei(context.Background(), "4700013"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	computeReward()
+	clock.Advance(src.DefaultRecentCacheTTL - time.Second)
+	computeReward()
+	if receiptRequests.Load() != 1 {
+		t.Fatalf("Expected the reward to be served from the cache before its ttl, but got %d computations", receiptRequests.Load())
+	}
+	clock.Advance(time.Second)
+	computeReward()
+	if receiptRequests.Load() != 2 {
+		t.Errorf("Expected the reward to be computed again after its ttl, but got %d computations", receiptRequests.Load())
+	}
+}
+
+func TestClockSlotFollowsClock(t *testing.T) {
+	parsedUrl, _ := url.Parse("http://127.0.0.1:1")
+	spec := src.MainnetChainSpec()
+	clock := src.NewFakeClock(spec.GenesisTime.Add(4700013 * spec.SlotDuration()))
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithClock(clock))
+
+	// slot 4700013 is in sync committee period 573, slots 4694016 to 4702207
+	period, err := client.GetSyncPeriod("")
+	if err != nil || period.CurrentPeriod != "573" {
+		t.Fatalf("Expected the clock slot to be in period 573, but got %+v %v", period, err)
+	}
+	clock.Advance(time.Duration(spec.SlotsPerEpoch*spec.EpochsPerSyncCommitteePeriod) * spec.SlotDuration())
+	period, err = client.GetSyncPeriod("")
+	if err != nil || period.CurrentPeriod != "574" {
+		t.Errorf("Expected the clock slot to move to period 574, but got %+v %v", period, err)
+	}
+	// slots epochs past the clock slot are rejected as future without contacting the upstream
+	if _, _, err := client.GetBlockRewardWei(context.Background(), "4710000"); !errors.Is(err, src.ErrFutureSlot) {
+		t.Errorf("Expected a slot beyond the clock to be in the future, but got %v", err)
+	}
+}
+
+func TestPayloadRetryWaitsOnClock(t *testing.T) {
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var blockRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// the first beacon block is served before the payload is known
+		if req.URL.Path == "/eth/v2/beacon/blocks/4700013" && blockRequests.Add(1) == 1 {
+			_, _ = rw.Write([]byte(`{"data":{"message":{"body":{"execution_payload":{"block_hash":"0x0000000000000000000000000000000000000000000000000000000000000000"}}}}}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	clock := src.NewFakeClock(time.Now())
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithClock(clock))
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.GetBlockRewardWei(context.Background(), "4700013")
+		done <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the payload retry to wait on the clock")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if blockRequests.Load() != 1 {
+		t.Errorf("Expected the retry to wait for the clock, but got %d block requests", blockRequests.Load())
+	}
+	clock.Advance(src.PayloadRetryDelay)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if blockRequests.Load() != 2 {
+		t.Errorf("Expected the retry once the clock advanced, but got %d block requests", blockRequests.Load())
+	}
+}
+
+func TestFakeClockFiresAfterOnceAdvanced(t *testing.T) {
+	clock := src.NewFakeClock(time.Now())
+	fired := clock.After(time.Second)
+	clock.Advance(time.Second - time.Nanosecond)
+	select {
+	case <-fired:
+		t.Fatal("Expected After not to fire before its duration passed")
+	default:
+	}
+	clock.Advance(time.Nanosecond)
+	select {
+	case <-fired:
+	default:
+		t.Error("Expected After to fire once its duration passed")
+	}
+	if clock.Waiters() != 0 {
+		t.Errorf("Expected no pending waiter, but got %d", clock.Waiters())
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/sync/errgroup"
+	"math/big"
+)
+
+// RewardComparison holds the rewards of two slots in gwei and the reward of B minus the reward of
+// A. A slot without a reward reports why in its entry and leaves the difference null.
+type RewardComparison struct {
+	A          SlotReward `json:"a"`
+	B          SlotReward `json:"b"`
+	Difference *string    `json:"difference"`
+}
+
+// CompareBlockRewards computes the rewards of both slots concurrently. A slot that is missing or
+// in the future only fails its own entry, invalid slot ids and upstream failures fail the
+// comparison.
+func (c *Web3Client) CompareBlockRewards(ctx context.Context, slotA string, slotB string) (*RewardComparison, error) {
+	slotIds := []string{slotA, slotB}
+	for _, slotId := range slotIds {
+		if _, err := c.parseSlotId(slotId); err != nil {
+			return nil, err
+		}
+	}
+	entries := make([]SlotReward, len(slotIds))
+	rewards := make([]*big.Int, len(slotIds))
+	group, ctx := errgroup.WithContext(ctx)
+	for i, slotId := range slotIds {
+		group.Go(func() error {
+			entries[i] = SlotReward{Slot: slotId}
+			reward, status, err := c.GetBlockRewardWei(ctx, slotId)
+			if errors.Is(err, ErrSlotMissing) || errors.Is(err, ErrFutureSlot) {
+				entries[i].Error = err.Error()
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			rewardAsText := new(big.Rat).SetFrac(reward, GWEI).FloatString(9)
+			entries[i].Reward, entries[i].Status, rewards[i] = &rewardAsText, &status, reward
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	comparison := &RewardComparison{A: entries[0], B: entries[1]}
+	if rewards[0] != nil && rewards[1] != nil {
+		difference := new(big.Rat).SetFrac(new(big.Int).Sub(rewards[1], rewards[0]), GWEI).FloatString(9)
+		comparison.Difference = &difference
+	}
+	return comparison, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/url"
+)
+
+// Redacted replaces secrets in the reported configuration, like net/url does for passwords.
+const Redacted = "xxxxx"
+
+// CacheTTLsConfig are the reward cache lifetimes as durations like "1h0m0s".
+type CacheTTLsConfig struct {
+	Finalized string `json:"finalized"`
+	Justified string `json:"justified"`
+	Recent    string `json:"recent"`
+}
+
+// ClientConfig is the effective configuration of a client, after the env variables were parsed
+// and the defaults applied. The upstream url is reported with its credentials redacted.
+type ClientConfig struct {
+	RPCURL               string             `json:"rpcUrl"`
+	RateLimit            float64            `json:"rateLimit"`
+	RateBurst            int                `json:"rateBurst"`
+	RequestCosts         RequestCosts       `json:"requestCosts"`
+	CacheTTL             string             `json:"cacheTtl"`
+	CacheMaxEntries      int                `json:"cacheMaxEntries"`
+	RewardCacheTTLs      CacheTTLsConfig    `json:"rewardCacheTtls"`
+	ValidatorBatchSize   int                `json:"validatorBatchSize"`
+	MaxURLLength         int                `json:"maxUrlLength"`
+	MergeSlot            uint64             `json:"mergeSlot"`
+	ExtendedStatuses     bool               `json:"extendedStatuses"`
+	Prefetch             bool               `json:"prefetch"`
+	BatchConcurrency     int                `json:"batchConcurrency"`
+	ReceiptConcurrency   int                `json:"receiptConcurrency"`
+	MaxReceiptCalls      int                `json:"maxReceiptCalls"`
+	MaxRedirects         int                `json:"maxRedirects"`
+	FeeRecipientLabels   FeeRecipientLabels `json:"feeRecipientLabels"`
+	MevFactor            int64              `json:"mevFactor"`
+	MevMinPriorityFee    string             `json:"mevMinPriorityFee"`
+	PayloadRetries       int                `json:"payloadRetries"`
+	ReorgRetries         int                `json:"reorgRetries"`
+	RewardCheckTolerance uint64             `json:"rewardCheckTolerance"`
+	CircuitThreshold     int                `json:"circuitThreshold"`
+	CircuitCooldown      string             `json:"circuitCooldown"`
+	UserAgent            string             `json:"userAgent"`
+	BeaconAccept         string             `json:"beaconAccept"`
+	DialTimeout          string             `json:"dialTimeout"`
+	Spec                 ChainSpec          `json:"spec"`
+}
+
+// RouteTimeoutsConfig are the route timeouts as durations, with the default applied to the routes
+// without their own.
+type RouteTimeoutsConfig struct {
+	Default     string `json:"default"`
+	BlockReward string `json:"blockReward"`
+	SyncDuties  string `json:"syncDuties"`
+	Batch       string `json:"batch"`
+}
+
+// Config returns the effective timeouts of the routes.
+func (t RouteTimeouts) Config() RouteTimeoutsConfig {
+	return RouteTimeoutsConfig{
+		Default:     t.Default.String(),
+		BlockReward: t.orDefault(t.BlockReward).String(),
+		SyncDuties:  t.orDefault(t.SyncDuties).String(),
+		Batch:       t.orDefault(t.Batch).String(),
+	}
+}
+
+// ServerConfig is the effective configuration of the server, after the env variables were parsed
+// and the defaults applied. EnabledRoutes lists all routes when ENABLED_ROUTES is empty. The
+// tracing endpoint and headers may carry credentials of the collector and are redacted when
+// reported, see redacted.
+type ServerConfig struct {
+	ServerAddr          string              `json:"serverAddr"`
+	GRPCAddr            string              `json:"grpcAddr"`
+	GinMode             string              `json:"ginMode"`
+	LogLevel            string              `json:"logLevel"`
+	Debug               bool                `json:"debug"`
+	AccessLogSampleRate uint32              `json:"accessLogSampleRate"`
+	TrustedProxies      []string            `json:"trustedProxies"`
+	Timeouts            RouteTimeoutsConfig `json:"timeouts"`
+	ShutdownTimeout     string              `json:"shutdownTimeout"`
+	PreDrainDelay       string              `json:"preDrainDelay"`
+	TLS                 bool                `json:"tls"`
+	TLSCertFile         string              `json:"tlsCertFile"`
+	TLSKeyFile          string              `json:"tlsKeyFile"`
+	EnabledRoutes       []string            `json:"enabledRoutes"`
+	Network             string              `json:"network"`
+	Networks            []string            `json:"networks"`
+	DialAttempts        int                 `json:"dialAttempts"`
+	WarmupSlots         uint64              `json:"warmupSlots"`
+	TracingEndpoint     string              `json:"tracingEndpoint"`
+	TracingHeaders      string              `json:"tracingHeaders"`
+}
+
+// redacted returns the configuration with the credentials of the tracing collector redacted. The
+// headers usually carry an authorization header and are only reported as set.
+func (s ServerConfig) redacted() ServerConfig {
+	if s.TracingEndpoint != "" {
+		if endpoint, err := url.Parse(s.TracingEndpoint); err == nil {
+			s.TracingEndpoint = redactURL(endpoint)
+		} else {
+			s.TracingEndpoint = Redacted
+		}
+	}
+	if s.TracingHeaders != "" {
+		s.TracingHeaders = Redacted
+	}
+	return s
+}
+
+// redactURL keeps the scheme and host of the url. User info, path and query may carry api keys of
+// the provider, e.g. https://mainnet.example.io/v3/<key>, and are redacted.
+func redactURL(u *url.URL) string {
+	redacted := &url.URL{Scheme: u.Scheme, Host: u.Host}
+	if u.User != nil {
+		redacted.User = url.User(Redacted)
+	}
+	if u.Path != "" && u.Path != "/" {
+		redacted.Path = "/" + Redacted
+	}
+	if u.RawQuery != "" {
+		redacted.RawQuery = Redacted
+	}
+	return redacted.String()
+}
+
+// Config returns the effective configuration of the client with its secrets redacted.
+func (c *Web3Client) Config() ClientConfig {
+	return ClientConfig{
+		RPCURL:       redactURL(c.BaseUrl),
+		RateLimit:    float64(c.limiter.Limit()),
+		RateBurst:    c.limiter.Burst(),
+		RequestCosts: c.requestCosts,
+		CacheTTL:     c.cacheTTL.String(),
+		RewardCacheTTLs: CacheTTLsConfig{
+			Finalized: c.cacheTTLs.Finalized.String(),
+			Justified: c.cacheTTLs.Justified.String(),
+			Recent:    c.cacheTTLs.Recent.String(),
+		},
+		CacheMaxEntries:      c.cacheMaxEntries,
+		ValidatorBatchSize:   c.validatorBatchSize,
+		MaxURLLength:         c.maxURLLength,
+		MergeSlot:            c.mergeSlot,
+		ExtendedStatuses:     c.extendedStatuses,
+		Prefetch:             c.prefetch,
+		BatchConcurrency:     c.batchConcurrency,
+		ReceiptConcurrency:   cap(c.receiptSlots),
+		MaxReceiptCalls:      c.maxReceiptCalls,
+		MaxRedirects:         c.maxRedirects,
+		FeeRecipientLabels:   c.feeRecipientLabels,
+		MevFactor:            c.mevThresholds.Factor,
+		MevMinPriorityFee:    c.mevThresholds.MinPriorityFee.String(),
+		PayloadRetries:       c.payloadRetries,
+		ReorgRetries:         c.reorgRetries,
+		RewardCheckTolerance: c.rewardCheckTolerance,
+		CircuitThreshold:     c.circuitThreshold,
+		CircuitCooldown:      c.circuitCooldown.String(),
+		UserAgent:            c.userAgent,
+		BeaconAccept:         c.beaconAccept,
+		DialTimeout:          c.dialTimeout.String(),
+		Spec:                 c.spec,
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/bilbeyt/staking_facilities_assignment/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type beaconRewardsServer struct {
+	pb.UnimplementedBeaconRewardsServer
+	client *Web3Client
+}
+
+// NewGRPCServer returns a gRPC server exposing the BeaconRewards service backed by the client.
+func NewGRPCServer(client *Web3Client) *grpc.Server {
+	server := grpc.NewServer()
+	pb.RegisterBeaconRewardsServer(server, &beaconRewardsServer{client: client})
+	return server
+}
+
+// grpcError maps the client errors to gRPC status codes the same way handleClientError maps
+// them to HTTP status codes.
+func grpcError(err error) error {
+	if errors.Is(err, ErrSlotMissing) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, ErrFutureSlot) || errors.Is(err, ErrInvalidSlot) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, ErrNodeSyncing) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Unavailable, "upstream request failed")
+}
+
+func (s *beaconRewardsServer) GetBlockReward(ctx context.Context, req *pb.SlotRequest) (*pb.BlockRewardResponse, error) {
+	reward, err := s.client.GetBlockReward(ctx, req.GetSlot())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &pb.BlockRewardResponse{Reward: reward.Gwei(), Status: reward.Status, Truncated: reward.Truncated}, nil
+}
+
+func (s *beaconRewardsServer) GetSyncDuties(ctx context.Context, req *pb.SlotRequest) (*pb.SyncDutiesResponse, error) {
+	pubKeys, err := s.client.GetSyncCommitteeDuties(ctx, req.GetSlot())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &pb.SyncDutiesResponse{Pubkeys: pubKeys}, nil
+}
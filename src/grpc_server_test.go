@@ -0,0 +1,109 @@
+package main_test
+
+import (
+	"context"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/bilbeyt/staking_facilities_assignment/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func setupGRPCClient(t *testing.T, testKey string, opts ...src.Option) pb.BeaconRewardsClient {
+	server := setupServer(testKey)
+	t.Cleanup(server.Close)
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100, opts...)
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := src.NewGRPCServer(client)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewBeaconRewardsClient(conn)
+}
+
+func TestGRPCGetBlockReward(t *testing.T) {
+	client := setupGRPCClient(t, "mev")
+	response, err := client.GetBlockReward(context.Background(), &pb.SlotRequest{Slot: "4700013"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Reward != "0.000000002" || response.Status != "mev" {
+		t.Errorf("Expected reward 0.000000002 and status mev, but got %s and %s", response.Reward, response.Status)
+	}
+}
+
+func TestGRPCGetBlockRewardFlagsTruncation(t *testing.T) {
+	client := setupGRPCClient(t, "hugeBlock", src.WithRateBurst(1000), src.WithMaxReceiptCalls(10))
+	response, err := client.GetBlockReward(context.Background(), &pb.SlotRequest{Slot: "4700013"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Reward != "0.000000078" || !response.Truncated {
+		t.Errorf("Expected a truncated reward of 0.000000078, but got %s truncated %t", response.Reward, response.Truncated)
+	}
+}
+
+func TestGRPCGetSyncDuties(t *testing.T) {
+	client := setupGRPCClient(t, "syncDuties")
+	response, err := client.GetSyncDuties(context.Background(), &pb.SlotRequest{Slot: "4700013"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Pubkeys) != 1 || response.Pubkeys[0] != "0x0000000000000000000000000000000000000000000000000000000000000001" {
+		t.Errorf("Unexpected pubkeys %v", response.Pubkeys)
+	}
+}
+
+func TestGRPCErrorCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		testKey string
+		call    func(client pb.BeaconRewardsClient) error
+		code    codes.Code
+	}{
+		{"missing slot", "rewardMissingSlot", func(client pb.BeaconRewardsClient) error {
+			_, err := client.GetBlockReward(context.Background(), &pb.SlotRequest{Slot: "5"})
+			return err
+		}, codes.NotFound},
+		{"future slot", "rewardFutureSlot", func(client pb.BeaconRewardsClient) error {
+			_, err := client.GetBlockReward(context.Background(), &pb.SlotRequest{Slot: "4700100"})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid slot", "rewardFutureSlot", func(client pb.BeaconRewardsClient) error {
+			_, err := client.GetBlockReward(context.Background(), &pb.SlotRequest{Slot: "abc"})
+			return err
+		}, codes.InvalidArgument},
+		{"upstream failure", "syncUpstreamError", func(client pb.BeaconRewardsClient) error {
+			_, err := client.GetSyncDuties(context.Background(), &pb.SlotRequest{Slot: "4700013"})
+			return err
+		}, codes.Unavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := setupGRPCClient(t, test.testKey)
+			err := test.call(client)
+			if status.Code(err) != test.code {
+				t.Errorf("Expected code %s, but got %v", test.code, err)
+			}
+		})
+	}
+}
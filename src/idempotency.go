@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+const DefaultIdempotencyTTL = 10 * time.Minute
+const DefaultIdempotencyMaxEntries = 1000
+const MaxIdempotentBodySize = 1 << 20
+
+// idempotencyIncompleteKey marks a response that must not be replayed, see markResponseIncomplete.
+const idempotencyIncompleteKey = "idempotencyIncomplete"
+
+var idempotencyKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,255}$`)
+
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// IdempotencyStore keeps responses by idempotency key for a ttl. It holds at most maxEntries
+// responses, the oldest one is dropped to make room for a new one.
+type IdempotencyStore struct {
+	mu         sync.Mutex
+	clock      Clock
+	ttl        time.Duration
+	maxEntries int
+	responses  map[string]idempotentResponse
+	order      []string
+}
+
+func NewIdempotencyStore(ttl time.Duration, maxEntries int) *IdempotencyStore {
+	return NewIdempotencyStoreWithClock(ttl, maxEntries, SystemClock)
+}
+
+// NewIdempotencyStoreWithClock returns a store whose responses expire by the clock.
+func NewIdempotencyStoreWithClock(ttl time.Duration, maxEntries int, clock Clock) *IdempotencyStore {
+	return &IdempotencyStore{
+		clock:      clock,
+		ttl:        ttl,
+		maxEntries: max(maxEntries, 1),
+		responses:  make(map[string]idempotentResponse),
+	}
+}
+
+func (s *IdempotencyStore) get(key string) (idempotentResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	response, ok := s.responses[key]
+	if !ok || !s.clock.Now().Before(response.expiresAt) {
+		return idempotentResponse{}, false
+	}
+	return response, true
+}
+
+func (s *IdempotencyStore) set(key string, response idempotentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	response.expiresAt = s.clock.Now().Add(s.ttl)
+	if _, ok := s.responses[key]; !ok {
+		for len(s.order) >= s.maxEntries {
+			delete(s.responses, s.order[0])
+			s.order = s.order[1:]
+		}
+		s.order = append(s.order, key)
+	}
+	s.responses[key] = response
+}
+
+// bodyRecorder passes the response through while keeping a copy of the body.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *bodyRecorder) WriteString(data string) (int, error) {
+	r.body.WriteString(data)
+	return r.ResponseWriter.WriteString(data)
+}
+
+// markResponseIncomplete keeps the response from being stored by IdempotencyMiddleware, for
+// responses whose status can not tell, like a stream cut short or holding upstream failures.
+func markResponseIncomplete(c *gin.Context) {
+	c.Set(idempotencyIncompleteKey, true)
+}
+
+// requestFingerprint hashes the body with the formats negotiated from the Accept header, so a
+// retry asking for another format does not get the stored one.
+func requestFingerprint(c *gin.Context, body []byte) [sha256.Size]byte {
+	hash := sha256.New()
+	hash.Write([]byte(c.NegotiateFormat(binding.MIMEJSON, MIMENDJSON) + "\n"))
+	hash.Write([]byte(c.NegotiateFormat(binding.MIMEJSON, binding.MIMEPlain) + "\n"))
+	hash.Write(body)
+	var fingerprint [sha256.Size]byte
+	copy(fingerprint[:], hash.Sum(nil))
+	return fingerprint
+}
+
+// IdempotencyMiddleware replays the stored response of a request that repeats the
+// Idempotency-Key of an earlier request with the same body and Accept format. Reusing a key with
+// a different request is rejected. Requests without the header are handled as usual. Server
+// errors and responses marked incomplete are not stored so they can be retried.
+func IdempotencyMiddleware(store *IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if !idempotencyKeyPattern.MatchString(key) {
+			abortWithError(c, http.StatusBadRequest, "Idempotency key is invalid")
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxIdempotentBodySize))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			abortWithError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must be at most %d bytes", maxBytesErr.Limit))
+			return
+		}
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, "Request body can not be read")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(c, body)
+
+		if stored, ok := store.get(key); ok {
+			if stored.fingerprint != fingerprint {
+				abortWithError(c, http.StatusUnprocessableEntity, "Idempotency key was used with a different request")
+				return
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.status, stored.contentType, stored.body)
+			c.Abort()
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		if status := recorder.Status(); status < http.StatusInternalServerError && !c.GetBool(idempotencyIncompleteKey) {
+			store.set(key, idempotentResponse{
+				fingerprint: fingerprint,
+				status:      status,
+				contentType: recorder.Header().Get("Content-Type"),
+				body:        recorder.body.Bytes(),
+			})
+		}
+	}
+}
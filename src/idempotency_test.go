@@ -0,0 +1,133 @@
+package main_test
+
+import (
+	"bytes"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func postWithIdempotencyKey(router *gin.Engine, path string, body string, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(body)))
+	req.Header.Set("Idempotency-Key", key)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestBlockRewardsReplaysIdempotentRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var upstreamCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		upstreamCalls.Add(1)
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	src.RegisterRoutes(router, src.NewWeb3Client(parsedUrl, 1000), src.DefaultRouteTimeouts(), false)
+	body := `{"slots": ["4700013", "4700012"]}`
+
+	first := postWithIdempotencyKey(router, "/blockrewards", body, "retry-1")
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", first.Code, first.Body.String())
+	}
+	expected := `{"rewards":[{"slot":"4700013","reward":"0.000000001","status":"vanilla"}` + "\n" +
+		`,{"slot":"4700012","error":"Slot is before the merge slot 4700013"}` + "\n" + `]}`
+	if first.Body.String() != expected {
+		t.Errorf("Expected %s, but got %s", expected, first.Body.String())
+	}
+	calls := upstreamCalls.Load()
+	second := postWithIdempotencyKey(router, "/blockrewards", body, "retry-1")
+	if upstreamCalls.Load() != calls {
+		t.Errorf("Expected no upstream calls for the retry, but got %d", upstreamCalls.Load()-calls)
+	}
+	if second.Body.String() != first.Body.String() || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the first response to be replayed, but got %s", second.Body.String())
+	}
+
+	if recorder := postWithIdempotencyKey(router, "/blockrewards", `{"slots": ["4700014"]}`, "retry-1"); recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for a reused key, but got %d", recorder.Code)
+	}
+	if recorder := postWithIdempotencyKey(router, "/blockrewards", body, "not a valid key"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid key, but got %d", recorder.Code)
+	}
+}
+
+func TestIdempotencyStoreIsBounded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var handled int
+	router := gin.New()
+	router.POST("/", src.IdempotencyMiddleware(src.NewIdempotencyStore(time.Minute, 2)), func(c *gin.Context) {
+		handled++
+		c.Status(http.StatusOK)
+	})
+	for _, key := range []string{"a", "b", "c", "b", "a"} {
+		postWithIdempotencyKey(router, "/", "", key)
+	}
+	// a is dropped to make room for c, so only the retry of b is replayed
+	if handled != 4 {
+		t.Errorf("Expected 4 requests to be handled, but got %d", handled)
+	}
+}
+
+func TestBlockRewardsDoesNotStoreUpstreamFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if failing.Load() {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	src.RegisterRoutes(router, src.NewWeb3Client(parsedUrl, 1000, src.WithCircuitBreaker(0, 0)), src.DefaultRouteTimeouts(), false)
+	body := `{"slots": ["4700013"]}`
+
+	first := postWithIdempotencyKey(router, "/blockrewards", body, "retry-1")
+	if !strings.Contains(first.Body.String(), src.UpstreamFailedMessage) {
+		t.Fatalf("Expected an upstream failure, but got %d %s", first.Code, first.Body.String())
+	}
+	failing.Store(false)
+	second := postWithIdempotencyKey(router, "/blockrewards", body, "retry-1")
+	expected := `{"rewards":[{"slot":"4700013","reward":"0.000000001","status":"vanilla"}` + "\n" + `]}`
+	if second.Body.String() != expected || second.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected the retry to be computed again as %s, but got %s", expected, second.Body.String())
+	}
+}
+
+func TestIdempotencyFingerprintsAcceptAndBoundsBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", src.IdempotencyMiddleware(src.NewIdempotencyStore(time.Minute, 10)), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	postWithIdempotencyKey(router, "/", `{"slots": ["1"]}`, "a")
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"slots": ["1"]}`)))
+	req.Header.Set("Idempotency-Key", "a")
+	req.Header.Set("Accept", src.MIMENDJSON)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for a retry asking for another format, but got %d", recorder.Code)
+	}
+
+	large := strings.Repeat("a", src.MaxIdempotentBodySize+1)
+	if recorder := postWithIdempotencyKey(router, "/", large, "b"); recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 for a large body, but got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"strings"
+)
+
+// FeeRecipientLabels maps fee recipients to the name of the pool or operator they belong to.
+type FeeRecipientLabels map[common.Address]string
+
+// ParseFeeRecipientLabels parses labels in the form "0xabc...=Pool A,0xdef...=Operator B".
+func ParseFeeRecipientLabels(value string) (FeeRecipientLabels, error) {
+	labels := FeeRecipientLabels{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		address, label, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("fee recipient label %q is not in the form address=label", pair)
+		}
+		address, label = strings.TrimSpace(address), strings.TrimSpace(label)
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("fee recipient %q is not an address", address)
+		}
+		if label == "" {
+			return nil, fmt.Errorf("fee recipient %s has an empty label", address)
+		}
+		labels[common.HexToAddress(address)] = label
+	}
+	return labels, nil
+}
+
+// label returns the label of the fee recipient, or nil when it is not listed.
+func (l FeeRecipientLabels) label(feeRecipient common.Address) *string {
+	if label, ok := l[feeRecipient]; ok {
+		return &label
+	}
+	return nil
+}
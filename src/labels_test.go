@@ -0,0 +1,52 @@
+package main_test
+
+import (
+	"context"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/ethereum/go-ethereum/common"
+	"net/url"
+	"testing"
+)
+
+func TestParseFeeRecipientLabels(t *testing.T) {
+	labels, err := src.ParseFeeRecipientLabels(" 0x00000000000000000000000000000000000000f1 = Pool A,,0x00000000000000000000000000000000000000F2=Operator B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels[common.HexToAddress("0xf1")] != "Pool A" || labels[common.HexToAddress("0xf2")] != "Operator B" || len(labels) != 2 {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+	for _, value := range []string{"0xf1", "0x00000000000000000000000000000000000000f1=", "pool=0x00000000000000000000000000000000000000f1"} {
+		if _, err := src.ParseFeeRecipientLabels(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestBlockRewardDetailsFeeRecipientLabel(t *testing.T) {
+	poolA := "Pool A"
+	labels := src.FeeRecipientLabels{common.HexToAddress("0xf1"): poolA}
+	tests := []struct {
+		testKey string
+		label   *string
+	}{
+		{"builtLocally", &poolA},
+		{"builtByRelay", nil},
+	}
+	for _, tt := range tests {
+		server := setupServer(tt.testKey)
+		parsedUrl, _ := url.Parse(server.URL)
+		client := src.NewWeb3Client(parsedUrl, 1000, src.WithFeeRecipientLabels(labels))
+		details, err := client.GetBlockRewardDetails(context.Background(), "4700013")
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.testKey, err)
+		}
+		switch {
+		case tt.label == nil && details.FeeRecipientLabel != nil:
+			t.Errorf("%s: expected no label, but got %s", tt.testKey, *details.FeeRecipientLabel)
+		case tt.label != nil && (details.FeeRecipientLabel == nil || *details.FeeRecipientLabel != *tt.label):
+			t.Errorf("%s: expected label %s, but got %v", tt.testKey, *tt.label, details.FeeRecipientLabel)
+		}
+	}
+}
@@ -1,25 +1,49 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
 	"errors"
+	"flag"
+	"fmt"
+	"github.com/bilbeyt/staking_facilities_assignment/pb"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/joho/godotenv"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/time/rate"
+	"math/big"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
+	checkOnly := flag.Bool("check", false, "check connectivity to the upstream node and exit")
+	flag.Parse()
 	envFilePath := os.Getenv("ENV_PATH")
 	err := godotenv.Load(envFilePath)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Error loading .env file")
 	}
 	gin.SetMode(os.Getenv("GIN_MODE"))
+	logLevel := zerolog.InfoLevel
+	if logLevelStr := os.Getenv("LOG_LEVEL"); logLevelStr != "" {
+		logLevel, err = zerolog.ParseLevel(logLevelStr)
+		if err != nil {
+			log.Fatal().Err(err).Msg("can not parse log level")
+		}
+	}
+	zerolog.SetGlobalLevel(logLevel)
 	rpcURL := os.Getenv("RPC_URL")
 	parsedUrl, err := url.Parse(rpcURL)
 	if err != nil {
@@ -33,74 +57,1069 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("can not parse rpc rate limit")
 	}
-	client := NewWeb3Client(parsedUrl, rate.Limit(rpcRateLimitFloat))
+	rpcRateBurst := 1
+	if rpcRateBurstStr := os.Getenv("RPC_RATE_BURST"); rpcRateBurstStr != "" {
+		rpcRateBurst, err = strconv.Atoi(rpcRateBurstStr)
+		if err != nil || rpcRateBurst < 1 {
+			log.Fatal().Err(err).Msg("rpc rate burst must be an integer of at least 1")
+		}
+	}
+	requestCosts := DefaultRequestCosts()
+	if requestCostsStr := os.Getenv("RPC_REQUEST_COSTS"); requestCostsStr != "" {
+		requestCosts, err = ParseRequestCosts(requestCostsStr)
+		if err != nil {
+			log.Fatal().Err(err).Msg("can not parse rpc request costs")
+		}
+	}
+	feeRecipientLabels, err := ParseFeeRecipientLabels(os.Getenv("FEE_RECIPIENT_LABELS"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("can not parse fee recipient labels")
+	}
+	clientOptions := []Option{
+		WithRateBurst(rpcRateBurst),
+		WithRequestCosts(requestCosts),
+		WithExtendedStatuses(os.Getenv("EXTENDED_STATUSES") == "true"),
+		WithPrefetch(os.Getenv("PREFETCH_NEXT_SLOT") == "true"),
+		WithBatchConcurrency(getIntEnv("BATCH_CONCURRENCY", DefaultBatchConcurrency)),
+		WithReceiptConcurrency(getIntEnv("RECEIPT_CONCURRENCY", DefaultReceiptConcurrency)),
+		WithMaxReceiptCalls(getIntEnv("MAX_RECEIPT_CALLS", DefaultMaxReceiptCalls)),
+		WithMaxRedirects(getIntEnv("MAX_REDIRECTS", DefaultMaxRedirects)),
+		WithFeeRecipientLabels(feeRecipientLabels),
+		WithUserAgent(os.Getenv("USER_AGENT")),
+		WithBeaconAccept(os.Getenv("BEACON_ACCEPT")),
+		WithDialTimeout(getDurationEnv("RPC_DIAL_TIMEOUT", DefaultDialTimeout)),
+		WithPayloadRetries(getIntEnv("PAYLOAD_RETRIES", DefaultPayloadRetries)),
+		WithReorgRetries(getIntEnv("REORG_RETRIES", DefaultReorgRetries)),
+		WithMaxURLLength(getIntEnv("MAX_URL_LENGTH", DefaultMaxURLLength)),
+		WithMergeSlot(getUintEnvOr("MERGE_SLOT", DefaultMergeSlot)),
+		WithCacheMaxEntries(getIntEnv("CACHE_MAX_ENTRIES", DefaultCacheMaxEntries)),
+		WithRewardCacheTTLs(CacheTTLs{
+			Finalized: getDurationEnv("CACHE_TTL_FINALIZED", DefaultFinalizedCacheTTL),
+			Justified: getDurationEnv("CACHE_TTL_JUSTIFIED", DefaultJustifiedCacheTTL),
+			Recent:    getDurationEnv("CACHE_TTL_RECENT", DefaultRecentCacheTTL),
+		}),
+		WithRewardCheckTolerance(getUintEnv("REWARD_CHECK_TOLERANCE")),
+		WithCircuitBreaker(getIntEnv("CIRCUIT_BREAKER_THRESHOLD", DefaultCircuitThreshold),
+			getDurationEnv("CIRCUIT_BREAKER_COOLDOWN", DefaultCircuitCooldown)),
+	}
+	dialAttempts := getIntEnv("RPC_DIAL_ATTEMPTS", DefaultDialAttempts)
+	network := os.Getenv("NETWORK")
+	if network == "" {
+		network = "mainnet"
+	}
+	client := dialWeb3Client(parsedUrl, rate.Limit(rpcRateLimitFloat),
+		append(slices.Clone(clientOptions), WithMevThresholds(mevThresholdsFromEnv(network, ""))), dialAttempts)
+	if *checkOnly || os.Getenv("CHECK_ONLY") == "true" {
+		slot, err := CheckUpstream(context.Background(), client)
+		if err != nil {
+			log.Fatal().Err(err).Msg("upstream check failed")
+		}
+		log.Info().Str("slot", slot.String()).Msg("upstream check succeeded")
+		return
+	}
+	specOverrides := SpecOverrides{
+		SlotsPerEpoch:  getUintEnv("SLOTS_PER_EPOCH"),
+		SecondsPerSlot: getUintEnv("SECONDS_PER_SLOT"),
+		GenesisTime:    int64(getUintEnv("GENESIS_TIME")),
+	}
+	if err := client.LoadSpec(context.Background(), specOverrides); err != nil {
+		log.Info().Err(err).Msg("can not load chain spec, using mainnet defaults")
+	}
+
+	networkNames, err := ParseNetworkNames(os.Getenv("NETWORKS"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("can not parse networks")
+	}
+	networks := make(map[string]*Web3Client, len(networkNames))
+	for _, name := range networkNames {
+		prefix := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		networkUrl, err := url.Parse(os.Getenv(prefix + "RPC_URL"))
+		if err != nil || networkUrl.Host == "" {
+			log.Fatal().Err(err).Str("network", name).Msg("can not parse the rpc url of the network")
+		}
+		networkRateLimit := rpcRateLimitFloat
+		if networkRateLimitStr := os.Getenv(prefix + "RPC_RATE_LIMIT"); networkRateLimitStr != "" {
+			networkRateLimit, err = strconv.ParseFloat(networkRateLimitStr, 64)
+			if err != nil {
+				log.Fatal().Err(err).Str("network", name).Msg("can not parse rpc rate limit of the network")
+			}
+		}
+		networkClient := dialWeb3Client(networkUrl, rate.Limit(networkRateLimit),
+			append(slices.Clone(clientOptions), WithMevThresholds(mevThresholdsFromEnv(name, prefix)),
+				WithMergeSlot(getUintEnvOr(prefix+"MERGE_SLOT", DefaultMergeSlot))), dialAttempts)
+		if err := networkClient.LoadSpec(context.Background(), SpecOverrides{}); err != nil {
+			log.Info().Err(err).Str("network", name).Msg("can not load chain spec of the network, using mainnet defaults")
+		}
+		networks[name] = networkClient
+	}
 
-	router := gin.Default()
+	shutdownTracing, err := SetupTracing(context.Background())
+	if err != nil {
+		log.Fatal().Err(err).Msg("can not set up tracing")
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Info().Err(err).Msg("can not shut down tracing")
+		}
+	}()
+
+	accessLogSampleRate := uint32(getUintEnv("ACCESS_LOG_SAMPLE_RATE"))
+	router := gin.New()
+	router.Use(gin.Recovery(), AccessLogMiddleware(log.Logger, accessLogSampleRate))
+	router.Use(TracingMiddleware())
 	router.ForwardedByClientIP = true
-	err = router.SetTrustedProxies(strings.Split(trustedProxiesStr, ","))
+	trustedProxies := strings.Split(trustedProxiesStr, ",")
+	err = router.SetTrustedProxies(trustedProxies)
 	if err != nil {
 		log.Fatal().Err(err).Msg("can not set trusted proxies")
 	}
-	router.GET("/blockreward/:slotId", GetBlockRewardHandler(client))
-	router.GET("/syncduties/:slotId", GetSyncDutiesHandler(client))
+	defaultTimeouts := DefaultRouteTimeouts()
+	timeouts := RouteTimeouts{
+		Default:     getDurationEnv("REQUEST_TIMEOUT", defaultTimeouts.Default),
+		BlockReward: getDurationEnv("BLOCKREWARD_TIMEOUT", defaultTimeouts.BlockReward),
+		SyncDuties:  getDurationEnv("SYNCDUTIES_TIMEOUT", defaultTimeouts.SyncDuties),
+		Batch:       getDurationEnv("BATCH_TIMEOUT", defaultTimeouts.Batch),
+	}
+	enabledRoutes, err := ParseEnabledRoutes(os.Getenv("ENABLED_ROUTES"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("can not parse enabled routes")
+	}
+	debug := os.Getenv("DEBUG") == "true"
+	apiRouter := FilterRoutes(router, enabledRoutes)
+	RegisterRoutes(apiRouter, client, timeouts, debug)
+	RegisterNetworkRoutes(apiRouter, networks, timeouts, debug)
+	RegisterMetricsRoute(router, NewMetricsRegistry(client))
+	readiness := NewReadiness()
+	router.GET("/ready", ReadyHandler(readiness))
+
+	tlsCertFile, tlsKeyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		log.Fatal().Msg("both TLS_CERT_FILE and TLS_KEY_FILE must be set to serve https")
+	}
+	serverOptions := ServerOptions{
+		ShutdownTimeout: getDurationEnv("SHUTDOWN_TIMEOUT", DefaultShutdownTimeout),
+		PreDrainDelay:   getDurationEnv("PRE_DRAIN_DELAY", DefaultPreDrainDelay),
+		Readiness:       readiness,
+		TLSCertFile:     tlsCertFile,
+		TLSKeyFile:      tlsKeyFile,
+	}
+	grpcAddr := os.Getenv("GRPC_ADDR")
+	warmupSlots := getUintEnv("WARMUP_SLOTS")
+	if adminAPIKey := os.Getenv("ADMIN_API_KEY"); adminAPIKey != "" {
+		routeNames := enabledRoutes
+		if routeNames == nil {
+			routeNames = APIRouteNames
+		}
+		RegisterAdminRoutes(router, client, networks, ServerConfig{
+			ServerAddr:          serverAddr,
+			GRPCAddr:            grpcAddr,
+			GinMode:             gin.Mode(),
+			LogLevel:            logLevel.String(),
+			Debug:               debug,
+			AccessLogSampleRate: accessLogSampleRate,
+			TrustedProxies:      trustedProxies,
+			Timeouts:            timeouts.Config(),
+			ShutdownTimeout:     serverOptions.ShutdownTimeout.String(),
+			PreDrainDelay:       serverOptions.PreDrainDelay.String(),
+			TLS:                 tlsCertFile != "",
+			TLSCertFile:         tlsCertFile,
+			TLSKeyFile:          tlsKeyFile,
+			EnabledRoutes:       routeNames,
+			Network:             network,
+			Networks:            networkNames,
+			DialAttempts:        dialAttempts,
+			WarmupSlots:         warmupSlots,
+			TracingEndpoint:     cmp.Or(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")),
+			TracingHeaders:      cmp.Or(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS"), os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		}, adminAPIKey)
+	}
+
+	if grpcAddr != "" {
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatal().Err(err).Msg("can not listen on grpc address")
+		}
+		go func() {
+			if err := NewGRPCServer(client).Serve(listener); err != nil {
+				log.Fatal().Err(err).Msg("gRPC server exit")
+			}
+		}()
+	}
+
+	listener, err := net.Listen("tcp", serverAddr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("can not listen on server address")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if warmupSlots > 0 {
+		// the warmup runs next to the server, so it neither delays readiness nor shutdown
+		go func() {
+			warmed, err := client.WarmUp(ctx, warmupSlots)
+			if err != nil {
+				log.Info().Err(err).Int("warmed", warmed).Msg("cache warmup stopped")
+				return
+			}
+			log.Info().Int("warmed", warmed).Uint64("slots", warmupSlots).Msg("cache warmup finished")
+		}()
+	}
+	server := &http.Server{Handler: router}
+	err = RunServer(ctx, server, listener, serverOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("Server exit")
+	}
+}
+
+// dialWeb3Client creates the client, retrying the dial up to attempts times before giving up.
+func dialWeb3Client(parsedUrl *url.URL, reqPerSec rate.Limit, options []Option, attempts int) *Web3Client {
+	for attempt := 1; ; attempt++ {
+		client := NewWeb3Client(parsedUrl, reqPerSec, options...)
+		if client != nil {
+			return client
+		}
+		if attempt >= attempts {
+			log.Fatal().Int("attempts", attempt).Msg("can not dial ethereum client")
+		}
+		log.Info().Int("attempt", attempt).Dur("delay", DialRetryDelay).Msg("retrying ethereum client dial")
+		time.Sleep(DialRetryDelay)
+	}
+}
 
-	err = router.Run(serverAddr)
+// mevThresholdsFromEnv returns the mev thresholds of the network with the overrides of the
+// MEV_FEE_FACTOR and MEV_MIN_PRIORITY_FEE env variables carrying the prefix.
+func mevThresholdsFromEnv(network string, prefix string) MevThresholds {
+	thresholds, err := MevThresholdsFor(network).WithOverrides(os.Getenv(prefix+"MEV_FEE_FACTOR"),
+		os.Getenv(prefix+"MEV_MIN_PRIORITY_FEE"))
 	if err != nil {
-		log.Fatal().Err(err).Msg("Server exit")
+		log.Fatal().Err(err).Str("network", network).Msg("can not parse mev thresholds")
 	}
+	return thresholds
 }
 
+// CheckUpstream fetches the head slot once to verify the upstream node is reachable and serving
+// the beacon API.
+func CheckUpstream(ctx context.Context, client *Web3Client) (*big.Int, error) {
+	return client.getCurrentSlotId(ctx)
+}
+
+// getUintEnv returns the unsigned integer value of the env variable, or 0 when it is not set.
+func getUintEnv(name string) uint64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Fatal().Err(err).Str("name", name).Msg("can not parse env variable")
+	}
+	return parsed
+}
+
+// getUintEnvOr returns the unsigned value of the env variable, or fallback when it is not set, so
+// that 0 can be configured explicitly.
+func getUintEnvOr(name string, fallback uint64) uint64 {
+	if os.Getenv(name) == "" {
+		return fallback
+	}
+	return getUintEnv(name)
+}
+
+// getIntEnv returns the integer value of the env variable, or fallback when it is not set.
+func getIntEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatal().Err(err).Str("name", name).Msg("can not parse env variable")
+	}
+	return parsed
+}
+
+// getDurationEnv returns the duration value of the env variable, e.g. "30s", or fallback when it
+// is not set.
+func getDurationEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatal().Err(err).Str("name", name).Msg("can not parse env variable")
+	}
+	return parsed
+}
+
+func handleClientError(c *gin.Context, err error) {
+	if errors.Is(err, ErrSlotMissing) {
+		respondError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, ErrFutureSlot) || errors.Is(err, ErrInvalidSlot) {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, ErrPayloadUnavailable) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrNodeSyncing) {
+		respondError(c, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if errors.Is(err, ErrMismatchedSlot) {
+		respondError(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		respondError(c, http.StatusGatewayTimeout, "Upstream request timed out")
+		return
+	}
+	c.JSON(http.StatusInternalServerError, nil)
+}
+
+// bypassCacheIfRequested makes the request ignore cached values when it has ?nocache=true or a
+// Cache-Control: no-cache header. The fresh result still populates the cache.
+func bypassCacheIfRequested(c *gin.Context) {
+	if c.Query("nocache") == "true" || strings.Contains(c.GetHeader("Cache-Control"), "no-cache") {
+		c.Request = c.Request.WithContext(WithCacheBypass(c.Request.Context()))
+	}
+}
+
+// wantsProtoBuf reports whether the client asked for a protobuf response through the Accept
+// header. JSON stays the default when the header is missing or does not match.
+func wantsProtoBuf(c *gin.Context) bool {
+	return c.NegotiateFormat(binding.MIMEJSON, binding.MIMEPROTOBUF) == binding.MIMEPROTOBUF
+}
+
+const MIMENDJSON = "application/x-ndjson"
+
+// wantsNDJSON reports whether the client asked for newline-delimited JSON through the Accept
+// header.
+func wantsNDJSON(c *gin.Context) bool {
+	return c.NegotiateFormat(binding.MIMEJSON, MIMENDJSON) == MIMENDJSON
+}
+
+// respondError writes the error message as plain text when the client prefers text/plain through
+// the Accept header, and as {"error": message} otherwise.
+func respondError(c *gin.Context, status int, message string) {
+	if c.NegotiateFormat(binding.MIMEJSON, binding.MIMEPlain) == binding.MIMEPlain {
+		c.String(status, message)
+		return
+	}
+	c.JSON(status, gin.H{
+		"error": message,
+	})
+}
+
+// abortWithError responds like respondError and stops the remaining handlers.
+func abortWithError(c *gin.Context, status int, message string) {
+	respondError(c, status, message)
+	c.Abort()
+}
+
+// GetBlockRewardHandler returns the reward of the block of the slot, of the head slot when the
+// slot is "head" or missing.
 func GetBlockRewardHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slotId, err := client.resolveSlotId(c.Request.Context(), c.Param("slotId"))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		// a failed request would spend the rate limit on warming a slot nobody may ask for
+		defer func() {
+			if c.Writer.Status() == http.StatusOK {
+				client.prefetchNextReward(slotId)
+			}
+		}()
+		bypassCacheIfRequested(c)
+		ctx, _ := withRewardTimings(c.Request.Context())
+		ctx, _ = withCacheOutcome(ctx)
+		c.Request = c.Request.WithContext(ctx)
+		if c.Query("format") == "wei" {
+			getBlockRewardWei(c, client, slotId)
+			return
+		}
+		if format := c.Query("format"); format == "object" || format == "suffixed" {
+			getBlockRewardInUnit(c, client, slotId, format == "suffixed")
+			return
+		}
+		if c.Query("detailed") == "true" {
+			getBlockRewardDetailed(c, client, slotId)
+			return
+		}
+		if baseline := c.Query("baseline"); baseline != "" {
+			getBlockRewardWithBaseline(c, client, slotId, baseline)
+			return
+		}
+		reward, err := client.GetBlockReward(c.Request.Context(), slotId)
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		setServerTiming(c)
+		setCacheHeader(c)
+		if wantsProtoBuf(c) {
+			c.ProtoBuf(http.StatusOK, &pb.BlockRewardResponse{Reward: reward.Gwei(), Status: reward.Status, Truncated: reward.Truncated})
+			return
+		}
+		body := gin.H{
+			"reward": reward.Gwei(),
+			"status": reward.Status,
+		}
+		// only flagged when set, like the details, so exact rewards keep their response
+		if reward.Truncated {
+			body["truncated"] = true
+		}
+		c.JSON(http.StatusOK, withSource(c, body))
+	}
+}
+
+// setServerTiming reports the phase timings of the reward computation of the request in the
+// Server-Timing header.
+func setServerTiming(c *gin.Context) {
+	if timings, ok := c.Request.Context().Value(rewardTimingsKey{}).(*RewardTimings); ok {
+		c.Header("Server-Timing", timings.serverTiming())
+	}
+}
+
+// setCacheHeader reports in the X-Cache header whether the response was served from the cache.
+func setCacheHeader(c *gin.Context) {
+	outcome, ok := c.Request.Context().Value(cacheOutcomeKey{}).(*cacheOutcome)
+	if !ok {
+		return
+	}
+	source, ok := outcome.source()
+	switch {
+	case ok && source == SourceCache:
+		c.Header("X-Cache", "HIT")
+	case ok:
+		c.Header("X-Cache", "MISS")
+	}
+}
+
+const debugKey = "debug"
+
+// markDebug flags the request as served by a route registered in debug mode.
+func markDebug(c *gin.Context) {
+	c.Set(debugKey, true)
+}
+
+// withSource adds the source of the response, cache or live, to the body in debug mode.
+func withSource(c *gin.Context, body gin.H) gin.H {
+	if !c.GetBool(debugKey) {
+		return body
+	}
+	if outcome, ok := c.Request.Context().Value(cacheOutcomeKey{}).(*cacheOutcome); ok {
+		if source, ok := outcome.source(); ok {
+			body["source"] = source
+		}
+	}
+	return body
+}
+
+// getBlockRewardWei responds with the reward as an integer wei JSON number when it fits
+// in int64, otherwise as a decimal string flagged with overflow.
+func getBlockRewardWei(c *gin.Context, client *Web3Client, slotId string) {
+	reward, status, err := client.GetBlockRewardWei(c.Request.Context(), slotId)
+	if err != nil {
+		handleClientError(c, err)
+		return
+	}
+	setServerTiming(c)
+	setCacheHeader(c)
+	if !reward.IsInt64() {
+		c.JSON(http.StatusOK, withSource(c, gin.H{
+			"reward":   reward.String(),
+			"overflow": true,
+			"status":   status,
+		}))
+		return
+	}
+	c.JSON(http.StatusOK, withSource(c, gin.H{
+		"reward": reward.Int64(),
+		"status": status,
+	}))
+}
+
+// getBlockRewardWithBaseline responds with the reward in gwei next to its comparison to the median
+// of the ?window= slots before it, DefaultBaselineWindow by default. Median is the only baseline.
+func getBlockRewardWithBaseline(c *gin.Context, client *Web3Client, slotId string, baseline string) {
+	if baseline != "median" {
+		respondError(c, http.StatusBadRequest, "Baseline must be median")
+		return
+	}
+	window := DefaultBaselineWindow
+	if windowStr := c.Query("window"); windowStr != "" {
+		var err error
+		window, err = strconv.Atoi(windowStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("Window must be between 1 and %d", MaxSlotRange))
+			return
+		}
+	}
+	reward, status, err := client.GetBlockRewardWei(c.Request.Context(), slotId)
+	if err != nil {
+		handleClientError(c, err)
+		return
+	}
+	rewardBaseline, err := client.GetRewardBaseline(c.Request.Context(), slotId, reward, window)
+	if err != nil {
+		handleClientError(c, err)
+		return
+	}
+	setServerTiming(c)
+	setCacheHeader(c)
+	c.JSON(http.StatusOK, withSource(c, gin.H{
+		"reward":   new(big.Rat).SetFrac(reward, GWEI).FloatString(9),
+		"status":   status,
+		"baseline": rewardBaseline,
+	}))
+}
+
+// getBlockRewardInUnit responds with the reward in the unit of the unit query parameter, gwei by
+// default, as a {value, unit} object or, when suffixed is set, as a string ending in the unit.
+func getBlockRewardInUnit(c *gin.Context, client *Web3Client, slotId string, suffixed bool) {
+	unit := c.DefaultQuery("unit", "gwei")
+	if _, ok := UnitDecimals(unit); !ok {
+		respondError(c, http.StatusBadRequest, "Unit is invalid")
+		return
+	}
+	reward, status, err := client.GetBlockRewardWei(c.Request.Context(), slotId)
+	if err != nil {
+		handleClientError(c, err)
+		return
+	}
+	amount, err := NewUnitAmount(reward, unit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, nil)
+		return
+	}
+	setServerTiming(c)
+	setCacheHeader(c)
+	if suffixed {
+		c.JSON(http.StatusOK, withSource(c, gin.H{
+			"reward": amount.String(),
+			"status": status,
+		}))
+		return
+	}
+	c.JSON(http.StatusOK, withSource(c, gin.H{
+		"reward": amount,
+		"status": status,
+	}))
+}
+
+// GetBlockRewardDetailsHandler returns the full reward decomposition of a slot. It is meant for
+// troubleshooting and only registered when DEBUG is enabled.
+func GetBlockRewardDetailsHandler(client *Web3Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		slotId := c.Param("slotId")
-		reward, status, err := client.GetBlockRewardAndStatusBySlot(c, slotId)
-		if err != nil {
-			var slotMissingError *SlotMissingError
-			var futureSlotError *FutureSlotError
-			if errors.As(err, &slotMissingError) {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error": err.Error(),
-				})
-				return
+		details, err := client.GetBlockRewardDetails(c.Request.Context(), slotId)
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, details)
+	}
+}
+
+// getBlockRewardDetailed responds with the reward decomposition without the per transaction
+// contributions, which are only exposed by the debug endpoint.
+func getBlockRewardDetailed(c *gin.Context, client *Web3Client, slotId string) {
+	details, err := client.GetBlockRewardDetails(c.Request.Context(), slotId)
+	if err != nil {
+		handleClientError(c, err)
+		return
+	}
+	setServerTiming(c)
+	details.Transactions = nil
+	if c.Query("check") != "true" {
+		details.Check = nil
+	}
+	c.JSON(http.StatusOK, details)
+}
+
+func GetSyncDutiesHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slotId := c.Param("slotId")
+		bypassCacheIfRequested(c)
+		ctx, _ := withCacheOutcome(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		group := c.Query("group")
+		if group != "" && group != "subcommittee" {
+			respondError(c, http.StatusBadRequest, "Group must be subcommittee")
+			return
+		}
+		if group != "" && (c.Query("partial") == "true" || c.Query("limit") != "") {
+			respondError(c, http.StatusBadRequest, "Subcommittees need the full committee, without partial or limit")
+			return
+		}
+		limitDuties, ok := parseSyncDutiesLimit(c, client)
+		if !ok {
+			return
+		}
+		if c.Query("partial") == "true" {
+			getSyncDutiesBestEffort(c, client, slotId, limitDuties)
+			return
+		}
+		if group != "" {
+			getSyncSubcommittees(c, client, slotId)
+			return
+		}
+		pubKeys, err := client.GetSyncCommitteeDuties(c.Request.Context(), slotId)
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		pubKeys = limitDuties(pubKeys)
+		setCacheHeader(c)
+		if wantsProtoBuf(c) {
+			c.ProtoBuf(http.StatusOK, &pb.SyncDutiesResponse{Pubkeys: pubKeys})
+			return
+		}
+		c.JSON(http.StatusOK, pubKeys)
+	}
+}
+
+// parseSyncDutiesLimit parses ?limit=, ?sample=random and ?seed= into a function capping the
+// pubkeys, see LimitSyncDuties. Without a seed a random one is drawn and reported in the
+// X-Sample-Seed header, so the sample can be reproduced. It responds 400 and returns false when
+// the values are invalid.
+func parseSyncDutiesLimit(c *gin.Context, client *Web3Client) (func([]string) []string, bool) {
+	limitStr, sampleStr := c.Query("limit"), c.Query("sample")
+	if limitStr == "" {
+		if sampleStr != "" {
+			respondError(c, http.StatusBadRequest, "Sample requires a limit")
+			return nil, false
+		}
+		return func(pubKeys []string) []string { return pubKeys }, true
+	}
+	committeeSize := int(client.Spec().SyncCommitteeSize)
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > committeeSize {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("Limit must be between 1 and %d", committeeSize))
+		return nil, false
+	}
+	if sampleStr != "" && sampleStr != "random" {
+		respondError(c, http.StatusBadRequest, "Sample must be random")
+		return nil, false
+	}
+	seed := client.clock.Now().UnixNano()
+	if seedStr := c.Query("seed"); seedStr != "" {
+		seed, err = strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Seed must be an integer")
+			return nil, false
+		}
+	}
+	sample := sampleStr == "random"
+	if sample {
+		c.Header("X-Sample-Seed", strconv.FormatInt(seed, 10))
+	}
+	return func(pubKeys []string) []string {
+		return LimitSyncDuties(pubKeys, limit, sample, seed)
+	}, true
+}
+
+// getSyncDutiesBestEffort responds with the pubkeys that could be resolved and the indexes of the
+// members that could not. The limit only caps the resolved pubkeys.
+func getSyncDutiesBestEffort(c *gin.Context, client *Web3Client, slotId string, limitDuties func([]string) []string) {
+	duties, err := client.GetSyncCommitteeDutiesBestEffort(c.Request.Context(), slotId)
+	if err != nil {
+		handleClientError(c, err)
+		return
+	}
+	duties.Pubkeys = limitDuties(duties.Pubkeys)
+	setCacheHeader(c)
+	c.JSON(http.StatusOK, duties)
+}
+
+// getSyncSubcommittees responds with the committee split into its subcommittees. Members are
+// assigned by position, so a committee with unresolved members answers 206 with the resolved
+// pubkeys and the missing indexes instead, and one that does not have the committee size of the
+// chain spec answers 502.
+func getSyncSubcommittees(c *gin.Context, client *Web3Client, slotId string) {
+	duties, err := client.GetSyncCommitteeDutiesBestEffort(c.Request.Context(), slotId)
+	if err != nil {
+		handleClientError(c, err)
+		return
+	}
+	setCacheHeader(c)
+	if len(duties.Missing) > 0 {
+		c.JSON(http.StatusPartialContent, duties)
+		return
+	}
+	subcommittees, err := GroupSyncSubcommittees(duties.Pubkeys, client.Spec().SyncCommitteeSize)
+	if err != nil {
+		log.Info().Err(err).Str("slotId", slotId).Msg("can not group sync committee")
+		respondError(c, http.StatusBadGateway, "Sync committee can not be split into subcommittees")
+		return
+	}
+	c.JSON(http.StatusOK, subcommittees)
+}
+
+func GetGraffitiHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slotId := c.Param("slotId")
+		graffiti, err := client.GetGraffitiBySlot(c.Request.Context(), slotId)
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, graffiti)
+	}
+}
+
+func GetBlockBuilderHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slotId := c.Param("slotId")
+		builder, err := client.GetBlockBuilder(c.Request.Context(), slotId)
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, builder)
+	}
+}
+
+func GetBlockNumberHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slotId := c.Param("slotId")
+		blockNumber, err := client.GetBlockNumberBySlot(c.Request.Context(), slotId)
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"blockNumber": strconv.FormatUint(blockNumber, 10),
+		})
+	}
+}
+
+// GetRawBlockHandler streams the beacon block of the slot as returned by the beacon node.
+func GetRawBlockHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slotId := c.Param("slotId")
+		block, err := client.GetRawBlock(c.Request.Context(), slotId)
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		defer func() {
+			if err := block.Body.Close(); err != nil {
+				log.Info().Err(err).Str("slotId", slotId).Msg("can not close raw block body")
 			}
-			if errors.As(err, &futureSlotError) {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": err.Error(),
-				})
+		}()
+		contentType := block.ContentType
+		if contentType == "" {
+			contentType = binding.MIMEJSON
+		}
+		c.DataFromReader(block.StatusCode, block.ContentLength, contentType, block.Body, nil)
+	}
+}
+
+// GetCompareBlockRewardsHandler returns the rewards of the slots in the a and b query parameters
+// side by side with their difference.
+func GetCompareBlockRewardsHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bypassCacheIfRequested(c)
+		comparison, err := client.CompareBlockRewards(c.Request.Context(), c.Query("a"), c.Query("b"))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, comparison)
+	}
+}
+
+// GetBlockFeesHandler returns the base fee, gas used, gas limit and burnt fees of the block of the
+// slot.
+func GetBlockFeesHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fees, err := client.GetBlockFees(c.Request.Context(), c.Param("slotId"))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, fees)
+	}
+}
+
+func GetLogsSummaryHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		summary, err := client.GetLogsSummary(c.Request.Context(), c.Param("slotId"))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, summary)
+	}
+}
+
+// GetFeeSplitHandler returns the share of the gas fees of the block of the slot that was burnt and
+// the share paid as tips.
+func GetFeeSplitHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		split, err := client.GetFeeSplit(c.Request.Context(), c.Param("slotId"))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, split)
+	}
+}
+
+// GetBurntTotalHandler returns the ETH burnt in the blocks of the slot range given by the from
+// and to query parameters.
+func GetBurntTotalHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		total, err := client.GetBurntTotal(c.Request.Context(), c.Query("from"), c.Query("to"))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, total)
+	}
+}
+
+// GetAverageRewardHandler returns the average reward of the slot range given by the from and to
+// query parameters, formatted in the unit (gwei by default) with precision decimals (the decimals
+// of the unit by default).
+func GetAverageRewardHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		unit := c.DefaultQuery("unit", "gwei")
+		precision, _ := UnitDecimals(unit)
+		if precisionStr := c.Query("precision"); precisionStr != "" {
+			var err error
+			precision, err = strconv.Atoi(precisionStr)
+			if err != nil || precision < 0 || precision > MaxPrecision {
+				respondError(c, http.StatusBadRequest, fmt.Sprintf("Precision must be between 0 and %d", MaxPrecision))
 				return
 			}
-			c.JSON(http.StatusInternalServerError, nil)
+		}
+		average, err := client.GetAverageReward(c.Request.Context(), c.Query("from"), c.Query("to"), unit, precision)
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, average)
+	}
+}
+
+func GetMevRatioHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ratio, err := client.GetMevRatio(c.Request.Context(), c.Query("from"), c.Query("to"))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ratio)
+	}
+}
+
+func GetEpochBlockRewardsHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rewards, err := client.GetEpochBlockRewards(c.Request.Context(), c.Param("epoch"))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		if wantsNDJSON(c) {
+			writeNDJSON(c, rewards.Rewards)
+			return
+		}
+		c.JSON(http.StatusOK, rewards)
+	}
+}
+
+func GetProposerDutiesHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		duties, err := client.GetProposerDuties(c.Request.Context(), c.Param("epoch"))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, duties)
+	}
+}
+
+func GetSlotByBlockNumberHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slot, err := client.GetSlotByBlockNumber(c.Request.Context(), c.Param("number"))
+		if err != nil {
+			handleClientError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{
-			"reward": reward,
-			"status": status,
+			"slot": strconv.FormatUint(slot, 10),
 		})
 	}
 }
 
-func GetSyncDutiesHandler(client *Web3Client) gin.HandlerFunc {
+// GetSlotAtTimeHandler maps the unix timestamp in the ts query parameter to the slot that was
+// the head slot at that time.
+func GetSlotAtTimeHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timestamp, err := strconv.ParseInt(c.Query("ts"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Timestamp is invalid")
+			return
+		}
+		slot, err := client.SlotAtTime(time.Unix(timestamp, 0))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"slot": strconv.FormatUint(slot, 10),
+		})
+	}
+}
+
+// GetForkHandler returns the name of the fork active at the slot.
+func GetForkHandler(client *Web3Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		slotId := c.Param("slotId")
-		pubKeys, err := client.GetSyncCommitteeDuties(slotId)
-		if err != nil {
-			var slotMissingError *SlotMissingError
-			var futureSlotError *FutureSlotError
-			if errors.As(err, &slotMissingError) {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error": err.Error(),
-				})
-				return
-			}
-			if errors.As(err, &futureSlotError) {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": err.Error(),
-				})
-				return
+		fork, err := client.GetForkAtSlot(slotId)
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"slot": slotId,
+			"fork": fork,
+		})
+	}
+}
+
+// GetSyncPeriodHandler returns the sync committee period boundaries of the slot in the slot
+// query parameter, or of the current slot when it is missing.
+func GetSyncPeriodHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		period, err := client.GetSyncPeriod(c.Query("slot"))
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, period)
+	}
+}
+
+// GetMergeStatsHandler returns how many slots and seconds passed between the merge and the head.
+func GetMergeStatsHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats, err := client.GetMergeStats(c.Request.Context())
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	}
+}
+
+// writeNDJSON responds with one JSON object per line.
+func writeNDJSON[T any](c *gin.Context, items []T) {
+	c.Header("Content-Type", MIMENDJSON)
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			log.Info().Err(err).Msg("can not write ndjson response")
+			return
+		}
+	}
+}
+
+type blockRewardsRequest struct {
+	Slots []string `json:"slots" binding:"required,min=1"`
+}
+
+// GetBlockRewardsHandler returns the rewards of up to MaxSlotRange posted slots.
+func GetBlockRewardsHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request blockRewardsRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(request.Slots) > MaxSlotRange {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("At most %d slots can be requested at once", MaxSlotRange))
+			return
+		}
+		streamBlockRewards(c, client, request.Slots)
+	}
+}
+
+// streamBlockRewards writes {"rewards": [...]} with every reward encoded and flushed as soon as it
+// and the rewards of the slots before it are computed. Clients accepting application/x-ndjson get
+// one reward object per line instead. Once streaming started the status can no longer change, so
+// a failing write only ends the stream. A stream cut short or holding upstream failures is marked
+// incomplete so an idempotent retry computes it again.
+func streamBlockRewards(c *gin.Context, client *Web3Client, slotIds []string) {
+	ndjson := wantsNDJSON(c)
+	prefix, separator, suffix := `{"rewards":[`, ",", "]}"
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	if ndjson {
+		prefix, separator, suffix = "", "", ""
+		c.Header("Content-Type", MIMENDJSON)
+	}
+	c.Status(http.StatusOK)
+	writer := c.Writer
+	encoder := json.NewEncoder(writer)
+	if _, err := writer.WriteString(prefix); err != nil {
+		markResponseIncomplete(c)
+		return
+	}
+	first := true
+	err := client.StreamBlockRewards(c.Request.Context(), slotIds, func(reward SlotReward) error {
+		if reward.Error == UpstreamFailedMessage {
+			markResponseIncomplete(c)
+		}
+		if !first {
+			if _, err := writer.WriteString(separator); err != nil {
+				return err
 			}
-			c.JSON(http.StatusInternalServerError, nil)
+		}
+		first = false
+		if err := encoder.Encode(reward); err != nil {
+			return err
+		}
+		writer.Flush()
+		return nil
+	})
+	if err != nil {
+		log.Info().Err(err).Msg("can not stream block rewards")
+		markResponseIncomplete(c)
+		return
+	}
+	_, _ = writer.WriteString(suffix)
+}
+
+type validatorIndexesRequest struct {
+	PubKeys []string `json:"pubkeys" binding:"required,min=1,dive,hexadecimal,len=98"`
+	Slot    string   `json:"slot"`
+}
+
+// GetValidatorIndexesHandler resolves the indexes of the posted pubkeys at the given slot, or at
+// the head when no slot is given.
+func GetValidatorIndexesHandler(client *Web3Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request validatorIndexesRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
 			return
 		}
-		c.JSON(http.StatusOK, pubKeys)
+		slotId := request.Slot
+		if slotId == "" {
+			slotId = "head"
+		}
+		indexes, unknown, err := client.ResolveValidatorIndexes(c.Request.Context(), slotId, request.PubKeys)
+		if err != nil {
+			handleClientError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"indexes": indexes,
+			"unknown": unknown,
+		})
 	}
 }
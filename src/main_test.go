@@ -0,0 +1,478 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/bilbeyt/staking_facilities_assignment/pb"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func performRequest(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	return performRequestWithAccept(router, path, "")
+}
+
+func performRequestWithAccept(router *gin.Engine, path string, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func setupRouter(testKey string) (*gin.Engine, func()) {
+	gin.SetMode(gin.TestMode)
+	server := setupServer(testKey)
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100)
+	router := gin.New()
+	router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(client))
+	router.GET("/syncduties/:slotId", src.GetSyncDutiesHandler(client))
+	router.GET("/slot/:slotId/full", src.GetBlockRewardDetailsHandler(client))
+	return router, server.Close
+}
+
+func TestBlockRewardHandlerWeiFormat(t *testing.T) {
+	router, closeServer := setupRouter("vanilla")
+	defer closeServer()
+	recorder := performRequest(router, "/blockreward/4700013?format=wei")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", recorder.Code)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if reward, ok := response["reward"].(float64); !ok || reward != 1 {
+		t.Errorf("Expected reward to be the number 1, but got %v", response["reward"])
+	}
+	if _, ok := response["overflow"]; ok {
+		t.Errorf("Expected no overflow flag, but got %v", response["overflow"])
+	}
+}
+
+func TestBlockRewardHandlerUnitFormats(t *testing.T) {
+	router, closeServer := setupRouter("vanilla")
+	defer closeServer()
+	tests := []struct {
+		path     string
+		code     int
+		expected string
+	}{
+		{"/blockreward/4700013?format=object", http.StatusOK, `{"reward":{"value":"0.000000001","unit":"gwei"},"status":"vanilla"}`},
+		{"/blockreward/4700013?format=object&unit=wei", http.StatusOK, `{"reward":{"value":"1","unit":"wei"},"status":"vanilla"}`},
+		{"/blockreward/4700013?format=suffixed&unit=eth", http.StatusOK, `{"reward":"0.000000000000000001 eth","status":"vanilla"}`},
+		{"/blockreward/4700013?format=suffixed&unit=finney", http.StatusBadRequest, `{"error":"Unit is invalid"}`},
+	}
+	for _, test := range tests {
+		recorder := performRequest(router, test.path)
+		if recorder.Code != test.code || recorder.Body.String() != test.expected {
+			t.Errorf("Expected %d %s for %s, but got %d %s", test.code, test.expected, test.path, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestBlockRewardHandlerWeiFormatOverflow(t *testing.T) {
+	router, closeServer := setupRouter("rewardOverflow")
+	defer closeServer()
+	recorder := performRequest(router, "/blockreward/4700013?format=wei")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", recorder.Code)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if reward, ok := response["reward"].(string); !ok || reward != "18446744073709551614" {
+		t.Errorf("Expected reward to be the string 18446744073709551614, but got %v", response["reward"])
+	}
+	if response["overflow"] != true {
+		t.Errorf("Expected overflow to be true, but got %v", response["overflow"])
+	}
+}
+
+func TestBlockRewardDetailsHandler(t *testing.T) {
+	router, closeServer := setupRouter("detailedDeep")
+	defer closeServer()
+	recorder := performRequest(router, "/slot/4700013/full")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", recorder.Code)
+	}
+	var details struct {
+		Slot             string `json:"slot"`
+		BlockHash        string `json:"blockHash"`
+		FeeRecipient     string `json:"feeRecipient"`
+		TransactionCount int    `json:"transactionCount"`
+		Fees             int64  `json:"fees"`
+		Burnt            int64  `json:"burnt"`
+		Tips             int64  `json:"tips"`
+		Reward           int64  `json:"reward"`
+		Status           string `json:"status"`
+		Transactions     []struct {
+			Fee              int64 `json:"fee"`
+			Tip              int64 `json:"tip"`
+			ReceiptAvailable bool  `json:"receiptAvailable"`
+		} `json:"transactions"`
+		Timings map[string]int64 `json:"timings"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &details); err != nil {
+		t.Fatal(err)
+	}
+	if details.Slot != "4700013" || details.Status != "mev" || details.TransactionCount != 1 {
+		t.Errorf("Unexpected slot, status or transaction count: %+v", details)
+	}
+	if details.BlockHash != "0x0000000000000000000000000000000000000000000000000000000000001111" {
+		t.Errorf("Expected block hash of the beacon block, but got %s", details.BlockHash)
+	}
+	if details.Fees != 4 || details.Burnt != 2 || details.Tips != 3 || details.Reward != 2 {
+		t.Errorf("Unexpected amounts: fees %d, burnt %d, tips %d, reward %d", details.Fees, details.Burnt, details.Tips, details.Reward)
+	}
+	if len(details.Transactions) != 1 || details.Transactions[0].Fee != 4 || !details.Transactions[0].ReceiptAvailable {
+		t.Errorf("Unexpected transaction contributions: %+v", details.Transactions)
+	}
+	for _, phase := range []string{"headLookupNs", "blockFetchNs", "receiptsNs", "totalNs"} {
+		if _, ok := details.Timings[phase]; !ok {
+			t.Errorf("Expected timing for %s", phase)
+		}
+	}
+}
+
+func TestBlockRewardHandlerProtoBuf(t *testing.T) {
+	router, closeServer := setupRouter("mev")
+	defer closeServer()
+	recorder := performRequestWithAccept(router, "/blockreward/4700013", "application/x-protobuf")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/x-protobuf" {
+		t.Errorf("Expected protobuf content type, but got %s", contentType)
+	}
+	var response pb.BlockRewardResponse
+	if err := proto.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Reward != "0.000000002" || response.Status != "mev" {
+		t.Errorf("Expected reward 0.000000002 and status mev, but got %s and %s", response.Reward, response.Status)
+	}
+}
+
+func TestSyncDutiesHandlerProtoBuf(t *testing.T) {
+	router, closeServer := setupRouter("syncDuties")
+	defer closeServer()
+	recorder := performRequestWithAccept(router, "/syncduties/4700013", "application/x-protobuf")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", recorder.Code)
+	}
+	var response pb.SyncDutiesResponse
+	if err := proto.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Pubkeys) != 1 || response.Pubkeys[0] != "0x0000000000000000000000000000000000000000000000000000000000000001" {
+		t.Errorf("Unexpected pubkeys %v", response.Pubkeys)
+	}
+}
+
+func TestBlockRewardHandlerDefaultsToJSON(t *testing.T) {
+	router, closeServer := setupRouter("mev")
+	defer closeServer()
+	recorder := performRequest(router, "/blockreward/4700013")
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json; charset=utf-8" {
+		t.Errorf("Expected JSON content type, but got %s", contentType)
+	}
+}
+
+func TestBlockRewardHandlerServerTiming(t *testing.T) {
+	router, closeServer := setupRouter("vanilla")
+	defer closeServer()
+	serverTiming := regexp.MustCompile(`^head;dur=\d+\.\d{3}, block;dur=\d+\.\d{3}, receipts;dur=\d+\.\d{3}, total;dur=\d+\.\d{3}$`)
+	recorder := performRequest(router, "/blockreward/4700013")
+	if header := recorder.Header().Get("Server-Timing"); !serverTiming.MatchString(header) {
+		t.Errorf("Expected the phase timings in the Server-Timing header, but got %q", header)
+	}
+	// the second request is served from the cache
+	recorder = performRequest(router, "/blockreward/4700013")
+	if header := recorder.Header().Get("Server-Timing"); header != `cache;desc="hit"` {
+		t.Errorf("Expected a cache hit in the Server-Timing header, but got %q", header)
+	}
+}
+
+func TestBlockRewardHandlerDetailed(t *testing.T) {
+	router, closeServer := setupRouter("detailedDeep")
+	defer closeServer()
+	recorder := performRequest(router, "/blockreward/4700013?detailed=true")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", recorder.Code)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response["depth"] != float64(2) || response["finalized"] != true {
+		t.Errorf("Expected depth 2 and finalized, but got %v and %v", response["depth"], response["finalized"])
+	}
+	if _, ok := response["transactions"]; ok {
+		t.Error("Expected transactions to be left out of the detailed response")
+	}
+}
+
+func TestBlockRewardHandlerDetailedZeroReward(t *testing.T) {
+	// the transaction pays the base fee of 1 for the 2 gas of the block, which is all burnt
+	router, closeServer := setupRouter("zeroReward")
+	defer closeServer()
+	recorder := performRequest(router, "/blockreward/4700013?detailed=true")
+	var response map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); recorder.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected status 200, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if response["fees"] != response["burnt"] || response["reward"] != float64(0) || response["isZeroReward"] != true {
+		t.Errorf("Expected fees equal to the burnt fees and a zero reward, but got %s", recorder.Body.String())
+	}
+
+	router, closeMevServer := setupRouter("detailedDeep")
+	defer closeMevServer()
+	recorder = performRequest(router, "/blockreward/4700013?detailed=true")
+	if !strings.Contains(recorder.Body.String(), `"isZeroReward":false`) {
+		t.Errorf("Expected a reward above zero not to be flagged, but got %s", recorder.Body.String())
+	}
+}
+
+func TestBlockRewardHandlerFlagsTruncation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupServer("hugeBlock")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100000, src.WithRateBurst(1000), src.WithMaxReceiptCalls(10))
+	router := gin.New()
+	router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(client))
+
+	expected := `{"reward":"0.000000078","status":"mev","truncated":true}`
+	for _, cache := range []string{"MISS", "HIT"} {
+		recorder := performRequest(router, "/blockreward/4700013")
+		if recorder.Body.String() != expected || recorder.Header().Get("X-Cache") != cache {
+			t.Errorf("Expected %s on a cache %s, but got %s %s", expected, cache, recorder.Header().Get("X-Cache"), recorder.Body.String())
+		}
+	}
+}
+
+func TestCheckUpstream(t *testing.T) {
+	server := setupServer("mev")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	slot, err := src.CheckUpstream(context.Background(), src.NewWeb3Client(parsedUrl, 100))
+	if err != nil {
+		t.Fatalf("Expected healthy upstream, but got %v", err)
+	}
+	if slot.Sign() <= 0 {
+		t.Errorf("Expected a positive head slot, but got %s", slot)
+	}
+}
+
+func TestCheckUpstreamUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	if _, err := src.CheckUpstream(context.Background(), src.NewWeb3Client(parsedUrl, 100)); err == nil {
+		t.Error("Expected the check to fail")
+	}
+}
+
+func TestBlockNumberHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupServer("vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/slot/:slotId/blocknumber", src.GetBlockNumberHandler(src.NewWeb3Client(parsedUrl, 100)))
+	recorder := performRequest(router, "/slot/4700013/blocknumber")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"blockNumber":"15537394"}` {
+		t.Errorf("Expected block number 15537394, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := performRequest(router, "/slot/1/blocknumber"); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a pre-merge slot, but got %d", recorder.Code)
+	}
+}
+
+func TestRawBlockHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupServer("graffiti")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/slot/:slotId/raw", src.GetRawBlockHandler(src.NewWeb3Client(parsedUrl, 100)))
+	recorder := performRequest(router, "/slot/4700013/raw")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", recorder.Code)
+	}
+	if recorder.Body.String() != src.AllTestData["graffiti"].BlocksResponse {
+		t.Errorf("Expected the upstream block to be passed through, but got %s", recorder.Body.String())
+	}
+}
+
+func TestRawBlockHandlerFutureSlot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/slot/:slotId/raw", src.GetRawBlockHandler(src.NewWeb3Client(parsedUrl, 100)))
+	recorder := performRequest(router, "/slot/4700013/raw")
+	if recorder.Code != http.StatusBadRequest || recorder.Body.String() != `{"error":"Slot is in the future"}` {
+		t.Errorf("Expected a future slot error, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestSlotByBlockNumberHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	// the execution block of slot 4700013 is produced at genesis + 4700013 * 12 seconds
+	header := strings.Replace(src.AllTestData["vanilla"].BlockHashResponse, `"timestamp": "0x111"`, `"timestamp": "0x6322c973"`, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		if bytes.Contains(body, []byte("eth_getBlockByNumber")) {
+			_, _ = rw.Write([]byte(header))
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/blocknumber/:number/slot", src.GetSlotByBlockNumberHandler(src.NewWeb3Client(parsedUrl, 100)))
+
+	recorder := performRequest(router, "/blocknumber/15537394/slot")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"slot":"4700013"}` {
+		t.Errorf("Expected slot 4700013, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := performRequest(router, "/blocknumber/15537395/slot"); recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected a mismatching beacon block to fail, but got %d", recorder.Code)
+	}
+	if recorder := performRequest(router, "/blocknumber/abc/slot"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid block number, but got %d", recorder.Code)
+	}
+}
+
+func TestBlockRewardHandlerDetailedEstimatedTotal(t *testing.T) {
+	tests := []struct {
+		testKey         string
+		consensusReward interface{}
+		estimatedTotal  interface{}
+	}{
+		// EL reward of 2 wei plus a CL reward of 3 gwei
+		{"detailedWithConsensus", float64(3000000000), float64(3000000002)},
+		{"detailedDeep", nil, nil},
+	}
+	for _, test := range tests {
+		router, closeServer := setupRouter(test.testKey)
+		recorder := performRequest(router, "/blockreward/4700013?detailed=true")
+		closeServer()
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, but got %d", test.testKey, recorder.Code)
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response["consensusReward"] != test.consensusReward || response["estimatedTotal"] != test.estimatedTotal {
+			t.Errorf("%s: expected consensus reward %v and estimated total %v, but got %v and %v", test.testKey,
+				test.consensusReward, test.estimatedTotal, response["consensusReward"], response["estimatedTotal"])
+		}
+	}
+}
+
+func TestErrorResponseFollowsAccept(t *testing.T) {
+	router, closeServer := setupRouter("vanilla")
+	defer closeServer()
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", "application/json; charset=utf-8", `{"error":"Slot is before the merge slot 4700013"}`},
+		{"*/*", "application/json; charset=utf-8", `{"error":"Slot is before the merge slot 4700013"}`},
+		{"application/json", "application/json; charset=utf-8", `{"error":"Slot is before the merge slot 4700013"}`},
+		{"text/plain", "text/plain; charset=utf-8", "Slot is before the merge slot 4700013"},
+	}
+	for _, tt := range tests {
+		recorder := performRequestWithAccept(router, "/blockreward/1", tt.accept)
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Accept %q: expected status 404, but got %d", tt.accept, recorder.Code)
+		}
+		if contentType := recorder.Header().Get("Content-Type"); contentType != tt.contentType {
+			t.Errorf("Accept %q: expected content type %s, but got %s", tt.accept, tt.contentType, contentType)
+		}
+		if recorder.Body.String() != tt.body {
+			t.Errorf("Accept %q: expected body %s, but got %s", tt.accept, tt.body, recorder.Body.String())
+		}
+	}
+}
+
+func TestMergeStatsHandler(t *testing.T) {
+	server := setupServer("vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/stats/merge", src.GetMergeStatsHandler(src.NewWeb3Client(parsedUrl, 1000)))
+
+	// the mocked head is slot 4700015, two slots of 12 seconds after the merge slot
+	recorder := performRequest(router, "/stats/merge")
+	expected := `{"mergeSlot":"4700013","currentSlot":"4700015","slotsSinceMerge":"2","secondsSinceMerge":"24"}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestBlockRewardHandlerDefaultsToHead(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupServer("vanilla")
+	defer upstream.Close()
+	var mu sync.Mutex
+	var blockPaths []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, src.BlockDetailPath) {
+			mu.Lock()
+			blockPaths = append(blockPaths, req.URL.Path)
+			mu.Unlock()
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	src.RegisterRoutes(router, src.NewWeb3Client(parsedUrl, 1000), src.DefaultRouteTimeouts(), false)
+
+	for _, path := range []string{"/blockreward", "/blockreward/head", "/blockreward/4700013"} {
+		recorder := performRequest(router, path)
+		if recorder.Code != http.StatusOK || recorder.Body.String() != `{"reward":"0.000000001","status":"vanilla"}` {
+			t.Errorf("Expected the reward for %s, but got %d %s", path, recorder.Code, recorder.Body.String())
+		}
+	}
+	// the head reported by the beacon node is 4700015
+	expected := []string{src.BlockDetailPath + "4700015", src.BlockDetailPath + "4700013"}
+	for _, path := range blockPaths {
+		if !slices.Contains(expected, path) {
+			t.Errorf("Expected only the blocks %v to be fetched, but got %s", expected, path)
+		}
+	}
+	if !slices.Contains(blockPaths, expected[0]) || !slices.Contains(blockPaths, expected[1]) {
+		t.Errorf("Expected the blocks %v to be fetched, but got %v", expected, blockPaths)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const MetricsNamespace = "beacon_rewards"
+
+// NewMetricsRegistry returns a registry exposing the cache usage of the client. The values are
+// read from the cache counters when the registry is scraped, so they are never out of date. Reads
+// are labelled with the cache they went to, so a cold cache does not hide behind a warm one.
+func NewMetricsRegistry(client *Web3Client) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "cache_entries",
+		Help:      "Cached rewards, blocks and sync committees, -1 when the cache can not report its size.",
+	}, func() float64 {
+		return float64(client.CacheStats(context.Background()).Entries)
+	}))
+	for _, name := range CacheNames {
+		labels := prometheus.Labels{"cache": name}
+		registry.MustRegister(
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Namespace:   MetricsNamespace,
+				Name:        "cache_hits_total",
+				Help:        "Reads of rewards, blocks or sync committees served from the cache.",
+				ConstLabels: labels,
+			}, func() float64 {
+				hits, _ := client.CacheReads(name)
+				return float64(hits)
+			}),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Namespace:   MetricsNamespace,
+				Name:        "cache_misses_total",
+				Help:        "Reads of rewards, blocks or sync committees that were not cached.",
+				ConstLabels: labels,
+			}, func() float64 {
+				_, misses := client.CacheReads(name)
+				return float64(misses)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace:   MetricsNamespace,
+				Name:        "cache_hit_ratio",
+				Help:        "Share of the reads that were hits since the start, 0 before the first read.",
+				ConstLabels: labels,
+			}, func() float64 {
+				hits, misses := client.CacheReads(name)
+				if hits+misses == 0 {
+					return 0
+				}
+				return float64(hits) / float64(hits+misses)
+			}),
+		)
+	}
+	return registry
+}
+
+// RegisterMetricsRoute serves the metrics of the registry on /metrics in the Prometheus text
+// format.
+func RegisterMetricsRoute(router gin.IRouter, registry *prometheus.Registry) {
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+}
@@ -0,0 +1,42 @@
+package main_test
+
+import (
+	"context"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMetricsReportCacheUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupServer("vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+	ctx := context.Background()
+	// the first read of each slot misses the reward and the block, the later ones hit the reward
+	for _, slotId := range []string{"4700013", "4700013", "4700014", "4700014", "4700014"} {
+		if _, _, err := client.GetBlockRewardAndStatusBySlot(ctx, slotId); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := gin.New()
+	src.RegisterMetricsRoute(router, src.NewMetricsRegistry(client))
+	recorder := performRequest(router, "/metrics")
+	for _, line := range []string{
+		`beacon_rewards_cache_hits_total{cache="reward"} 3`,
+		`beacon_rewards_cache_misses_total{cache="reward"} 2`,
+		`beacon_rewards_cache_hit_ratio{cache="reward"} 0.6`,
+		`beacon_rewards_cache_hits_total{cache="block"} 0`,
+		`beacon_rewards_cache_misses_total{cache="block"} 2`,
+		`beacon_rewards_cache_hit_ratio{cache="block"} 0`,
+		`beacon_rewards_cache_misses_total{cache="committee"} 0`,
+		"beacon_rewards_cache_entries 4",
+	} {
+		if !strings.Contains(recorder.Body.String(), line+"\n") {
+			t.Errorf("Expected metrics to contain %q, but got %s", line, recorder.Body.String())
+		}
+	}
+}
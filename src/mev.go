@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+)
+
+// TestnetMinPriorityFee is the priority fee per gas, in wei, below which a transaction never
+// marks its block as mev on testnets. Their base fee stays at a few wei, so any usual tip pays
+// more than three times the base fee.
+var TestnetMinPriorityFee = big.NewInt(10_000_000_000)
+
+// testnets are the networks that get the testnet thresholds by default.
+var testnets = []string{"goerli", "holesky", "hoodi", "sepolia"}
+
+// MevThresholds decide when a transaction marks its block as mev: it has to pay more than Factor
+// times the base fee per gas and at least MinPriorityFee wei per gas above the base fee.
+type MevThresholds struct {
+	Factor         int64
+	MinPriorityFee *big.Int
+}
+
+// MevThresholdsFor returns the default thresholds of the network. Testnets require
+// TestnetMinPriorityFee on top of the base fee factor, other networks only the factor.
+func MevThresholdsFor(network string) MevThresholds {
+	thresholds := MevThresholds{Factor: MevFeeCalculationFactor, MinPriorityFee: new(big.Int)}
+	if slices.Contains(testnets, network) {
+		thresholds.MinPriorityFee = TestnetMinPriorityFee
+	}
+	return thresholds
+}
+
+// WithOverrides replaces the factor and the minimum priority fee with the given values, empty
+// values keep the current ones.
+func (t MevThresholds) WithOverrides(factor string, minPriorityFee string) (MevThresholds, error) {
+	if factor != "" {
+		parsed, err := strconv.ParseInt(factor, 10, 64)
+		if err != nil || parsed < 1 {
+			return t, fmt.Errorf("mev fee factor must be an integer of at least 1")
+		}
+		t.Factor = parsed
+	}
+	if minPriorityFee != "" {
+		parsed, ok := new(big.Int).SetString(minPriorityFee, 10)
+		if !ok || parsed.Sign() < 0 {
+			return t, fmt.Errorf("mev minimum priority fee must be a non-negative integer in wei")
+		}
+		t.MinPriorityFee = parsed
+	}
+	return t, nil
+}
+
+func (t MevThresholds) isMev(gasPrice *big.Int, baseFee *big.Int) bool {
+	if gasPrice.Cmp(new(big.Int).Mul(baseFee, big.NewInt(t.Factor))) != 1 {
+		return false
+	}
+	return t.MinPriorityFee == nil || new(big.Int).Sub(gasPrice, baseFee).Cmp(t.MinPriorityFee) >= 0
+}
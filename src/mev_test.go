@@ -0,0 +1,48 @@
+package main_test
+
+import (
+	"context"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"math/big"
+	"net/url"
+	"testing"
+)
+
+func TestTestnetThresholdsDoNotFlagUsualTipsAsMev(t *testing.T) {
+	server := setupServer("lowBaseFee")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	tests := []struct {
+		thresholds src.MevThresholds
+		status     string
+	}{
+		{src.MevThresholdsFor("mainnet"), src.StatusMev},
+		{src.MevThresholdsFor("holesky"), src.StatusVanilla},
+		{src.MevThresholds{Factor: 3, MinPriorityFee: big.NewInt(1_000_000_000)}, src.StatusMev},
+	}
+	for _, tt := range tests {
+		client := src.NewWeb3Client(parsedUrl, 1000, src.WithMevThresholds(tt.thresholds))
+		_, status, err := client.GetBlockRewardWei(context.Background(), "4700013")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != tt.status {
+			t.Errorf("Expected %+v to label the block %s, but got %s", tt.thresholds, tt.status, status)
+		}
+	}
+}
+
+func TestMevThresholdsWithOverrides(t *testing.T) {
+	thresholds, err := src.MevThresholdsFor("sepolia").WithOverrides("5", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thresholds.Factor != 5 || thresholds.MinPriorityFee.Cmp(src.TestnetMinPriorityFee) != 0 {
+		t.Errorf("Expected factor 5 with the testnet floor, but got %+v", thresholds)
+	}
+	for _, values := range [][2]string{{"0", ""}, {"x", ""}, {"", "-1"}, {"", "1.5"}} {
+		if _, err := thresholds.WithOverrides(values[0], values[1]); err == nil {
+			t.Errorf("Expected overrides %v to be rejected", values)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var networkNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// reservedNetworkNames are the first path segments of the routes served at the root, a network
+// with such a name would shadow them.
+var reservedNetworkNames = append([]string{"admin", "metrics", "ready"}, APIRouteNames...)
+
+// ParseNetworkNames parses network names in the form "holesky,sepolia". Names are lower case
+// letters, digits and dashes and must not collide with the root routes.
+func ParseNetworkNames(value string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !networkNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("network name %q is invalid", name)
+		}
+		if slices.Contains(reservedNetworkNames, name) || slices.Contains(names, name) {
+			return nil, fmt.Errorf("network name %q is already in use", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// RegisterNetworkRoutes adds the API routes of every network under /<network>, served by the
+// client of the network, e.g. /holesky/blockreward/:slotId. Each network keeps its own upstream,
+// cache and configuration. Paths of unknown networks match no route and answer 404.
+func RegisterNetworkRoutes(router gin.IRouter, networks map[string]*Web3Client, timeouts RouteTimeouts, debug bool) {
+	for name, client := range networks {
+		RegisterRoutes(groupRoutes(router, "/"+name), client, timeouts, debug)
+	}
+}
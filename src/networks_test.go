@@ -0,0 +1,56 @@
+package main_test
+
+import (
+	"context"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNetworkRoutesUseTheClientOfTheNetwork(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mainnetServer, holeskyServer := setupServer("vanilla"), setupServer("mev")
+	defer mainnetServer.Close()
+	defer holeskyServer.Close()
+	mainnetUrl, _ := url.Parse(mainnetServer.URL)
+	holeskyUrl, _ := url.Parse(holeskyServer.URL)
+	mainnet := src.NewWeb3Client(mainnetUrl, 100)
+	holesky := src.NewWeb3Client(holeskyUrl, 100)
+	router := gin.New()
+	src.RegisterRoutes(router, mainnet, src.DefaultRouteTimeouts(), false)
+	src.RegisterNetworkRoutes(router, map[string]*src.Web3Client{"mainnet": mainnet, "holesky": holesky}, src.DefaultRouteTimeouts(), false)
+
+	recorder := performRequest(router, "/mainnet/blockreward/4700013")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"reward":"0.000000001","status":"vanilla"}` {
+		t.Errorf("Expected the vanilla reward of mainnet, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if stats := holesky.CacheStats(context.Background()); stats.Entries != 0 {
+		t.Errorf("Expected the holesky cache to stay empty, but got %d entries", stats.Entries)
+	}
+	recorder = performRequest(router, "/holesky/blockreward/4700013")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"status":"mev"`) {
+		t.Errorf("Expected the mev reward of holesky, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if stats := mainnet.CacheStats(context.Background()); stats.Entries != 2 || stats.Misses != 2 {
+		t.Errorf("Expected only the mainnet request in the mainnet cache, but got %+v", stats)
+	}
+
+	if recorder := performRequest(router, "/sepolia/blockreward/4700013"); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown network, but got %d", recorder.Code)
+	}
+}
+
+func TestParseNetworkNames(t *testing.T) {
+	names, err := src.ParseNetworkNames(" holesky, sepolia ,")
+	if err != nil || len(names) != 2 || names[0] != "holesky" || names[1] != "sepolia" {
+		t.Errorf("Expected holesky and sepolia, but got %v %v", names, err)
+	}
+	for _, value := range []string{"Holesky", "holesky,holesky", "slot", "a/b"} {
+		if _, err := src.ParseNetworkNames(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
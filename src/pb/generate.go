@@ -0,0 +1,4 @@
+// Package pb contains the protobuf types generated from the definitions in the proto directory.
+package pb
+
+//go:generate protoc --proto_path=../proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative rewards.proto
@@ -0,0 +1,313 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: rewards.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// BlockRewardResponse mirrors the JSON response of the /blockreward endpoint.
+type BlockRewardResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// reward in gwei with 9 decimals
+	Reward string `protobuf:"bytes,1,opt,name=reward,proto3" json:"reward,omitempty"`
+	// vanilla or mev
+	Status string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	// set when the block had more transactions than receipts may be fetched for, the reward then
+	// includes estimated contributions
+	Truncated bool `protobuf:"varint,3,opt,name=truncated,proto3" json:"truncated,omitempty"`
+}
+
+func (x *BlockRewardResponse) Reset() {
+	*x = BlockRewardResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rewards_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockRewardResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockRewardResponse) ProtoMessage() {}
+
+func (x *BlockRewardResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rewards_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockRewardResponse.ProtoReflect.Descriptor instead.
+func (*BlockRewardResponse) Descriptor() ([]byte, []int) {
+	return file_rewards_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BlockRewardResponse) GetReward() string {
+	if x != nil {
+		return x.Reward
+	}
+	return ""
+}
+
+func (x *BlockRewardResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *BlockRewardResponse) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+// SyncDutiesResponse mirrors the JSON response of the /syncduties endpoint.
+type SyncDutiesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pubkeys []string `protobuf:"bytes,1,rep,name=pubkeys,proto3" json:"pubkeys,omitempty"`
+}
+
+func (x *SyncDutiesResponse) Reset() {
+	*x = SyncDutiesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rewards_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SyncDutiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncDutiesResponse) ProtoMessage() {}
+
+func (x *SyncDutiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rewards_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncDutiesResponse.ProtoReflect.Descriptor instead.
+func (*SyncDutiesResponse) Descriptor() ([]byte, []int) {
+	return file_rewards_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SyncDutiesResponse) GetPubkeys() []string {
+	if x != nil {
+		return x.Pubkeys
+	}
+	return nil
+}
+
+type SlotRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Slot string `protobuf:"bytes,1,opt,name=slot,proto3" json:"slot,omitempty"`
+}
+
+func (x *SlotRequest) Reset() {
+	*x = SlotRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rewards_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SlotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SlotRequest) ProtoMessage() {}
+
+func (x *SlotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rewards_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SlotRequest.ProtoReflect.Descriptor instead.
+func (*SlotRequest) Descriptor() ([]byte, []int) {
+	return file_rewards_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SlotRequest) GetSlot() string {
+	if x != nil {
+		return x.Slot
+	}
+	return ""
+}
+
+var File_rewards_proto protoreflect.FileDescriptor
+
+var file_rewards_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x72, 0x65, 0x77, 0x61, 0x72, 0x64, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x14, 0x73, 0x74, 0x61, 0x6b, 0x69, 0x6e, 0x67, 0x66, 0x61, 0x63, 0x69, 0x6c, 0x69, 0x74, 0x69,
+	0x65, 0x73, 0x2e, 0x76, 0x31, 0x22, 0x63, 0x0a, 0x13, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65,
+	0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06,
+	0x72, 0x65, 0x77, 0x61, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65,
+	0x77, 0x61, 0x72, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1c, 0x0a, 0x09,
+	0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x09, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x22, 0x2e, 0x0a, 0x12, 0x53, 0x79,
+	0x6e, 0x63, 0x44, 0x75, 0x74, 0x69, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x07, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x73, 0x22, 0x21, 0x0a, 0x0b, 0x53, 0x6c,
+	0x6f, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x6c, 0x6f,
+	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x6c, 0x6f, 0x74, 0x32, 0xcd, 0x01,
+	0x0a, 0x0d, 0x42, 0x65, 0x61, 0x63, 0x6f, 0x6e, 0x52, 0x65, 0x77, 0x61, 0x72, 0x64, 0x73, 0x12,
+	0x5e, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x77, 0x61, 0x72,
+	0x64, 0x12, 0x21, 0x2e, 0x73, 0x74, 0x61, 0x6b, 0x69, 0x6e, 0x67, 0x66, 0x61, 0x63, 0x69, 0x6c,
+	0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6c, 0x6f, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x29, 0x2e, 0x73, 0x74, 0x61, 0x6b, 0x69, 0x6e, 0x67, 0x66, 0x61,
+	0x63, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c, 0x6f, 0x63,
+	0x6b, 0x52, 0x65, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x5c, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x53, 0x79, 0x6e, 0x63, 0x44, 0x75, 0x74, 0x69, 0x65, 0x73,
+	0x12, 0x21, 0x2e, 0x73, 0x74, 0x61, 0x6b, 0x69, 0x6e, 0x67, 0x66, 0x61, 0x63, 0x69, 0x6c, 0x69,
+	0x74, 0x69, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6c, 0x6f, 0x74, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x28, 0x2e, 0x73, 0x74, 0x61, 0x6b, 0x69, 0x6e, 0x67, 0x66, 0x61, 0x63,
+	0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x44,
+	0x75, 0x74, 0x69, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x35, 0x5a,
+	0x33, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x69, 0x6c, 0x62,
+	0x65, 0x79, 0x74, 0x2f, 0x73, 0x74, 0x61, 0x6b, 0x69, 0x6e, 0x67, 0x5f, 0x66, 0x61, 0x63, 0x69,
+	0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x5f, 0x61, 0x73, 0x73, 0x69, 0x67, 0x6e, 0x6d, 0x65, 0x6e,
+	0x74, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_rewards_proto_rawDescOnce sync.Once
+	file_rewards_proto_rawDescData = file_rewards_proto_rawDesc
+)
+
+func file_rewards_proto_rawDescGZIP() []byte {
+	file_rewards_proto_rawDescOnce.Do(func() {
+		file_rewards_proto_rawDescData = protoimpl.X.CompressGZIP(file_rewards_proto_rawDescData)
+	})
+	return file_rewards_proto_rawDescData
+}
+
+var file_rewards_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_rewards_proto_goTypes = []any{
+	(*BlockRewardResponse)(nil), // 0: stakingfacilities.v1.BlockRewardResponse
+	(*SyncDutiesResponse)(nil),  // 1: stakingfacilities.v1.SyncDutiesResponse
+	(*SlotRequest)(nil),         // 2: stakingfacilities.v1.SlotRequest
+}
+var file_rewards_proto_depIdxs = []int32{
+	2, // 0: stakingfacilities.v1.BeaconRewards.GetBlockReward:input_type -> stakingfacilities.v1.SlotRequest
+	2, // 1: stakingfacilities.v1.BeaconRewards.GetSyncDuties:input_type -> stakingfacilities.v1.SlotRequest
+	0, // 2: stakingfacilities.v1.BeaconRewards.GetBlockReward:output_type -> stakingfacilities.v1.BlockRewardResponse
+	1, // 3: stakingfacilities.v1.BeaconRewards.GetSyncDuties:output_type -> stakingfacilities.v1.SyncDutiesResponse
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_rewards_proto_init() }
+func file_rewards_proto_init() {
+	if File_rewards_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_rewards_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*BlockRewardResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rewards_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*SyncDutiesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rewards_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*SlotRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_rewards_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_rewards_proto_goTypes,
+		DependencyIndexes: file_rewards_proto_depIdxs,
+		MessageInfos:      file_rewards_proto_msgTypes,
+	}.Build()
+	File_rewards_proto = out.File
+	file_rewards_proto_rawDesc = nil
+	file_rewards_proto_goTypes = nil
+	file_rewards_proto_depIdxs = nil
+}
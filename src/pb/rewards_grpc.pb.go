@@ -0,0 +1,152 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: rewards.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	BeaconRewards_GetBlockReward_FullMethodName = "/stakingfacilities.v1.BeaconRewards/GetBlockReward"
+	BeaconRewards_GetSyncDuties_FullMethodName  = "/stakingfacilities.v1.BeaconRewards/GetSyncDuties"
+)
+
+// BeaconRewardsClient is the client API for BeaconRewards service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// BeaconRewards exposes the same operations as the HTTP server.
+type BeaconRewardsClient interface {
+	GetBlockReward(ctx context.Context, in *SlotRequest, opts ...grpc.CallOption) (*BlockRewardResponse, error)
+	GetSyncDuties(ctx context.Context, in *SlotRequest, opts ...grpc.CallOption) (*SyncDutiesResponse, error)
+}
+
+type beaconRewardsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBeaconRewardsClient(cc grpc.ClientConnInterface) BeaconRewardsClient {
+	return &beaconRewardsClient{cc}
+}
+
+func (c *beaconRewardsClient) GetBlockReward(ctx context.Context, in *SlotRequest, opts ...grpc.CallOption) (*BlockRewardResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BlockRewardResponse)
+	err := c.cc.Invoke(ctx, BeaconRewards_GetBlockReward_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *beaconRewardsClient) GetSyncDuties(ctx context.Context, in *SlotRequest, opts ...grpc.CallOption) (*SyncDutiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncDutiesResponse)
+	err := c.cc.Invoke(ctx, BeaconRewards_GetSyncDuties_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BeaconRewardsServer is the server API for BeaconRewards service.
+// All implementations must embed UnimplementedBeaconRewardsServer
+// for forward compatibility
+//
+// BeaconRewards exposes the same operations as the HTTP server.
+type BeaconRewardsServer interface {
+	GetBlockReward(context.Context, *SlotRequest) (*BlockRewardResponse, error)
+	GetSyncDuties(context.Context, *SlotRequest) (*SyncDutiesResponse, error)
+	mustEmbedUnimplementedBeaconRewardsServer()
+}
+
+// UnimplementedBeaconRewardsServer must be embedded to have forward compatible implementations.
+type UnimplementedBeaconRewardsServer struct {
+}
+
+func (UnimplementedBeaconRewardsServer) GetBlockReward(context.Context, *SlotRequest) (*BlockRewardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockReward not implemented")
+}
+func (UnimplementedBeaconRewardsServer) GetSyncDuties(context.Context, *SlotRequest) (*SyncDutiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSyncDuties not implemented")
+}
+func (UnimplementedBeaconRewardsServer) mustEmbedUnimplementedBeaconRewardsServer() {}
+
+// UnsafeBeaconRewardsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BeaconRewardsServer will
+// result in compilation errors.
+type UnsafeBeaconRewardsServer interface {
+	mustEmbedUnimplementedBeaconRewardsServer()
+}
+
+func RegisterBeaconRewardsServer(s grpc.ServiceRegistrar, srv BeaconRewardsServer) {
+	s.RegisterService(&BeaconRewards_ServiceDesc, srv)
+}
+
+func _BeaconRewards_GetBlockReward_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SlotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BeaconRewardsServer).GetBlockReward(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BeaconRewards_GetBlockReward_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BeaconRewardsServer).GetBlockReward(ctx, req.(*SlotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BeaconRewards_GetSyncDuties_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SlotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BeaconRewardsServer).GetSyncDuties(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BeaconRewards_GetSyncDuties_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BeaconRewardsServer).GetSyncDuties(ctx, req.(*SlotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BeaconRewards_ServiceDesc is the grpc.ServiceDesc for BeaconRewards service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BeaconRewards_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "stakingfacilities.v1.BeaconRewards",
+	HandlerType: (*BeaconRewardsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBlockReward",
+			Handler:    _BeaconRewards_GetBlockReward_Handler,
+		},
+		{
+			MethodName: "GetSyncDuties",
+			Handler:    _BeaconRewards_GetSyncDuties_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "rewards.proto",
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+)
+
+const ProposerDutiesPath = "/eth/v1/validator/duties/proposer/"
+
+// ProposerDuty is the validator scheduled to propose the block of a slot.
+type ProposerDuty struct {
+	Slot           string `json:"slot"`
+	ValidatorIndex string `json:"validatorIndex"`
+	Pubkey         string `json:"pubkey"`
+}
+
+type proposerDutyData struct {
+	Pubkey         string       `json:"pubkey"`
+	ValidatorIndex BeaconUint64 `json:"validator_index"`
+	Slot           BeaconUint64 `json:"slot"`
+}
+
+type proposerDutiesResponse struct {
+	Data []proposerDutyData `json:"data"`
+}
+
+// GetProposerDuties returns the proposers of the slots of the epoch in slot order. The beacon node
+// only knows the proposers up to the epoch after the current one, later epochs are rejected as
+// future without asking it.
+func (c *Web3Client) GetProposerDuties(ctx context.Context, epoch string) ([]ProposerDuty, error) {
+	epochAsInt, err := strconv.ParseUint(epoch, 10, 64)
+	if err != nil {
+		return nil, &InvalidSlotError{msg: "Epoch is invalid"}
+	}
+	if epochAsInt > c.clockSlot(c.clock.Now())/c.spec.SlotsPerEpoch+1 {
+		return nil, &FutureSlotError{msg: "Epoch is too far in the future, proposers are known up to the next epoch"}
+	}
+	var response proposerDutiesResponse
+	err = c.sendAPIRequest(ctx, c.BaseUrl.String()+ProposerDutiesPath+epoch, "receive proposer duties", &response)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(response.Data, func(a, b proposerDutyData) int {
+		return cmp.Compare(a.Slot, b.Slot)
+	})
+	duties := make([]ProposerDuty, 0, len(response.Data))
+	for _, duty := range response.Data {
+		duties = append(duties, ProposerDuty{
+			Slot:           duty.Slot.String(),
+			ValidatorIndex: duty.ValidatorIndex.String(),
+			Pubkey:         duty.Pubkey,
+		})
+	}
+	return duties, nil
+}
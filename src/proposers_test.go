@@ -0,0 +1,50 @@
+package main_test
+
+import (
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProposerDutiesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var dutyRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/eth/v1/validator/duties/proposer/146876" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		dutyRequests.Add(1)
+		_, _ = rw.Write([]byte(`{"dependent_root":"0x01","execution_optimistic":false,"data":[
+			{"pubkey":"0xb2","validator_index":"20","slot":"4700033"},
+			{"pubkey":"0xa1","validator_index":"10","slot":"4700032"}]}`))
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/epoch/:epoch/proposers", src.GetProposerDutiesHandler(src.NewWeb3Client(parsedUrl, 1000)))
+
+	recorder := performRequest(router, "/epoch/146876/proposers")
+	expected := `[{"slot":"4700032","validatorIndex":"10","pubkey":"0xa1"},{"slot":"4700033","validatorIndex":"20","pubkey":"0xb2"}]`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+
+	clockEpoch := uint64(time.Since(src.MainnetChainSpec().GenesisTime)/(12*time.Second)) / 32
+	recorder = performRequest(router, "/epoch/"+strconv.FormatUint(clockEpoch+2, 10)+"/proposers")
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an epoch past the next one, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := performRequest(router, "/epoch/abc/proposers"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid epoch, but got %d", recorder.Code)
+	}
+	if dutyRequests.Load() != 1 {
+		t.Errorf("Expected only the valid epoch to reach the beacon node, but got %d requests", dutyRequests.Load())
+	}
+}
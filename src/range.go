@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+	"math"
+	"math/big"
+	"math/bits"
+	"strconv"
+)
+
+// MaxSlotRange is the largest number of slots a range request may cover.
+const MaxSlotRange = 100
+
+// SharedComputationTimeout bounds a reward computation shared by concurrent batches, which
+// follows the deadline of none of them.
+const SharedComputationTimeout = DefaultBatchTimeout
+
+// DefaultBatchConcurrency is the number of slots of a range processed at the same time.
+const DefaultBatchConcurrency = 8
+
+// BurntTotal is the ETH burnt in the blocks of a slot range, in wei. SlotCount is the number of
+// slots of the range that had a block.
+type BurntTotal struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Total     *big.Int `json:"total"`
+	SlotCount int      `json:"slotCount"`
+}
+
+// parseSlotRange validates both ends of the range like a single reward slot and rejects ranges
+// that are reversed or longer than MaxSlotRange.
+func (c *Web3Client) parseSlotRange(from string, to string) (uint64, uint64, error) {
+	fromAsInt, err := c.validateRewardSlot(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	toAsInt, err := c.validateRewardSlot(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if fromAsInt.Cmp(toAsInt) == 1 {
+		return 0, 0, &InvalidSlotError{msg: "Slot range is invalid"}
+	}
+	if new(big.Int).Sub(toAsInt, fromAsInt).Uint64() >= MaxSlotRange {
+		return 0, 0, &InvalidSlotError{msg: fmt.Sprintf("Slot range exceeds %d slots", MaxSlotRange)}
+	}
+	return fromAsInt.Uint64(), toAsInt.Uint64(), nil
+}
+
+// forEachSlot calls fn for every slot of the range with its offset from the start of the range,
+// batchConcurrency slots at a time. The range must end at or before the head, so slots without a
+// block are skipped instead of failing the whole range. The first other error cancels the
+// remaining slots.
+func (c *Web3Client) forEachSlot(ctx context.Context, from uint64, to uint64, fn func(ctx context.Context, offset uint64, slotId string) error) error {
+	head, err := c.getCurrentSlotId(ctx)
+	if err != nil {
+		return err
+	}
+	if new(big.Int).SetUint64(to).Cmp(head) == 1 {
+		return &FutureSlotError{msg: "Slot is in the future"}
+	}
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(c.batchConcurrency)
+	for slot := from; slot <= to; slot++ {
+		offset, slotId := slot-from, strconv.FormatUint(slot, 10)
+		group.Go(func() error {
+			err := fn(ctx, offset, slotId)
+			if errors.Is(err, ErrSlotMissing) || errors.Is(err, ErrFutureSlot) {
+				return nil
+			}
+			return err
+		})
+	}
+	return group.Wait()
+}
+
+// headerByHash fetches the execution block header inside its own span.
+func (c *Web3Client) headerByHash(ctx context.Context, blockHash common.Hash) (header *types.Header, err error) {
+	ctx, span := tracer().Start(ctx, "eth_getBlockByHash", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("block.hash", blockHash.Hex())))
+	defer func() {
+		endSpan(span, err)
+	}()
+	return c.w3Client.HeaderByHash(withUpstreamRequestName(ctx, "eth_getBlockByHash"), blockHash)
+}
+
+// BlockFees are the raw fee inputs of the block of a slot, the base fee and burnt fees in wei.
+type BlockFees struct {
+	BaseFee  *big.Int `json:"baseFee"`
+	GasUsed  uint64   `json:"gasUsed"`
+	GasLimit uint64   `json:"gasLimit"`
+	Burnt    *big.Int `json:"burnt"`
+}
+
+// GetBlockFees returns the base fee and gas of the block of the slot. Only the block header is
+// needed, so no receipts are fetched.
+func (c *Web3Client) GetBlockFees(ctx context.Context, slotId string) (*BlockFees, error) {
+	if _, err := c.validateRewardSlot(slotId); err != nil {
+		return nil, err
+	}
+	blockHash, err := c.getBlockHash(ctx, slotId)
+	if err != nil {
+		return nil, err
+	}
+	header, err := c.headerByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockFees{
+		BaseFee:  header.BaseFee,
+		GasUsed:  header.GasUsed,
+		GasLimit: header.GasLimit,
+		Burnt:    new(big.Int).Mul(header.BaseFee, new(big.Int).SetUint64(header.GasUsed)),
+	}, nil
+}
+
+// LogsSummary is the logs bloom of the block of a slot in hex. BloomBitsSet counts the bits set in
+// the bloom, a quick activity indicator: each log sets up to three bits per address and topic. The
+// header does not carry the number of logs, which would take all receipts.
+type LogsSummary struct {
+	LogsBloom    string `json:"logsBloom"`
+	BloomBitsSet int    `json:"bloomBitsSet"`
+}
+
+// GetLogsSummary returns the logs bloom of the block of the slot. Only the block header is needed,
+// so no receipts are fetched.
+func (c *Web3Client) GetLogsSummary(ctx context.Context, slotId string) (*LogsSummary, error) {
+	if _, err := c.validateRewardSlot(slotId); err != nil {
+		return nil, err
+	}
+	blockHash, err := c.getBlockHash(ctx, slotId)
+	if err != nil {
+		return nil, err
+	}
+	header, err := c.headerByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	bitsSet := 0
+	for _, b := range header.Bloom {
+		bitsSet += bits.OnesCount8(b)
+	}
+	return &LogsSummary{LogsBloom: hexutil.Encode(header.Bloom[:]), BloomBitsSet: bitsSet}, nil
+}
+
+// GetBurntTotal sums the burnt base fees of the blocks between from and to, both included. Only
+// the block headers are needed, so no receipts are fetched.
+func (c *Web3Client) GetBurntTotal(ctx context.Context, from string, to string) (*BurntTotal, error) {
+	fromSlot, toSlot, err := c.parseSlotRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	burnt := make([]*big.Int, toSlot-fromSlot+1)
+	err = c.forEachSlot(ctx, fromSlot, toSlot, func(ctx context.Context, offset uint64, slotId string) error {
+		blockHash, err := c.getBlockHash(ctx, slotId)
+		if err != nil {
+			return err
+		}
+		header, err := c.headerByHash(ctx, blockHash)
+		if err != nil {
+			return err
+		}
+		burnt[offset] = new(big.Int).Mul(header.BaseFee, new(big.Int).SetUint64(header.GasUsed))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	total := &BurntTotal{From: from, To: to, Total: new(big.Int)}
+	for _, slotBurnt := range burnt {
+		if slotBurnt == nil {
+			continue
+		}
+		total.Total.Add(total.Total, slotBurnt)
+		total.SlotCount++
+	}
+	return total, nil
+}
+
+// AverageReward is the average reward of the blocks of a slot range in the unit, next to the total
+// in wei it is computed from. SlotCount is the number of slots of the range that had a block.
+type AverageReward struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Total     *big.Int `json:"total"`
+	SlotCount int      `json:"slotCount"`
+	Average   *string  `json:"average"`
+	Unit      string   `json:"unit"`
+}
+
+// GetAverageReward averages the rewards of the blocks between from and to, both included. The sum
+// is kept in wei and only divided when formatting at the precision, so the average is exact up to
+// the last decimal. The average is nil when no slot of the range had a block.
+func (c *Web3Client) GetAverageReward(ctx context.Context, from string, to string, unit string, precision int) (*AverageReward, error) {
+	if _, ok := UnitDecimals(unit); !ok {
+		return nil, &InvalidSlotError{msg: "Unit is invalid"}
+	}
+	fromSlot, toSlot, err := c.parseSlotRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	rewards := make([]*big.Int, toSlot-fromSlot+1)
+	err = c.forEachSlot(ctx, fromSlot, toSlot, func(ctx context.Context, offset uint64, slotId string) error {
+		reward, _, err := c.GetBlockRewardWei(ctx, slotId)
+		if err != nil {
+			return err
+		}
+		rewards[offset] = reward
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	average := &AverageReward{From: from, To: to, Total: new(big.Int), Unit: unit}
+	for _, reward := range rewards {
+		if reward == nil {
+			continue
+		}
+		average.Total.Add(average.Total, reward)
+		average.SlotCount++
+	}
+	if average.SlotCount > 0 {
+		formatted, err := FormatWeiRatio(average.Total, big.NewInt(int64(average.SlotCount)), unit, precision)
+		if err != nil {
+			return nil, err
+		}
+		average.Average = &formatted
+	}
+	return average, nil
+}
+
+// MevRatio counts the mev and vanilla blocks of a slot range. Blocks with another status, only
+// reported with extended statuses, count as other. The percentages are shares of all blocks of the
+// range with two decimals.
+type MevRatio struct {
+	From           string `json:"from"`
+	To             string `json:"to"`
+	BlockCount     int    `json:"blockCount"`
+	Mev            int    `json:"mev"`
+	Vanilla        int    `json:"vanilla"`
+	Other          int    `json:"other"`
+	MevPercent     string `json:"mevPercent"`
+	VanillaPercent string `json:"vanillaPercent"`
+}
+
+// GetMevRatio computes the statuses of the blocks between from and to, both included, like a
+// slot range. Slots without a block are skipped.
+func (c *Web3Client) GetMevRatio(ctx context.Context, from string, to string) (*MevRatio, error) {
+	fromSlot, toSlot, err := c.parseSlotRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]string, toSlot-fromSlot+1)
+	err = c.forEachSlot(ctx, fromSlot, toSlot, func(ctx context.Context, offset uint64, slotId string) error {
+		_, status, err := c.GetBlockRewardWei(ctx, slotId)
+		if err != nil {
+			return err
+		}
+		statuses[offset] = status
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ratio := &MevRatio{From: from, To: to, MevPercent: "0.00", VanillaPercent: "0.00"}
+	for _, status := range statuses {
+		switch status {
+		case "":
+			continue
+		case StatusMev:
+			ratio.Mev++
+		case StatusVanilla:
+			ratio.Vanilla++
+		default:
+			ratio.Other++
+		}
+		ratio.BlockCount++
+	}
+	if ratio.BlockCount > 0 {
+		ratio.MevPercent = big.NewRat(int64(ratio.Mev*100), int64(ratio.BlockCount)).FloatString(2)
+		ratio.VanillaPercent = big.NewRat(int64(ratio.Vanilla*100), int64(ratio.BlockCount)).FloatString(2)
+	}
+	return ratio, nil
+}
+
+// SlotReward is the reward of one slot of a batch. Error is set instead of the reward when the
+// slot could not be computed.
+// UpstreamFailedMessage is the error of a slot whose reward could not be computed for an upstream
+// failure, unlike missing or future slots a retry may succeed.
+const UpstreamFailedMessage = "Upstream request failed"
+
+type SlotReward struct {
+	Slot   string  `json:"slot"`
+	Reward *string `json:"reward,omitempty"`
+	Status *string `json:"status,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// slotReward computes the reward entry of one slot of a batch.
+func (c *Web3Client) slotReward(ctx context.Context, slotId string) SlotReward {
+	entry := SlotReward{Slot: slotId}
+	reward, status, err := c.GetBlockRewardAndStatusBySlot(ctx, slotId)
+	switch {
+	case errors.Is(err, ErrSlotMissing) || errors.Is(err, ErrFutureSlot) || errors.Is(err, ErrInvalidSlot):
+		entry.Error = err.Error()
+	case err != nil:
+		entry.Error = UpstreamFailedMessage
+	default:
+		entry.Reward, entry.Status = reward, status
+	}
+	return entry
+}
+
+// pendingReward is the reward of a unique slot of a batch, shared by all its occurrences.
+type pendingReward struct {
+	done     chan struct{}
+	reward   SlotReward
+	released bool
+}
+
+// StreamBlockRewards computes the rewards of the slots, batchConcurrency slots at a time, and
+// passes them to emit in the order of the slots. A slot only starts once fewer than
+// batchConcurrency computed rewards wait to be emitted, so at most that many are held in memory.
+// A slot listed more than once is computed once and emitted at each of its positions. A failing
+// slot is reported in its entry and does not stop the stream, an error of emit does.
+func (c *Web3Client) StreamBlockRewards(ctx context.Context, slotIds []string, emit func(SlotReward) error) error {
+	pending := make([]*pendingReward, len(slotIds))
+	var unique []string
+	bySlot := make(map[string]*pendingReward)
+	for i, slotId := range slotIds {
+		if _, ok := bySlot[slotId]; !ok {
+			bySlot[slotId] = &pendingReward{done: make(chan struct{})}
+			unique = append(unique, slotId)
+		}
+		pending[i] = bySlot[slotId]
+	}
+	window := make(chan struct{}, c.batchConcurrency)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for _, slotId := range unique {
+			select {
+			case window <- struct{}{}:
+			case <-stop:
+				return
+			}
+			go func() {
+				result := bySlot[slotId]
+				result.reward = c.sharedSlotReward(ctx, slotId)
+				close(result.done)
+			}()
+		}
+	}()
+	for _, result := range pending {
+		<-result.done
+		// unique slots are started in the order of their first occurrence, so releasing the window
+		// there keeps the next slot to emit startable
+		if !result.released {
+			result.released = true
+			<-window
+		}
+		if err := emit(result.reward); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharedSlotReward computes the reward of the slot like slotReward, sharing the computation with
+// concurrent batches asking for the same slot. The shared computation is detached from the
+// cancellation of the batch that started it and bounded by SharedComputationTimeout instead, so
+// a cancelled batch does not fail the batches waiting on it. Each batch stops waiting when its
+// own context is done.
+func (c *Web3Client) sharedSlotReward(ctx context.Context, slotId string) SlotReward {
+	result := c.rewardFlights.DoChan(slotId, func() (interface{}, error) {
+		sharedCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), SharedComputationTimeout)
+		defer cancel()
+		return c.slotReward(sharedCtx, slotId), nil
+	})
+	select {
+	case shared := <-result:
+		return shared.Val.(SlotReward)
+	case <-ctx.Done():
+		return SlotReward{Slot: slotId, Error: UpstreamFailedMessage}
+	}
+}
+
+// GetBlockRewards computes the rewards of the slots like StreamBlockRewards and returns them
+// together.
+func (c *Web3Client) GetBlockRewards(ctx context.Context, slotIds []string) []SlotReward {
+	rewards := make([]SlotReward, 0, len(slotIds))
+	_ = c.StreamBlockRewards(ctx, slotIds, func(reward SlotReward) error {
+		rewards = append(rewards, reward)
+		return nil
+	})
+	return rewards
+}
+
+// EpochSlotReward is the reward of one slot of an epoch in wei.
+type EpochSlotReward struct {
+	Slot   string   `json:"slot"`
+	Reward *big.Int `json:"reward"`
+	Status string   `json:"status"`
+}
+
+// EpochRewards are the rewards of the slots of an epoch that had a block, in slot order, and
+// their sum in wei.
+type EpochRewards struct {
+	Epoch   string            `json:"epoch"`
+	Rewards []EpochSlotReward `json:"rewards"`
+	Total   *big.Int          `json:"total"`
+}
+
+// GetEpochBlockRewards computes the rewards of all slots of the epoch like a slot range. Slots
+// without a block, including the slots before the merge, are skipped. The epoch must have ended
+// at or before the head.
+func (c *Web3Client) GetEpochBlockRewards(ctx context.Context, epoch string) (*EpochRewards, error) {
+	epochAsInt, err := strconv.ParseUint(epoch, 10, 64)
+	if err != nil || epochAsInt > math.MaxUint64/c.spec.SlotsPerEpoch-1 {
+		return nil, &InvalidSlotError{msg: "Epoch is invalid"}
+	}
+	from := epochAsInt * c.spec.SlotsPerEpoch
+	to := from + c.spec.SlotsPerEpoch - 1
+	if _, err := c.validateRewardSlot(strconv.FormatUint(to, 10)); err != nil {
+		return nil, err
+	}
+	rewards := make([]*EpochSlotReward, c.spec.SlotsPerEpoch)
+	err = c.forEachSlot(ctx, from, to, func(ctx context.Context, offset uint64, slotId string) error {
+		reward, status, err := c.GetBlockRewardWei(ctx, slotId)
+		if err != nil {
+			return err
+		}
+		rewards[offset] = &EpochSlotReward{Slot: slotId, Reward: reward, Status: status}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &EpochRewards{Epoch: epoch, Rewards: []EpochSlotReward{}, Total: new(big.Int)}
+	for _, reward := range rewards {
+		if reward == nil {
+			continue
+		}
+		result.Rewards = append(result.Rewards, *reward)
+		result.Total.Add(result.Total, reward.Reward)
+	}
+	return result, nil
+}
@@ -0,0 +1,543 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setupRangeServer serves the test data of the key for every slot except the missed ones, for
+// which the beacon node reports a missing block.
+func setupRangeServer(t *testing.T, testKey string, missed ...string) *httptest.Server {
+	upstream := setupServer(testKey)
+	t.Cleanup(upstream.Close)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		for _, slot := range missed {
+			if req.URL.Path == "/eth/v2/beacon/blocks/"+slot {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	return server
+}
+
+func TestGetBurntTotalSkipsMissedSlots(t *testing.T) {
+	server := setupRangeServer(t, "vanilla", "4700014")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+	total, err := client.GetBurntTotal(context.Background(), "4700013", "4700015")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each block burns a base fee of 1 for 2 gas
+	if total.Total.Int64() != 4 || total.SlotCount != 2 {
+		t.Errorf("Expected 4 wei burnt over 2 slots, but got %s over %d", total.Total, total.SlotCount)
+	}
+}
+
+func TestAverageRewardHandlerIsExact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupRangeServer(t, "rewardOverflow", "4700014")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/rewards/average", src.GetAverageRewardHandler(src.NewWeb3Client(parsedUrl, 1000)))
+
+	// both blocks pay 2^64-2 wei, a float64 average would round it to 2^64
+	recorder := performRequest(router, "/rewards/average?from=4700013&to=4700015&unit=wei")
+	expected := `{"from":"4700013","to":"4700015","total":36893488147419103228,"slotCount":2,"average":"18446744073709551614","unit":"wei"}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+	recorder = performRequest(router, "/rewards/average?from=4700013&to=4700015&unit=eth&precision=4")
+	if !strings.Contains(recorder.Body.String(), `"average":"18.4467"`) {
+		t.Errorf("Expected an average of 18.4467 eth, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	for _, query := range []string{"unit=finney", "precision=-1", "precision=37"} {
+		if recorder := performRequest(router, "/rewards/average?from=4700013&to=4700015&"+query); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, but got %d", query, recorder.Code)
+		}
+	}
+}
+
+func TestGetBurntTotalRejectsInvalidRanges(t *testing.T) {
+	parsedUrl, _ := url.Parse("http://127.0.0.1:1")
+	client := src.NewWeb3Client(parsedUrl, 1000)
+	for _, slots := range [][2]string{{"4700015", "4700013"}, {"4700013", "4700113"}, {"1", "4700013"}} {
+		_, err := client.GetBurntTotal(context.Background(), slots[0], slots[1])
+		var invalidSlotError *src.InvalidSlotError
+		if !errors.As(err, &invalidSlotError) && !errors.Is(err, src.ErrPreMergeSlot) {
+			t.Errorf("Expected range %s-%s to be rejected, but got %v", slots[0], slots[1], err)
+		}
+	}
+}
+
+// inFlightCounter records the highest number of concurrent requests it saw.
+type inFlightCounter struct {
+	mu      sync.Mutex
+	current int
+	peak    int
+}
+
+func (c *inFlightCounter) track(next func()) {
+	c.mu.Lock()
+	c.current++
+	c.peak = max(c.peak, c.current)
+	c.mu.Unlock()
+	next()
+	c.mu.Lock()
+	c.current--
+	c.mu.Unlock()
+}
+
+func TestConcurrencyCapsAreRespected(t *testing.T) {
+	upstream := setupServer("manyTransactions")
+	defer upstream.Close()
+	blocks, receipts := &inFlightCounter{}, &inFlightCounter{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		serve := func() {
+			time.Sleep(10 * time.Millisecond)
+			upstream.Config.Handler.ServeHTTP(rw, req)
+		}
+		switch {
+		case strings.HasPrefix(req.URL.Path, "/eth/v2/beacon/blocks/"):
+			blocks.track(serve)
+		case bytes.Contains(body, []byte("eth_getTransactionReceipt")):
+			receipts.track(serve)
+		default:
+			serve()
+		}
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100000, src.WithRateBurst(1000),
+		src.WithBatchConcurrency(2), src.WithReceiptConcurrency(2))
+
+	if _, err := client.GetBurntTotal(context.Background(), "4700013", "4700015"); err != nil {
+		t.Fatal(err)
+	}
+	if blocks.peak != 2 {
+		t.Errorf("Expected at most 2 slots in flight, but got %d", blocks.peak)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetBlockRewardDetails(context.Background(), "4700013"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if receipts.peak != 2 {
+		t.Errorf("Expected at most 2 receipts in flight across computations, but got %d", receipts.peak)
+	}
+}
+
+func TestBlockRewardsHandlerStreamsInSlotOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupRangeServer(t, "vanilla", "4700014")
+	defer upstream.Close()
+	// the first slot is the slowest, so the later slots are computed before it
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/eth/v2/beacon/blocks/4700013" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.POST("/blockrewards", src.GetBlockRewardsHandler(src.NewWeb3Client(parsedUrl, 1000, src.WithRateBurst(100))))
+
+	recorder := httptest.NewRecorder()
+	body := `{"slots": ["4700013", "4700014", "4700015", "4700016", "4700012"]}`
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/blockrewards", strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", recorder.Code)
+	}
+	var response struct {
+		Rewards []src.SlotReward `json:"rewards"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected valid JSON, but got %v: %s", err, recorder.Body.String())
+	}
+	expected := []struct{ slot, error string }{
+		{"4700013", ""}, {"4700014", "Slot is not found"}, {"4700015", ""}, {"4700016", "Slot is in the future"}, {"4700012", "Slot is before the merge slot 4700013"},
+	}
+	if len(response.Rewards) != len(expected) {
+		t.Fatalf("Expected %d rewards, but got %d", len(expected), len(response.Rewards))
+	}
+	for i, reward := range response.Rewards {
+		if reward.Slot != expected[i].slot || reward.Error != expected[i].error {
+			t.Errorf("Expected entry %d to be slot %s with error %q, but got %+v", i, expected[i].slot, expected[i].error, reward)
+		}
+	}
+}
+
+func TestBlockRewardsHandlerComputesDuplicateSlotsOnce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupRangeServer(t, "vanilla")
+	defer upstream.Close()
+	var mu sync.Mutex
+	blockRequests := make(map[string]int)
+	// slow blocks keep the duplicates in flight together, so the cache can not dedupe them
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if slot, ok := strings.CutPrefix(req.URL.Path, "/eth/v2/beacon/blocks/"); ok {
+			mu.Lock()
+			blockRequests[slot]++
+			mu.Unlock()
+			time.Sleep(50 * time.Millisecond)
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.POST("/blockrewards", src.GetBlockRewardsHandler(src.NewWeb3Client(parsedUrl, 1000, src.WithRateBurst(100))))
+
+	recorder := httptest.NewRecorder()
+	body := `{"slots": ["4700013", "4700015", "4700013", "4700013", "4700015"]}`
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/blockrewards", strings.NewReader(body)))
+	var response struct {
+		Rewards []src.SlotReward `json:"rewards"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected valid JSON, but got %v: %s", err, recorder.Body.String())
+	}
+	expected := []string{"4700013", "4700015", "4700013", "4700013", "4700015"}
+	if len(response.Rewards) != len(expected) {
+		t.Fatalf("Expected %d rewards, but got %d", len(expected), len(response.Rewards))
+	}
+	for i, reward := range response.Rewards {
+		if reward.Slot != expected[i] || reward.Error != "" {
+			t.Errorf("Expected entry %d to be the reward of slot %s, but got %+v", i, expected[i], reward)
+		}
+	}
+	if blockRequests["4700013"] != 1 || blockRequests["4700015"] != 1 {
+		t.Errorf("Expected one block request per unique slot, but got %v", blockRequests)
+	}
+}
+
+func TestBlockRewardsHandlerStreamsNDJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupRangeServer(t, "vanilla", "4700014")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.POST("/blockrewards", src.GetBlockRewardsHandler(src.NewWeb3Client(parsedUrl, 1000, src.WithRateBurst(100))))
+
+	req := httptest.NewRequest(http.MethodPost, "/blockrewards", strings.NewReader(`{"slots": ["4700013", "4700014", "4700015"]}`))
+	req.Header.Set("Accept", "application/x-ndjson")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Expected an ndjson response, but got %d %s", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if !strings.HasSuffix(recorder.Body.String(), "\n") {
+		t.Errorf("Expected every line to end with a newline, but got %q", recorder.Body.String())
+	}
+	lines := strings.Split(strings.TrimSuffix(recorder.Body.String(), "\n"), "\n")
+	expected := []string{"4700013", "4700014", "4700015"}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, but got %q", len(expected), recorder.Body.String())
+	}
+	for i, line := range lines {
+		var reward src.SlotReward
+		if err := json.Unmarshal([]byte(line), &reward); err != nil {
+			t.Fatalf("Expected line %d to be a JSON object, but got %v: %s", i, err, line)
+		}
+		if reward.Slot != expected[i] {
+			t.Errorf("Expected line %d to be slot %s, but got %s", i, expected[i], reward.Slot)
+		}
+	}
+}
+
+func TestEpochBlockRewardsHandlerSkipsMissedSlots(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupRangeServer(t, "vanilla", "4700020")
+	defer upstream.Close()
+	// the head is past the end of epoch 146875, slots 4700000 to 4700031
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/eth/v1/beacon/headers" {
+			_, _ = rw.Write([]byte(`{"data":[{"header":{"message":{"slot":"4700040"}}}]}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	src.RegisterRoutes(router, src.NewWeb3Client(parsedUrl, 100000, src.WithRateBurst(1000)), src.DefaultRouteTimeouts(), false)
+
+	recorder := performRequest(router, "/epoch/146875/blockrewards")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var rewards src.EpochRewards
+	if err := json.Unmarshal(recorder.Body.Bytes(), &rewards); err != nil {
+		t.Fatal(err)
+	}
+	// the 13 slots before the merge and the missed slot have no block, the other 18 pay 1 wei
+	if len(rewards.Rewards) != 18 || rewards.Total.Int64() != 18 {
+		t.Fatalf("Expected 18 rewards totalling 18 wei, but got %d totalling %s", len(rewards.Rewards), rewards.Total)
+	}
+	if rewards.Rewards[0].Slot != "4700013" || rewards.Rewards[7].Slot != "4700021" {
+		t.Errorf("Expected rewards in slot order without the missed slot, but got %+v", rewards.Rewards)
+	}
+
+	for path, statusCode := range map[string]int{
+		"/epoch/146876/blockrewards":  http.StatusBadRequest,
+		"/epoch/146874/blockrewards":  http.StatusNotFound,
+		"/epoch/epoch/blockrewards":   http.StatusBadRequest,
+		"/epoch/-146875/blockrewards": http.StatusBadRequest,
+	} {
+		if recorder := performRequest(router, path); recorder.Code != statusCode {
+			t.Errorf("Expected %s to return %d, but got %d", path, statusCode, recorder.Code)
+		}
+	}
+}
+
+func TestCompareBlockRewardsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupRangeServer(t, "vanilla", "4700014")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/blockreward/compare", src.GetCompareBlockRewardsHandler(src.NewWeb3Client(parsedUrl, 1000)))
+
+	recorder := performRequest(router, "/blockreward/compare?a=4700013&b=4700015")
+	expected := `{"a":{"slot":"4700013","reward":"0.000000001","status":"vanilla"},` +
+		`"b":{"slot":"4700015","reward":"0.000000001","status":"vanilla"},"difference":"0.000000000"}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+	recorder = performRequest(router, "/blockreward/compare?a=4700013&b=4700014")
+	expected = `{"a":{"slot":"4700013","reward":"0.000000001","status":"vanilla"},` +
+		`"b":{"slot":"4700014","error":"Slot is not found"},"difference":null}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+	if recorder := performRequest(router, "/blockreward/compare?a=4700013&b=abc"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid slot, but got %d", recorder.Code)
+	}
+}
+
+func TestBlockFeesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupRangeServer(t, "vanilla", "4700014")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/slot/:slotId/fees", src.GetBlockFeesHandler(src.NewWeb3Client(parsedUrl, 1000)))
+
+	// the block has a base fee of 1 and used 2 of its 17 gas
+	recorder := performRequest(router, "/slot/4700013/fees")
+	expected := `{"baseFee":1,"gasUsed":2,"gasLimit":17,"burnt":2}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+	if recorder := performRequest(router, "/slot/4700014/fees"); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missed slot, but got %d", recorder.Code)
+	}
+}
+
+func TestLogsSummaryHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupRangeServer(t, "vanilla", "4700014")
+	var receiptRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		if bytes.Contains(body, []byte("eth_getTransactionReceipt")) {
+			receiptRequests.Add(1)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/slot/:slotId/logs/summary", src.GetLogsSummaryHandler(src.NewWeb3Client(parsedUrl, 1000)))
+
+	recorder := performRequest(router, "/slot/4700013/logs/summary")
+	var summary src.LogsSummary
+	if err := json.Unmarshal(recorder.Body.Bytes(), &summary); recorder.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected a logs summary, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	// the mocked bloom has 12 bits set in its 256 bytes
+	if len(summary.LogsBloom) != 2+2*256 || !strings.HasPrefix(summary.LogsBloom, "0x") || summary.BloomBitsSet != 12 {
+		t.Errorf("Expected a 256 byte bloom with 12 bits set, but got %s with %d bits", summary.LogsBloom, summary.BloomBitsSet)
+	}
+	if receiptRequests.Load() != 0 {
+		t.Errorf("Expected the summary to come from the header, but got %d receipt requests", receiptRequests.Load())
+	}
+	if recorder := performRequest(router, "/slot/4700014/logs/summary"); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missed slot, but got %d", recorder.Code)
+	}
+}
+
+func TestSharedRewardSurvivesCancelledLeader(t *testing.T) {
+	upstream := setupRangeServer(t, "vanilla")
+	defer upstream.Close()
+	var blockRequests atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, src.BlockDetailPath) {
+			if blockRequests.Add(1) == 1 {
+				close(started)
+			}
+			<-release
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithRateBurst(100))
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leader := make(chan []src.SlotReward)
+	go func() {
+		leader <- client.GetBlockRewards(leaderCtx, []string{"4700013"})
+	}()
+	<-started
+	joiner := make(chan []src.SlotReward)
+	go func() {
+		joiner <- client.GetBlockRewards(context.Background(), []string{"4700013"})
+	}()
+	// let the joiner wait on the computation of the leader before the leader gives up
+	time.Sleep(50 * time.Millisecond)
+	cancelLeader()
+	if rewards := <-leader; rewards[0].Error == "" {
+		t.Errorf("Expected the cancelled leader to get an error, but got %+v", rewards[0])
+	}
+	close(release)
+	rewards := <-joiner
+	if rewards[0].Error != "" || rewards[0].Reward == nil || *rewards[0].Reward != "0.000000001" {
+		t.Errorf("Expected the joiner to get the reward, but got %+v", rewards[0])
+	}
+	if blockRequests.Load() != 1 {
+		t.Errorf("Expected the block to be fetched once, but got %d requests", blockRequests.Load())
+	}
+}
+
+func TestFeeSplitHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupServer("mev")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/slot/:slotId/feesplit", src.GetFeeSplitHandler(src.NewWeb3Client(parsedUrl, 1000)))
+
+	// the block burns a base fee of 1 for 2 gas and its transaction tips 3 for 1 gas
+	recorder := performRequest(router, "/slot/4700013/feesplit")
+	var split src.FeeSplit
+	if err := json.Unmarshal(recorder.Body.Bytes(), &split); err != nil {
+		t.Fatalf("Expected valid JSON, but got %v: %d %s", err, recorder.Code, recorder.Body.String())
+	}
+	if split.Burnt.Int64() != 2 || split.Tips.Int64() != 3 || split.BurntPercent != "40.00" || split.TipsPercent != "60.00" {
+		t.Errorf("Expected 2 wei burnt and 3 wei tips split 40/60, but got %s", recorder.Body.String())
+	}
+	burntPercent, _ := strconv.ParseFloat(split.BurntPercent, 64)
+	tipsPercent, _ := strconv.ParseFloat(split.TipsPercent, 64)
+	if burntPercent+tipsPercent != 100 {
+		t.Errorf("Expected the percentages to sum to 100, but got %v", burntPercent+tipsPercent)
+	}
+}
+
+func TestMevRatioHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupRangeServer(t, "vanilla", "4700014")
+	// the head is past the range, so all of its slots can be computed
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/eth/v1/beacon/headers" {
+			_, _ = rw.Write([]byte(`{"data":[{"header":{"message":{"slot":"4700020"}}}]}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	// the mev slots are served from the cache, the other slots are computed from the vanilla block
+	cache := newFakeCache()
+	for _, slot := range []string{"4700013", "4700016", "4700017"} {
+		cache.values["reward:"+slot] = []byte(`{"reward":2,"status":"mev","blockHash":"` + common.Hash{}.Hex() + `","final":true}`)
+	}
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/stats/mevratio", src.GetMevRatioHandler(src.NewWeb3Client(parsedUrl, 1000, src.WithCache(cache))))
+
+	recorder := performRequest(router, "/stats/mevratio?from=4700013&to=4700018")
+	expected := `{"from":"4700013","to":"4700018","blockCount":5,"mev":3,"vanilla":2,"other":0,"mevPercent":"60.00","vanillaPercent":"40.00"}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+	if recorder := performRequest(router, "/stats/mevratio?from=4700018&to=4700013"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an inverted range, but got %d", recorder.Code)
+	}
+}
+
+func TestBlockRewardHandlerBaseline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupRangeServer(t, "vanilla")
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/eth/v1/beacon/headers" {
+			_, _ = rw.Write([]byte(`{"data":[{"header":{"message":{"slot":"4700020"}}}]}`))
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	// slots 4700013 to 4700017 earn 1 to 5 wei, slot 4700018 earns 4 wei
+	cache := newFakeCache()
+	for i, reward := range []int{1, 2, 3, 4, 5, 4} {
+		slot := strconv.Itoa(4700013 + i)
+		cache.values["reward:"+slot] = []byte(`{"reward":` + strconv.Itoa(reward) + `,"status":"vanilla","blockHash":"` + common.Hash{}.Hex() + `","final":true}`)
+	}
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(src.NewWeb3Client(parsedUrl, 1000, src.WithCache(cache))))
+
+	// 3 rewards are below 4 and 1 equals it, (3 + 0.5) / 5 puts it at the 70th percentile
+	recorder := performRequest(router, "/blockreward/4700018?baseline=median&window=5")
+	expected := `{"baseline":{"window":5,"blockCount":5,"median":"0.000000003","deltaFromMedian":"0.000000001","percentile":"70.00"},` +
+		`"reward":"0.000000004","status":"vanilla"}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+	// the reward and the rewards of its window were all served from the cache
+	if recorder.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("Expected X-Cache HIT, but got %q", recorder.Header().Get("X-Cache"))
+	}
+	// the window of slot 4700014 is cut at the merge slot, one block is not enough history
+	recorder = performRequest(router, "/blockreward/4700014?baseline=median&window=5")
+	expected = `{"baseline":{"window":5,"blockCount":1,"median":null,"deltaFromMedian":null,"percentile":null},` +
+		`"reward":"0.000000002","status":"vanilla"}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+	for _, query := range []string{"baseline=mean", "baseline=median&window=0", "baseline=median&window=101", "baseline=median&window=x"} {
+		if recorder := performRequest(router, "/blockreward/4700018?"+query); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, but got %d", query, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/time/rate"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Kinds of upstream requests that can be given their own cost in the rate limiter.
+const (
+	RequestKindRPC            = "rpc"
+	RequestKindHeaders        = "headers"
+	RequestKindBlocks         = "blocks"
+	RequestKindSyncCommittees = "sync_committees"
+	RequestKindValidators     = "validators"
+	RequestKindOther          = "other"
+)
+
+// RequestCosts maps request kinds to the number of rate limiter tokens one request of the kind
+// consumes. Kinds that are not listed cost one token.
+type RequestCosts map[string]int
+
+// DefaultRequestCosts charges validator lookups, which list up to a batch of ids, more than the
+// other requests.
+func DefaultRequestCosts() RequestCosts {
+	return RequestCosts{RequestKindValidators: 4}
+}
+
+// ParseRequestCosts parses costs in the form "validators=4,rpc=1".
+func ParseRequestCosts(value string) (RequestCosts, error) {
+	costs := RequestCosts{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kind, costStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("request cost %q is not in the form kind=cost", pair)
+		}
+		cost, err := strconv.Atoi(strings.TrimSpace(costStr))
+		if err != nil || cost < 1 {
+			return nil, fmt.Errorf("request cost of %s must be an integer of at least 1", kind)
+		}
+		costs[strings.TrimSpace(kind)] = cost
+	}
+	return costs, nil
+}
+
+func (r RequestCosts) cost(req *http.Request) int {
+	return r.kindCost(requestKind(req))
+}
+
+func (r RequestCosts) kindCost(kind string) int {
+	if cost, ok := r[kind]; ok {
+		return cost
+	}
+	return 1
+}
+
+// requestKind classifies the upstream request by its method and beacon API path.
+func requestKind(req *http.Request) string {
+	path := req.URL.Path
+	switch {
+	case req.Method == http.MethodPost:
+		return RequestKindRPC
+	case strings.HasSuffix(path, "/validators"):
+		return RequestKindValidators
+	case strings.HasSuffix(path, "/sync_committees"):
+		return RequestKindSyncCommittees
+	case strings.HasPrefix(path, BlockDetailPath):
+		return RequestKindBlocks
+	case strings.HasSuffix(path, "/headers"):
+		return RequestKindHeaders
+	}
+	return RequestKindOther
+}
+
+// limiterWaitError is returned for a request that gave up waiting for its tokens, so it never
+// reached the upstream.
+type limiterWaitError struct {
+	err error
+}
+
+func (e *limiterWaitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *limiterWaitError) Unwrap() error {
+	return e.err
+}
+
+// waitTokens takes n tokens from the limiter. Costs above the burst are taken in chunks of the
+// burst, so heavy requests still wait for their full cost instead of failing.
+func waitTokens(ctx context.Context, limiter *rate.Limiter, n int) error {
+	for n > 0 {
+		chunk := min(n, limiter.Burst())
+		if err := limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+type tokenReservationKey struct{}
+
+// tokenReservation holds tokens taken from the limiter in advance for the requests of one context.
+type tokenReservation struct {
+	mu     sync.Mutex
+	tokens int
+}
+
+// take uses up to n of the reserved tokens and returns how many were used.
+func (r *tokenReservation) take(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	taken := min(n, r.tokens)
+	r.tokens -= taken
+	return taken
+}
+
+// reserveTokens takes n tokens from the limiter at once, at most the burst, and returns a context
+// whose requests use them before waiting on the limiter. Sub-calls of one request sent in
+// parallel then do not queue on the limiter one after the other, and requests of other callers
+// can not take the tokens in between.
+func (c *Web3Client) reserveTokens(ctx context.Context, n int) (context.Context, error) {
+	n = min(n, c.limiter.Burst())
+	if err := waitTokens(ctx, c.limiter, n); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, tokenReservationKey{}, &tokenReservation{tokens: n}), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
+)
+
+const MaxRawBlockSize = 16 << 20 // mainnet beacon blocks stay far below this
+
+// RawBlock is the unparsed beacon block response of a slot. Body must be closed by the caller
+// and fails reading once more than MaxRawBlockSize bytes were read.
+type RawBlock struct {
+	StatusCode    int
+	ContentType   string
+	ContentLength int64
+	Body          io.ReadCloser
+}
+
+// GetRawBlock opens the beacon block response of the slot without decoding it, so it can be
+// streamed to the caller. Missing and future slots are reported like in the other lookups, any
+// other upstream status is kept.
+func (c *Web3Client) GetRawBlock(ctx context.Context, slotId string) (block *RawBlock, err error) {
+	if _, err := c.parseSlotId(slotId); err != nil {
+		return nil, err
+	}
+	ctx, span := tracer().Start(ctx, "raw beacon block", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		endSpan(span, err)
+	}()
+	req, err := http.NewRequestWithContext(withUpstreamRequestName(ctx, "receive raw beacon block"), "GET", c.BaseUrl.String()+BlockDetailPath+slotId, nil)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("upstream.endpoint", req.URL.Path))
+	c.setBeaconHeaders(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Info().Err(err).Str("slotId", slotId).Msg("can not send raw block request")
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		_ = resp.Body.Close()
+		return nil, &FutureSlotError{msg: "Slot is in the future"}
+	case resp.StatusCode == http.StatusBadRequest:
+		_ = resp.Body.Close()
+		return nil, &SlotMissingError{msg: "Slot is not found"}
+	case resp.ContentLength > MaxRawBlockSize:
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("raw block of %d bytes exceeds the limit of %d bytes", resp.ContentLength, MaxRawBlockSize)
+	}
+	return &RawBlock{
+		StatusCode:    resp.StatusCode,
+		ContentType:   resp.Header.Get("Content-Type"),
+		ContentLength: resp.ContentLength,
+		Body:          http.MaxBytesReader(nil, resp.Body, MaxRawBlockSize),
+	}, nil
+}
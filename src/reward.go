@@ -0,0 +1,670 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Block statuses. Vanilla and mev are always reported, the others only when extended statuses
+// are enabled with WithExtendedStatuses.
+const (
+	StatusVanilla     = "vanilla"
+	StatusMev         = "mev"
+	StatusEmpty       = "empty"
+	StatusLowActivity = "low-activity"
+	StatusUnknown     = "unknown"
+)
+
+// DefaultReceiptConcurrency is the number of transaction receipts the client fetches at the same
+// time, across all reward computations.
+const DefaultReceiptConcurrency = 8
+
+// ReorgSafeEpochs is the depth in epochs below the head after which a slot is treated as final
+// and its cached reward is no longer checked for reorgs. On a healthy chain slots finalize after
+// two epochs.
+const ReorgSafeEpochs = 2
+
+// FutureEpochMargin is how many epochs past the clock epoch a slot may be before it is rejected
+// as future without asking the beacon node for the head.
+const FutureEpochMargin = 2
+
+// MethodNotFoundCode is the JSON-RPC error code of calls to methods the node does not provide.
+const MethodNotFoundCode = -32601
+
+// DefaultMaxReceiptCalls is the number of receipts fetched for one block. Transactions past it are
+// estimated, so a block with thousands of transactions can not monopolize the upstream.
+const DefaultMaxReceiptCalls = 1000
+
+// LowActivityGasPercent is the share of the gas limit below which a block counts as low activity.
+const LowActivityGasPercent = 10
+
+type TransactionReward struct {
+	Hash             common.Hash `json:"hash"`
+	GasPrice         *big.Int    `json:"gasPrice"`
+	GasUsed          uint64      `json:"gasUsed"`
+	Fee              *big.Int    `json:"fee"`
+	Tip              *big.Int    `json:"tip"`
+	ReceiptAvailable bool        `json:"receiptAvailable"`
+}
+
+// RewardTimings holds how long each phase of the reward computation took.
+type RewardTimings struct {
+	HeadLookup time.Duration `json:"headLookupNs"`
+	BlockFetch time.Duration `json:"blockFetchNs"`
+	Receipts   time.Duration `json:"receiptsNs"`
+	Total      time.Duration `json:"totalNs"`
+}
+
+type rewardTimingsKey struct{}
+
+// withRewardTimings returns a context in which the reward computation records its phase timings
+// into the returned RewardTimings. It stays zero when the reward is served from the cache.
+func withRewardTimings(ctx context.Context) (context.Context, *RewardTimings) {
+	timings := &RewardTimings{}
+	return context.WithValue(ctx, rewardTimingsKey{}, timings), timings
+}
+
+func recordRewardTimings(ctx context.Context, timings RewardTimings) {
+	if target, ok := ctx.Value(rewardTimingsKey{}).(*RewardTimings); ok {
+		*target = timings
+	}
+}
+
+// serverTiming formats the timings as a Server-Timing header value with durations in
+// milliseconds. A reward that was not computed is reported as a cache hit.
+func (t RewardTimings) serverTiming() string {
+	if t.Total == 0 {
+		return `cache;desc="hit"`
+	}
+	metrics := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"head", t.HeadLookup},
+		{"block", t.BlockFetch},
+		{"receipts", t.Receipts},
+		{"total", t.Total},
+	}
+	parts := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		ms := strconv.FormatFloat(float64(metric.duration)/float64(time.Millisecond), 'f', 3, 64)
+		parts = append(parts, metric.name+";dur="+ms)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// BlockRewardDetails is the full decomposition of the execution layer reward of a slot.
+// All amounts are in wei. Reward is the sum of the fees minus the burnt fees. IsZeroReward is set
+// when the fees only covered the burnt fees, the reward of 0 is then computed and not a missing
+// value, slots without a reward answer with an error instead. ConsensusReward is
+// the proposer reward reported by the beacon node and EstimatedTotal the estimate of the total
+// proposer reward, both are left out when the beacon node can not report the consensus reward.
+// FinalityStatus tells how likely the block is to still be orphaned. FeeRecipientLabel is the
+// configured label of the fee recipient, nil when it has none. Truncated is set when the block
+// had more transactions than receipts may be fetched for, the contributions of the remaining
+// transactions are then estimated. Approximate is set when the node does not provide receipt
+// queries or failed every receipt call, so the contributions of all transactions are estimated. CoinbaseTransfers is the
+// value sent to the fee recipient by the transactions of the block, such as direct searcher
+// payments. It is not part of Reward. ReceiptFallbacks counts the transactions whose contribution
+// was estimated without a receipt, ReceiptsComplete is set when there were none.
+type BlockRewardDetails struct {
+	Slot              string         `json:"slot"`
+	ProposerIndex     string         `json:"proposerIndex"`
+	BlockHash         common.Hash    `json:"blockHash"`
+	FeeRecipient      common.Address `json:"feeRecipient"`
+	FeeRecipientLabel *string        `json:"feeRecipientLabel"`
+	TransactionCount  int            `json:"transactionCount"`
+	Fees              *big.Int       `json:"fees"`
+	Burnt             *big.Int       `json:"burnt"`
+	Tips              *big.Int       `json:"tips"`
+	Reward            *big.Int       `json:"reward"`
+	IsZeroReward      bool           `json:"isZeroReward"`
+	Status            string         `json:"status"`
+	Depth             uint64         `json:"depth"`
+	Finalized         bool           `json:"finalized"`
+	FinalityStatus    string         `json:"finalityStatus"`
+	Truncated         bool           `json:"truncated,omitempty"`
+	Approximate       bool           `json:"approximate,omitempty"`
+	ReceiptsComplete  bool           `json:"receiptsComplete"`
+	ReceiptFallbacks  int            `json:"receiptFallbacks"`
+	ConsensusReward   *big.Int       `json:"consensusReward,omitempty"`
+	EstimatedTotal    *big.Int       `json:"estimatedTotal,omitempty"`
+	CoinbaseTransfers *big.Int       `json:"coinbaseTransfers"`
+	// ProposerSlashings and AttesterSlashings count the slashings included in the block. A block
+	// with slashings pays the whistleblower reward to its proposer, and clients can check the
+	// proposer index against slashed validators, which is not detected here.
+	ProposerSlashings int `json:"proposerSlashings"`
+	AttesterSlashings int `json:"attesterSlashings"`
+	// Ommers counts the uncles of the block. Blocks since the merge have none, the field is always
+	// reported, also as 0, so that its absence is not mistaken for missing data.
+	Ommers       int                 `json:"ommers"`
+	Transactions []TransactionReward `json:"transactions,omitempty"`
+	Timings      RewardTimings       `json:"timings"`
+	// Check compares the reward with the block rewards of the beacon node, see RewardCheck. It is
+	// left out when the node can not report them.
+	Check *RewardCheck `json:"check,omitempty"`
+}
+
+// coinbaseTransfers sums the value of the transactions of the block sent to its fee recipient.
+// Only execution layer transactions are scanned: withdrawals also credit the fee recipient when it
+// is a withdrawal address, but they are paid by the beacon chain and are no payment for the block,
+// so they are left out on purpose. Transfers made inside contract calls need traces and are not
+// seen either.
+func coinbaseTransfers(block *types.Block) *big.Int {
+	total := new(big.Int)
+	for _, tx := range block.Transactions() {
+		if tx.To() != nil && *tx.To() == block.Coinbase() {
+			total.Add(total, tx.Value())
+		}
+	}
+	return total
+}
+
+// fallbackPriorityFee returns the priority fee per gas paid by tx when its receipt is not available.
+// Legacy and access list transactions pay everything above the base fee, dynamic fee transactions
+// pay their priority fee capped by what is left of the max fee after the base fee.
+func fallbackPriorityFee(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	var priorityFee *big.Int
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		priorityFee = new(big.Int).Sub(tx.GasPrice(), baseFee)
+	default:
+		priorityFee = new(big.Int).Sub(tx.GasFeeCap(), baseFee)
+		if tx.GasTipCap().Cmp(priorityFee) == -1 {
+			priorityFee = new(big.Int).Set(tx.GasTipCap())
+		}
+	}
+	if priorityFee.Sign() < 0 {
+		return big.NewInt(0)
+	}
+	return priorityFee
+}
+
+// effectiveGasPrice returns the price per gas tx paid in a block with the base fee, for receipts
+// that do not report it, e.g. from nodes predating London. Legacy and access list transactions pay
+// their gas price, dynamic fee transactions the base fee plus their priority fee, capped by their
+// max fee.
+func effectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		return new(big.Int).Set(tx.GasPrice())
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if tx.GasFeeCap().Cmp(price) == -1 {
+		price.Set(tx.GasFeeCap())
+	}
+	return price
+}
+
+// extendedStatus refines a vanilla status. A block without transactions is empty, a block with
+// a missing receipt is unknown as its fees are estimated, and a block using less than
+// LowActivityGasPercent of its gas limit is low activity. A mev status is kept as is.
+func extendedStatus(status string, block *types.Block, receiptsMissing bool) string {
+	if status != StatusVanilla {
+		return status
+	}
+	switch {
+	case len(block.Transactions()) == 0:
+		return StatusEmpty
+	case receiptsMissing:
+		return StatusUnknown
+	case block.GasUsed()*100 < block.GasLimit()*LowActivityGasPercent:
+		return StatusLowActivity
+	}
+	return status
+}
+
+// validateRewardSlot parses the slot id and rejects slots before the merge slot. The merge slot
+// itself is the first slot with a reward.
+func (c *Web3Client) validateRewardSlot(slotId string) (*big.Int, error) {
+	slotIdAsInt, err := c.parseSlotId(slotId)
+	if err != nil {
+		return nil, err
+	}
+	if slotIdAsInt.IsUint64() && slotIdAsInt.Uint64() < c.mergeSlot {
+		return nil, &PreMergeSlotError{msg: fmt.Sprintf("Slot is before the merge slot %d", c.mergeSlot)}
+	}
+	return slotIdAsInt, nil
+}
+
+// blockByHash fetches the execution block inside its own span.
+func (c *Web3Client) blockByHash(ctx context.Context, blockHash common.Hash) (block *types.Block, err error) {
+	ctx, span := tracer().Start(ctx, "eth_getBlockByHash", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("block.hash", blockHash.Hex())))
+	defer func() {
+		endSpan(span, err)
+	}()
+	return c.w3Client.BlockByHash(withUpstreamRequestName(ctx, "eth_getBlockByHash"), blockHash)
+}
+
+// getCanonicalBlock returns the beacon block of the slot with its execution block. The execution
+// client does not know a block orphaned between the hash lookup and the block fetch, so for a slot
+// that is not final the hash is looked up again, bypassing the cached block, up to reorgRetries
+// times, ReorgRetryDelay apart.
+func (c *Web3Client) getCanonicalBlock(ctx context.Context, slotId string, final bool) (*beaconBlockDetailResponse, common.Hash, *types.Block, error) {
+	for attempt := 0; ; attempt++ {
+		lookup := c.getCachedBlockDetailWithPayload
+		if attempt > 0 {
+			lookup = c.getBlockDetailWithPayload
+		}
+		blockDetail, blockHash, err := lookup(ctx, slotId)
+		if err != nil {
+			return nil, common.Hash{}, nil, err
+		}
+		block, err := c.blockByHash(ctx, blockHash)
+		if err == nil {
+			if attempt > 0 {
+				c.cacheBlockDetail(ctx, slotId, blockDetail)
+			}
+			return blockDetail, blockHash, block, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) || final || attempt >= c.reorgRetries {
+			log.Info().Err(err).Msg("can not get block by hash")
+			return nil, common.Hash{}, nil, err
+		}
+		log.Info().Str("slotId", slotId).Str("blockHash", blockHash.Hex()).Int("attempt", attempt+1).
+			Msg("block not found, slot may have been reorged, retrying")
+		select {
+		case <-c.clock.After(ReorgRetryDelay):
+		case <-ctx.Done():
+			return nil, common.Hash{}, nil, ctx.Err()
+		}
+	}
+}
+
+// transactionReceipt fetches the receipt of the transaction inside its own span.
+func (c *Web3Client) transactionReceipt(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
+	ctx, span := tracer().Start(ctx, "eth_getTransactionReceipt", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("tx.hash", txHash.Hex())))
+	defer func() {
+		endSpan(span, err)
+	}()
+	return c.w3Client.TransactionReceipt(withUpstreamRequestName(ctx, "eth_getTransactionReceipt"), txHash)
+}
+
+// isReceiptsUnsupported reports whether the error of a receipt call means the node does not
+// provide receipt queries at all, like light nodes, rather than a failure of the single call.
+func isReceiptsUnsupported(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == MethodNotFoundCode
+}
+
+// receiptFee holds the fields of a receipt the reward needs. Receipts carry the logs of their
+// transaction, so only these fields are kept and the receipt itself is dropped as soon as it
+// arrives.
+type receiptFee struct {
+	gasUsed           uint64
+	effectiveGasPrice *big.Int
+}
+
+// fetchReceiptFees fetches the receipts of the transactions concurrently and keeps their
+// receiptFee. The receipt calls of all computations share the receipt slots of the client, so at
+// most receiptConcurrency receipts are in flight, and held in memory, at once regardless of the
+// size of the block. The fee of transactions[i] is stored at index i. Once the node reports that
+// it does not provide receipt queries, the remaining calls are skipped and unsupported is
+// returned, as it is when every receipt call failed, like on nodes that pruned the receipts of old
+// blocks. The fees are then left nil. Otherwise the fee of a receipt the node does not know is
+// left nil, and any other failed receipt call fails the whole lookup, so a single failure does not
+// make the reward approximate. When ctx is cancelled no further receipt is requested and the
+// error of ctx is returned once the calls in flight returned.
+func (c *Web3Client) fetchReceiptFees(ctx context.Context, transactions types.Transactions) (fees []*receiptFee, unsupported bool, err error) {
+	fees = make([]*receiptFee, len(transactions))
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var unsupportedFlag atomic.Bool
+	var failures atomic.Int32
+	var firstFailure, firstError error
+	var failureOnce, errorOnce sync.Once
+	var wg sync.WaitGroup
+	for i, tx := range transactions {
+		// a free receipt slot and a cancelled context can be ready together, select would pick
+		// either of them
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case c.receiptSlots <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+		wg.Add(1)
+		go func(i int, tx *types.Transaction) {
+			defer wg.Done()
+			defer func() { <-c.receiptSlots }()
+			if ctx.Err() != nil {
+				return
+			}
+			receipt, err := c.transactionReceipt(ctx, tx.Hash())
+			if err != nil && isReceiptsUnsupported(err) {
+				if !unsupportedFlag.Swap(true) {
+					log.Info().Err(err).Msg("node does not serve receipts, estimating the remaining transactions")
+				}
+				cancel()
+				return
+			}
+			if err != nil {
+				if parent.Err() == nil {
+					log.Info().Err(err).Str("txHash", tx.Hash().Hex()).Msg("can not get transaction receipt")
+					err = fmt.Errorf("can not get receipt of transaction %s: %w", tx.Hash().Hex(), err)
+					failures.Add(1)
+					failureOnce.Do(func() { firstFailure = err })
+					if !errors.Is(err, ethereum.NotFound) {
+						errorOnce.Do(func() { firstError = err })
+					}
+				}
+				return
+			}
+			fees[i] = &receiptFee{gasUsed: receipt.GasUsed, effectiveGasPrice: receipt.EffectiveGasPrice}
+		}(i, tx)
+	}
+	wg.Wait()
+	switch {
+	case parent.Err() != nil:
+		return fees, false, parent.Err()
+	case unsupportedFlag.Load():
+		return fees, true, nil
+	case failures.Load() > 0 && int(failures.Load()) == len(transactions):
+		log.Info().Err(firstFailure).Int("transactions", len(transactions)).Msg("node failed every receipt call, estimating the transactions")
+		return make([]*receiptFee, len(transactions)), true, nil
+	case firstError != nil:
+		return nil, false, firstError
+	}
+	return fees, false, nil
+}
+
+func (c *Web3Client) computeBlockRewardDetails(ctx context.Context, slotId string, slotIdAsInt *big.Int) (details *BlockRewardDetails, err error) {
+	ctx, span := tracer().Start(ctx, "compute block reward", trace.WithAttributes(attribute.String("slot.id", slotId)))
+	defer func() {
+		endSpan(span, err)
+	}()
+	start := time.Now()
+	details = &BlockRewardDetails{Slot: slotId}
+	currentSlotId, err := c.getCurrentSlotId(ctx)
+	if err != nil {
+		log.Info().Err(err).Msg("can not get current slot id")
+		return nil, err
+	}
+	if slotIdAsInt.Cmp(currentSlotId) == 1 {
+		return nil, &FutureSlotError{msg: "Slot is in the future"}
+	}
+	details.Timings.HeadLookup = time.Since(start)
+	details.Depth = new(big.Int).Sub(currentSlotId, slotIdAsInt).Uint64()
+
+	phaseStart := time.Now()
+	final := details.Depth >= ReorgSafeEpochs*c.spec.SlotsPerEpoch
+	blockDetail, blockHash, block, err := c.getCanonicalBlock(ctx, slotId, final)
+	if err != nil {
+		return nil, err
+	}
+	details.Timings.BlockFetch = time.Since(phaseStart)
+	details.ProposerIndex = blockDetail.Data.Message.ProposerIndex.String()
+	details.ProposerSlashings = len(blockDetail.Data.Message.Body.ProposerSlashings)
+	details.AttesterSlashings = len(blockDetail.Data.Message.Body.AttesterSlashings)
+	details.BlockHash = blockHash
+	details.FeeRecipient = block.Coinbase()
+	details.FeeRecipientLabel = c.feeRecipientLabels.label(block.Coinbase())
+	details.TransactionCount = len(block.Transactions())
+	details.CoinbaseTransfers = coinbaseTransfers(block)
+	details.Ommers = len(block.Uncles())
+
+	phaseStart = time.Now()
+	baseFee := block.BaseFee()
+	burntFees := new(big.Int).Mul(baseFee, big.NewInt(int64(block.GasUsed())))
+	txCosts := new(big.Int).SetInt64(0)
+	tips := new(big.Int).SetInt64(0)
+	transactions := block.Transactions()
+	// only the first maxReceiptCalls transactions get a receipt, builders order transactions by
+	// what they pay, so the estimated tail contributes the least
+	fetchCount := min(len(transactions), c.maxReceiptCalls)
+	details.Truncated = fetchCount < len(transactions)
+	receipts, receiptsUnsupported, err := c.fetchReceiptFees(ctx, transactions[:fetchCount])
+	if err != nil {
+		// the missing receipts were abandoned or failed on their own, estimating them would report
+		// a wrong reward
+		return nil, err
+	}
+	details.Approximate = receiptsUnsupported
+	receipts = append(receipts, make([]*receiptFee, len(transactions)-fetchCount)...)
+	status := StatusVanilla
+	// the contributions are folded in block order once all receipts are collected, so neither the
+	// sums nor the status depend on the order in which the receipts arrived
+	for i, tx := range transactions {
+		receipt := receipts[i]
+		var cost, gasPrice *big.Int
+		var gasUsed uint64
+		if receipt != nil {
+			gasUsed = receipt.gasUsed
+			gasPrice = receipt.effectiveGasPrice
+			if gasPrice == nil {
+				gasPrice = effectiveGasPrice(tx, baseFee)
+			}
+			cost = new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasUsed))
+		} else {
+			// without a receipt the gas used is unknown, so the gas limit is used as an upper bound
+			gasUsed = tx.Gas()
+			gasPrice = new(big.Int).Add(baseFee, fallbackPriorityFee(tx, baseFee))
+			cost = new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasUsed))
+		}
+		if c.mevThresholds.isMev(gasPrice, baseFee) {
+			status = StatusMev
+		}
+		if receipt == nil {
+			details.ReceiptFallbacks++
+		}
+		tip := new(big.Int).Mul(new(big.Int).Sub(gasPrice, baseFee), new(big.Int).SetUint64(gasUsed))
+		txCosts = new(big.Int).Add(txCosts, cost)
+		tips = new(big.Int).Add(tips, tip)
+		details.Transactions = append(details.Transactions, TransactionReward{
+			Hash:             tx.Hash(),
+			GasPrice:         gasPrice,
+			GasUsed:          gasUsed,
+			Fee:              cost,
+			Tip:              tip,
+			ReceiptAvailable: receipt != nil,
+		})
+	}
+	details.Timings.Receipts = time.Since(phaseStart)
+	details.ReceiptsComplete = details.ReceiptFallbacks == 0
+
+	details.Fees = txCosts
+	details.Burnt = burntFees
+	details.Tips = tips
+	details.Reward = new(big.Int).Sub(txCosts, burntFees)
+	details.IsZeroReward = details.Reward.Sign() == 0
+	details.Status = status
+	if c.extendedStatuses {
+		details.Status = extendedStatus(status, block, details.ReceiptFallbacks > 0)
+	}
+	details.Timings.Total = time.Since(start)
+	recordRewardTimings(ctx, details.Timings)
+	return details, nil
+}
+
+// GetBlockRewardDetails computes the full reward decomposition of a slot together with its depth
+// below the head and whether it is finalized. It always queries the upstream and does not use
+// the reward cache.
+func (c *Web3Client) GetBlockRewardDetails(ctx context.Context, slotId string) (*BlockRewardDetails, error) {
+	slotIdAsInt, err := c.validateRewardSlot(slotId)
+	if err != nil {
+		return nil, err
+	}
+	details, err := c.computeBlockRewardDetails(ctx, slotId, slotIdAsInt)
+	if err != nil {
+		return nil, err
+	}
+	checkpoints, err := c.getFinalityCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	details.Finalized = c.isSlotFinalized(slotIdAsInt, checkpoints)
+	if details.Finalized {
+		c.promoteBlockDetail(ctx, slotId)
+	}
+	details.FinalityStatus = c.finalityStatus(slotIdAsInt, checkpoints)
+	blockRewards, err := c.getBlockRewards(ctx, slotId)
+	if err != nil {
+		log.Info().Err(err).Str("slotId", slotId).Msg("can not get consensus reward, leaving out estimated total")
+		return details, nil
+	}
+	consensusReward := new(big.Int).Mul(new(big.Int).SetUint64(uint64(blockRewards.Total)), GWEI)
+	details.ConsensusReward = consensusReward
+	details.EstimatedTotal = new(big.Int).Add(details.Reward, consensusReward)
+	details.Check = c.checkBlockRewards(details, blockRewards)
+	return details, nil
+}
+
+// FeeSplit is how the gas fees of a block were split between the burnt base fee and the tips, in
+// wei and in percent of their sum with two decimals.
+type FeeSplit struct {
+	Burnt        *big.Int `json:"burnt"`
+	Tips         *big.Int `json:"tips"`
+	BurntPercent string   `json:"burntPercent"`
+	TipsPercent  string   `json:"tipsPercent"`
+}
+
+// GetFeeSplit computes the burnt fees and tips of the block of the slot like the reward. The
+// tips share is the rest of the rounded burnt share, so both add up to exactly 100. A block
+// without fees reports 0 for both.
+func (c *Web3Client) GetFeeSplit(ctx context.Context, slotId string) (*FeeSplit, error) {
+	slotIdAsInt, err := c.validateRewardSlot(slotId)
+	if err != nil {
+		return nil, err
+	}
+	details, err := c.computeBlockRewardDetails(ctx, slotId, slotIdAsInt)
+	if err != nil {
+		return nil, err
+	}
+	split := &FeeSplit{Burnt: details.Burnt, Tips: details.Tips, BurntPercent: "0.00", TipsPercent: "0.00"}
+	total := new(big.Int).Add(details.Burnt, details.Tips)
+	if total.Sign() == 0 {
+		return split, nil
+	}
+	burntPercent := new(big.Rat).SetFrac(new(big.Int).Mul(details.Burnt, big.NewInt(100)), total)
+	split.BurntPercent = burntPercent.FloatString(2)
+	rounded, _ := new(big.Rat).SetString(split.BurntPercent)
+	split.TipsPercent = new(big.Rat).Sub(big.NewRat(100, 1), rounded).FloatString(2)
+	return split, nil
+}
+
+// isReorged reports whether the block of the slot changed since the reward was cached. Rewards
+// of final slots are not checked. When the block hash can not be fetched the cached reward is
+// kept.
+func (c *Web3Client) isReorged(ctx context.Context, slotId string, cached cachedReward) bool {
+	if cached.Final {
+		return false
+	}
+	// the cached block would hide the reorg, it is replaced when the slot was reorged
+	blockDetail, blockHash, err := c.getBlockDetailWithPayload(ctx, slotId)
+	if err != nil {
+		log.Info().Err(err).Str("slotId", slotId).Msg("can not check cached reward for reorg")
+		return false
+	}
+	if blockHash != cached.BlockHash {
+		c.cacheBlockDetail(ctx, slotId, blockDetail)
+		log.Info().Str("slotId", slotId).Str("cachedBlockHash", cached.BlockHash.Hex()).
+			Str("blockHash", blockHash.Hex()).Msg("slot was reorged, invalidating cached reward")
+		return true
+	}
+	return false
+}
+
+// BlockReward is the reward in wei and the status of the block of a slot. Truncated is set when
+// the block had more transactions than receipts may be fetched for, the reward then includes
+// estimated contributions, see BlockRewardDetails.
+type BlockReward struct {
+	Reward    *big.Int
+	Status    string
+	Truncated bool
+}
+
+// Gwei formats the reward in gwei with nine decimals. The division is exact, a float would round
+// rewards above 2^64 wei in the last decimals.
+func (r *BlockReward) Gwei() string {
+	return new(big.Rat).SetFrac(r.Reward, GWEI).FloatString(9)
+}
+
+func (c *Web3Client) getBlockReward(ctx context.Context, slotId string) (*BlockReward, error) {
+	slotIdAsInt, err := c.validateRewardSlot(slotId)
+	if err != nil {
+		return nil, err
+	}
+	var cached cachedReward
+	if c.getCached(ctx, rewardCacheKey(slotId), &cached) && !c.isReorged(ctx, slotId, cached) {
+		recordCacheOutcome(ctx, true)
+		return &BlockReward{Reward: cached.Reward, Status: cached.Status, Truncated: cached.Truncated}, nil
+	}
+	details, err := c.computeBlockRewardDetails(ctx, slotId, slotIdAsInt)
+	if err != nil {
+		return nil, err
+	}
+	recordCacheOutcome(ctx, false)
+	ttl, finalized := c.rewardCacheTTL(ctx, slotIdAsInt)
+	if finalized {
+		c.promoteBlockDetail(ctx, slotId)
+	}
+	c.setCachedFor(ctx, rewardCacheKey(slotId), cachedReward{
+		Reward:    details.Reward,
+		Status:    details.Status,
+		Truncated: details.Truncated,
+		BlockHash: details.BlockHash,
+		Final:     finalized || details.Depth >= ReorgSafeEpochs*c.spec.SlotsPerEpoch,
+	}, ttl)
+	return &BlockReward{Reward: details.Reward, Status: details.Status, Truncated: details.Truncated}, nil
+}
+
+// rewardCacheTTL returns the cache ttl of the reward of the slot by its finality and whether the
+// slot is finalized. When the checkpoints can not be fetched the slot is treated as recent.
+func (c *Web3Client) rewardCacheTTL(ctx context.Context, slot *big.Int) (time.Duration, bool) {
+	checkpoints, err := c.getFinalityCheckpoints(ctx)
+	if err != nil {
+		log.Info().Err(err).Str("slotId", slot.String()).Msg("can not get finality checkpoints, caching reward as recent")
+		return c.cacheTTLs.Recent, false
+	}
+	finalityStatus := c.finalityStatus(slot, checkpoints)
+	return c.cacheTTLs.forStatus(finalityStatus), finalityStatus == FinalityFinalized
+}
+
+// GetBlockReward returns the reward of the block of the slot. Slots more than FutureEpochMargin
+// epochs past the clock are reported as future without looking up the head.
+func (c *Web3Client) GetBlockReward(ctx context.Context, slotId string) (*BlockReward, error) {
+	if slotIdAsInt, err := c.parseSlotId(slotId); err == nil && c.isBeyondClockEpoch(slotIdAsInt, c.clock.Now()) {
+		return nil, &FutureSlotError{msg: "Slot is in the future"}
+	}
+	return c.getBlockReward(ctx, slotId)
+}
+
+// GetBlockRewardWei returns the reward in wei and the status of the block of the slot, see
+// GetBlockReward.
+func (c *Web3Client) GetBlockRewardWei(ctx context.Context, slotId string) (*big.Int, string, error) {
+	reward, err := c.GetBlockReward(ctx, slotId)
+	if err != nil {
+		return nil, "", err
+	}
+	return reward.Reward, reward.Status, nil
+}
+
+// GetBlockRewardAndStatusBySlot returns the reward formatted in gwei with nine decimals and the
+// status of the block of the slot.
+func (c *Web3Client) GetBlockRewardAndStatusBySlot(ctx context.Context, slotId string) (*string, *string, error) {
+	reward, err := c.GetBlockReward(ctx, slotId)
+	if err != nil {
+		return nil, nil, err
+	}
+	rewardAsText := reward.Gwei()
+	return &rewardAsText, &reward.Status, nil
+}
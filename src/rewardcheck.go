@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// RewardCheckScope tells in the response what the check covers.
+const RewardCheckScope = "node-consistency"
+
+// RewardCheck sets the computed reward next to the block rewards the beacon node reports. The node
+// only reports the consensus layer reward, which can not be derived from the execution block, and
+// the consensus reward of the details is taken from the same report, so the computed execution
+// reward is not validated. Both figures are only shown side by side. The checks cover the
+// consistency of the node's data: the proposer and the slashings against the block it served and
+// whether its total adds up to its parts within the tolerance. Scope is always RewardCheckScope.
+type RewardCheck struct {
+	ComputedReward    *big.Int `json:"computedReward"`
+	NodeReward        *big.Int `json:"nodeReward"`
+	NodeProposerIndex string   `json:"nodeProposerIndex"`
+	Tolerance         *big.Int `json:"tolerance"`
+	Discrepancy       bool     `json:"discrepancy"`
+	Reasons           []string `json:"reasons,omitempty"`
+	Scope             string   `json:"scope"`
+}
+
+// checkBlockRewards compares the reward details with the block rewards of the beacon node. All
+// amounts are in wei.
+func (c *Web3Client) checkBlockRewards(details *BlockRewardDetails, node *blockRewardsData) *RewardCheck {
+	check := &RewardCheck{
+		ComputedReward:    details.Reward,
+		NodeReward:        new(big.Int).Mul(new(big.Int).SetUint64(uint64(node.Total)), GWEI),
+		NodeProposerIndex: node.ProposerIndex.String(),
+		Tolerance:         new(big.Int).Mul(new(big.Int).SetUint64(c.rewardCheckTolerance), GWEI),
+		Scope:             RewardCheckScope,
+	}
+	if node.ProposerIndex.String() != details.ProposerIndex {
+		check.Reasons = append(check.Reasons, fmt.Sprintf("node reports proposer %s, the block was proposed by %s",
+			node.ProposerIndex, details.ProposerIndex))
+	}
+	parts := new(big.Int)
+	for _, part := range []BeaconUint64{node.Attestations, node.SyncAggregate, node.ProposerSlashings, node.AttesterSlashings} {
+		parts.Add(parts, new(big.Int).SetUint64(uint64(part)))
+	}
+	difference := new(big.Int).Sub(new(big.Int).SetUint64(uint64(node.Total)), parts)
+	if difference.Mul(difference.Abs(difference), GWEI).Cmp(check.Tolerance) > 0 {
+		check.Reasons = append(check.Reasons, fmt.Sprintf("node total of %d gwei differs from its parts by %s wei",
+			node.Total, difference))
+	}
+	if (node.ProposerSlashings > 0) != (details.ProposerSlashings > 0) {
+		check.Reasons = append(check.Reasons, fmt.Sprintf("node reports %d gwei for proposer slashings, the block includes %d",
+			node.ProposerSlashings, details.ProposerSlashings))
+	}
+	if (node.AttesterSlashings > 0) != (details.AttesterSlashings > 0) {
+		check.Reasons = append(check.Reasons, fmt.Sprintf("node reports %d gwei for attester slashings, the block includes %d",
+			node.AttesterSlashings, details.AttesterSlashings))
+	}
+	check.Discrepancy = len(check.Reasons) > 0
+	return check
+}
@@ -0,0 +1,77 @@
+package main_test
+
+import (
+	"context"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestBlockRewardDetailsCheckAgainstNodeRewards(t *testing.T) {
+	tests := []struct {
+		name          string
+		proposerIndex string
+		nodeRewards   string
+		tolerance     uint64
+		discrepancy   bool
+		reason        string
+	}{
+		{"match", "1", `{"proposer_index":"1","total":"3","attestations":"2","sync_aggregate":"1","proposer_slashings":"0","attester_slashings":"0"}`, 0, false, ""},
+		{"other proposer", "2", `{"proposer_index":"1","total":"3","attestations":"2","sync_aggregate":"1","proposer_slashings":"0","attester_slashings":"0"}`, 0, true, "proposer"},
+		{"total beyond tolerance", "1", `{"proposer_index":"1","total":"6","attestations":"2","sync_aggregate":"1","proposer_slashings":"0","attester_slashings":"0"}`, 2, true, "differs from its parts"},
+		{"total within tolerance", "1", `{"proposer_index":"1","total":"5","attestations":"2","sync_aggregate":"1","proposer_slashings":"0","attester_slashings":"0"}`, 2, false, ""},
+		{"slashing not in block", "1", `{"proposer_index":"1","total":"5","attestations":"2","sync_aggregate":"1","proposer_slashings":"2","attester_slashings":"0"}`, 0, true, "proposer slashings"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			upstream := setupServer("detailedWithConsensus")
+			defer upstream.Close()
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/eth/v2/beacon/blocks/4700013":
+					_, _ = rw.Write([]byte(`{"data":{"message":{"proposer_index":"` + test.proposerIndex + `","body":{"execution_payload":{"block_hash":"1111"}}}}}`))
+				case "/eth/v1/beacon/rewards/blocks/4700013":
+					_, _ = rw.Write([]byte(`{"data":` + test.nodeRewards + `}`))
+				default:
+					upstream.Config.Handler.ServeHTTP(rw, req)
+				}
+			}))
+			defer server.Close()
+			parsedUrl, _ := url.Parse(server.URL)
+			client := src.NewWeb3Client(parsedUrl, 1000, src.WithRewardCheckTolerance(test.tolerance))
+
+			details, err := client.GetBlockRewardDetails(context.Background(), "4700013")
+			if err != nil {
+				t.Fatal(err)
+			}
+			check := details.Check
+			if check == nil || check.ComputedReward.Cmp(details.Reward) != 0 || check.NodeReward.Cmp(details.ConsensusReward) != 0 || check.Scope != src.RewardCheckScope {
+				t.Fatalf("Expected both rewards in the check, but got %+v", check)
+			}
+			if check.Discrepancy != test.discrepancy || !strings.Contains(strings.Join(check.Reasons, ";"), test.reason) {
+				t.Errorf("Expected discrepancy %t with a reason about %q, but got %t %v", test.discrepancy, test.reason, check.Discrepancy, check.Reasons)
+			}
+		})
+	}
+}
+
+func TestDetailedBlockRewardIncludesCheckOnRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupServer("detailedWithConsensus")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(src.NewWeb3Client(parsedUrl, 1000)))
+
+	if recorder := performRequest(router, "/blockreward/4700013?detailed=true"); strings.Contains(recorder.Body.String(), `"check"`) {
+		t.Errorf("Expected no check without check=true, but got %s", recorder.Body.String())
+	}
+	recorder := performRequest(router, "/blockreward/4700013?detailed=true&check=true")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"check":{"computedReward":`) {
+		t.Errorf("Expected the check, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
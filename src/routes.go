@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"slices"
+	"strings"
+)
+
+// APIRouteNames are the first path segments of the API routes, by which they are enabled.
+var APIRouteNames = []string{
+	"blocknumber", "blockreward", "blockrewards", "burnt", "epoch", "rewards", "slot", "stats", "syncduties",
+	"syncperiod", "validators",
+}
+
+// RegisterRoutes adds the API routes to the router, each with its own timeout. The debug routes
+// are only added when debug is set.
+func RegisterRoutes(router gin.IRouter, client *Web3Client, timeouts RouteTimeouts, debug bool) {
+	blockRewardTimeout := TimeoutMiddleware(timeouts.orDefault(timeouts.BlockReward))
+	defaultTimeout := TimeoutMiddleware(timeouts.Default)
+	rewardHandlers := []gin.HandlerFunc{blockRewardTimeout}
+	if debug {
+		rewardHandlers = append(rewardHandlers, markDebug)
+	}
+	router.GET("/blockreward/compare", blockRewardTimeout, GetCompareBlockRewardsHandler(client))
+	router.GET("/blockreward", append(rewardHandlers, GetBlockRewardHandler(client))...)
+	router.GET("/blockreward/:slotId", append(rewardHandlers, GetBlockRewardHandler(client))...)
+	router.POST("/blockrewards", TimeoutMiddleware(timeouts.orDefault(timeouts.Batch)),
+		IdempotencyMiddleware(NewIdempotencyStoreWithClock(DefaultIdempotencyTTL, DefaultIdempotencyMaxEntries, client.clock)),
+		GetBlockRewardsHandler(client))
+	router.GET("/epoch/:epoch/blockrewards", TimeoutMiddleware(timeouts.orDefault(timeouts.Batch)), GetEpochBlockRewardsHandler(client))
+	router.GET("/epoch/:epoch/proposers", defaultTimeout, GetProposerDutiesHandler(client))
+	router.GET("/syncduties/:slotId", TimeoutMiddleware(timeouts.orDefault(timeouts.SyncDuties)), GetSyncDutiesHandler(client))
+	router.GET("/slot/:slotId/graffiti", defaultTimeout, GetGraffitiHandler(client))
+	router.GET("/slot/:slotId/builder", defaultTimeout, GetBlockBuilderHandler(client))
+	router.GET("/slot/:slotId/blocknumber", defaultTimeout, GetBlockNumberHandler(client))
+	router.GET("/slot/:slotId/raw", defaultTimeout, GetRawBlockHandler(client))
+	router.GET("/slot/:slotId/fees", defaultTimeout, GetBlockFeesHandler(client))
+	router.GET("/slot/:slotId/logs/summary", defaultTimeout, GetLogsSummaryHandler(client))
+	router.GET("/slot/:slotId/feesplit", blockRewardTimeout, GetFeeSplitHandler(client))
+	router.GET("/blocknumber/:number/slot", defaultTimeout, GetSlotByBlockNumberHandler(client))
+	router.GET("/slot/at", GetSlotAtTimeHandler(client))
+	router.GET("/slot/:slotId/fork", GetForkHandler(client))
+	router.GET("/syncperiod", GetSyncPeriodHandler(client))
+	router.GET("/stats/merge", defaultTimeout, GetMergeStatsHandler(client))
+	router.GET("/burnt/total", defaultTimeout, GetBurntTotalHandler(client))
+	router.GET("/rewards/average", TimeoutMiddleware(timeouts.orDefault(timeouts.Batch)), GetAverageRewardHandler(client))
+	router.GET("/stats/mevratio", TimeoutMiddleware(timeouts.orDefault(timeouts.Batch)), GetMevRatioHandler(client))
+	router.POST("/validators/indexes", defaultTimeout, GetValidatorIndexesHandler(client))
+	if debug {
+		router.GET("/slot/:slotId/full", blockRewardTimeout, GetBlockRewardDetailsHandler(client))
+	}
+}
+
+// ParseEnabledRoutes parses route names in the form "blockreward,syncduties". An empty value
+// enables all routes and returns nil.
+func ParseEnabledRoutes(value string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(APIRouteNames, name) {
+			return nil, fmt.Errorf("route %q is unknown", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// routeFilter registers only the GET and POST routes whose first path segment is enabled, so
+// requests to the other routes answer 404 like unknown paths.
+type routeFilter struct {
+	gin.IRouter
+	enabled []string
+}
+
+// FilterRoutes returns a router that only registers the routes named in enabled on router. A nil
+// enabled registers all routes.
+func FilterRoutes(router gin.IRouter, enabled []string) gin.IRouter {
+	if enabled == nil {
+		return router
+	}
+	return &routeFilter{IRouter: router, enabled: enabled}
+}
+
+func (f *routeFilter) isEnabled(relativePath string) bool {
+	name, _, _ := strings.Cut(strings.TrimPrefix(relativePath, "/"), "/")
+	return slices.Contains(f.enabled, name)
+}
+
+func (f *routeFilter) GET(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	if !f.isEnabled(relativePath) {
+		return f
+	}
+	return f.IRouter.GET(relativePath, handlers...)
+}
+
+func (f *routeFilter) POST(relativePath string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	if !f.isEnabled(relativePath) {
+		return f
+	}
+	return f.IRouter.POST(relativePath, handlers...)
+}
+
+// groupRoutes returns the group at relativePath of router, keeping the route filter of router.
+func groupRoutes(router gin.IRouter, relativePath string) gin.IRouter {
+	if filter, ok := router.(*routeFilter); ok {
+		return &routeFilter{IRouter: filter.IRouter.Group(relativePath), enabled: filter.enabled}
+	}
+	return router.Group(relativePath)
+}
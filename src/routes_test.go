@@ -0,0 +1,46 @@
+package main_test
+
+import (
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestFilterRoutesRegistersOnlyEnabledRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupServer("vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+	router := gin.New()
+	routes := src.FilterRoutes(router, []string{"blockreward"})
+	src.RegisterRoutes(routes, client, src.DefaultRouteTimeouts(), false)
+	src.RegisterNetworkRoutes(routes, map[string]*src.Web3Client{"holesky": client}, src.DefaultRouteTimeouts(), false)
+
+	for path, code := range map[string]int{
+		"/blockreward/4700013":         http.StatusOK,
+		"/holesky/blockreward/4700013": http.StatusOK,
+		"/syncduties/4700013":          http.StatusNotFound,
+		"/holesky/syncduties/4700013":  http.StatusNotFound,
+		"/slot/4700013/graffiti":       http.StatusNotFound,
+	} {
+		if recorder := performRequest(router, path); recorder.Code != code {
+			t.Errorf("Expected status %d for %s, but got %d", code, path, recorder.Code)
+		}
+	}
+}
+
+func TestParseEnabledRoutes(t *testing.T) {
+	names, err := src.ParseEnabledRoutes(" blockreward, syncduties ")
+	if err != nil || len(names) != 2 || names[0] != "blockreward" || names[1] != "syncduties" {
+		t.Errorf("Expected blockreward and syncduties, but got %v %v", names, err)
+	}
+	if names, err := src.ParseEnabledRoutes(""); names != nil || err != nil {
+		t.Errorf("Expected all routes to stay enabled, but got %v %v", names, err)
+	}
+	if _, err := src.ParseEnabledRoutes("blockreward,admin"); err == nil {
+		t.Error("Expected an unknown route to be rejected")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultShutdownTimeout = 15 * time.Second
+const DefaultPreDrainDelay = 5 * time.Second
+
+// Readiness tells load balancers through /ready whether the instance takes new traffic.
+type Readiness struct {
+	draining atomic.Bool
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+func (r *Readiness) Ready() bool {
+	return !r.draining.Load()
+}
+
+// StartDraining makes /ready fail from now on.
+func (r *Readiness) StartDraining() {
+	r.draining.Store(true)
+}
+
+// ReadyHandler answers 200 while the instance takes traffic and 503 once it is shutting down.
+func ReadyHandler(readiness *Readiness) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !readiness.Ready() {
+			respondError(c, http.StatusServiceUnavailable, "Shutting down")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status": "ready",
+		})
+	}
+}
+
+// ServerOptions configures RunServer. HTTPS is served when both TLS files are set. Readiness, when
+// set, is flipped to draining as soon as the shutdown starts.
+type ServerOptions struct {
+	ShutdownTimeout time.Duration
+	PreDrainDelay   time.Duration
+	Readiness       *Readiness
+	TLSCertFile     string
+	TLSKeyFile      string
+}
+
+// RunServer serves HTTP, or HTTPS when TLS files are configured, on the listener until ctx is
+// done. It then fails the readiness check and keeps serving for the pre-drain delay, so load
+// balancers stop sending traffic before connections are refused. After that it stops accepting
+// connections and waits up to the shutdown timeout for in-flight requests to finish. Requests
+// still running after that are cut off by closing their connections.
+func RunServer(ctx context.Context, server *http.Server, listener net.Listener, options ServerOptions) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		if options.TLSCertFile != "" && options.TLSKeyFile != "" {
+			serveErr <- server.ServeTLS(listener, options.TLSCertFile, options.TLSKeyFile)
+			return
+		}
+		serveErr <- server.Serve(listener)
+	}()
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	if options.Readiness != nil {
+		options.Readiness.StartDraining()
+	}
+	if options.PreDrainDelay > 0 {
+		log.Info().Dur("delay", options.PreDrainDelay).Msg("failing readiness before draining")
+		select {
+		case err := <-serveErr:
+			return err
+		case <-time.After(options.PreDrainDelay):
+		}
+	}
+	log.Info().Dur("timeout", options.ShutdownTimeout).Msg("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), options.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Info().Err(err).Msg("drain timeout elapsed, closing remaining connections")
+		if closeErr := server.Close(); closeErr != nil {
+			return closeErr
+		}
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
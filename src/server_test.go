@@ -0,0 +1,199 @@
+package main_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunServerDrainsUntilShutdownTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var started sync.WaitGroup
+	started.Add(2)
+	server := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		duration, _ := time.ParseDuration(req.URL.Query().Get("sleep"))
+		started.Done()
+		time.Sleep(duration)
+		rw.WriteHeader(http.StatusOK)
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- src.RunServer(ctx, server, listener, src.ServerOptions{ShutdownTimeout: 300 * time.Millisecond})
+	}()
+
+	results := make(map[string]error)
+	var mu sync.Mutex
+	var done sync.WaitGroup
+	for _, sleep := range []string{"100ms", "2s"} {
+		done.Add(1)
+		go func(sleep string) {
+			defer done.Done()
+			resp, err := http.Get("http://" + listener.Addr().String() + "/?sleep=" + sleep)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results[sleep] = err
+		}(sleep)
+	}
+	started.Wait()
+	cancel()
+	done.Wait()
+
+	if results["100ms"] != nil {
+		t.Errorf("Expected the short request to complete, but got %v", results["100ms"])
+	}
+	if results["2s"] == nil {
+		t.Error("Expected the long request to be cut off")
+	}
+	if err := <-runErr; err == nil {
+		t.Error("Expected RunServer to report the elapsed drain timeout")
+	}
+}
+
+func TestRunServerFailsReadinessBeforeDraining(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readiness := src.NewReadiness()
+	started := make(chan struct{})
+	router := gin.New()
+	router.GET("/ready", src.ReadyHandler(readiness))
+	router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		time.Sleep(400 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- src.RunServer(ctx, &http.Server{Handler: router}, listener, src.ServerOptions{
+			ShutdownTimeout: 2 * time.Second,
+			PreDrainDelay:   200 * time.Millisecond,
+			Readiness:       readiness,
+		})
+	}()
+	baseUrl := "http://" + listener.Addr().String()
+	get := func(path string) (int, error) {
+		resp, err := http.Get(baseUrl + path)
+		if err != nil {
+			return 0, err
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	if status, err := get("/ready"); err != nil || status != http.StatusOK {
+		t.Fatalf("Expected the server to be ready, but got %d %v", status, err)
+	}
+
+	slowStatus := make(chan int, 1)
+	go func() {
+		status, _ := get("/slow")
+		slowStatus <- status
+	}()
+	<-started
+	cancel()
+	// the shutdown has started, but the server still answers during the pre-drain delay
+	time.Sleep(50 * time.Millisecond)
+	if status, err := get("/ready"); err != nil || status != http.StatusServiceUnavailable {
+		t.Errorf("Expected /ready to fail once the shutdown started, but got %d %v", status, err)
+	}
+	if status := <-slowStatus; status != http.StatusOK {
+		t.Errorf("Expected the in-flight request to complete, but got %d", status)
+	}
+	if err := <-runErr; err != nil {
+		t.Errorf("Expected a clean shutdown, but got %v", err)
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and its key to dir.
+func writeTestCertificate(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return certFile, keyFile, cert
+}
+
+func TestRunServerServesTLS(t *testing.T) {
+	certFile, keyFile, cert := writeTestCertificate(t, t.TempDir())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- src.RunServer(ctx, server, listener, src.ServerOptions{
+			ShutdownTimeout: time.Second,
+			TLSCertFile:     certFile,
+			TLSKeyFile:      keyFile,
+		})
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get("https://" + listener.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot || resp.TLS == nil {
+		t.Errorf("Expected a response over TLS, but got status %d", resp.StatusCode)
+	}
+	cancel()
+	if err := <-runErr; err != nil {
+		t.Errorf("Expected a clean shutdown, but got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+const SpecPath = "/eth/v1/config/spec"
+const GenesisPath = "/eth/v1/beacon/genesis"
+
+// FarFutureEpoch is the fork epoch of forks that are not scheduled.
+const FarFutureEpoch = math.MaxUint64
+
+// Fork names, in activation order.
+const (
+	ForkPhase0    = "phase0"
+	ForkAltair    = "altair"
+	ForkBellatrix = "bellatrix"
+	ForkCapella   = "capella"
+	ForkDeneb     = "deneb"
+	ForkElectra   = "electra"
+)
+
+// ForkSchedule holds the activation epoch of every fork after phase0, FarFutureEpoch when the
+// fork is not scheduled.
+type ForkSchedule struct {
+	Altair    uint64 `json:"altair"`
+	Bellatrix uint64 `json:"bellatrix"`
+	Capella   uint64 `json:"capella"`
+	Deneb     uint64 `json:"deneb"`
+	Electra   uint64 `json:"electra"`
+}
+
+// ForkAt returns the name of the fork active at the epoch.
+func (f ForkSchedule) ForkAt(epoch uint64) string {
+	forks := []struct {
+		name  string
+		epoch uint64
+	}{
+		{ForkElectra, f.Electra},
+		{ForkDeneb, f.Deneb},
+		{ForkCapella, f.Capella},
+		{ForkBellatrix, f.Bellatrix},
+		{ForkAltair, f.Altair},
+	}
+	for _, fork := range forks {
+		if epoch >= fork.epoch {
+			return fork.name
+		}
+	}
+	return ForkPhase0
+}
+
+// ChainSpec holds the chain parameters used in slot, epoch and timestamp math.
+type ChainSpec struct {
+	SlotsPerEpoch                uint64       `json:"slotsPerEpoch"`
+	SecondsPerSlot               uint64       `json:"secondsPerSlot"`
+	EpochsPerSyncCommitteePeriod uint64       `json:"epochsPerSyncCommitteePeriod"`
+	SyncCommitteeSize            uint64       `json:"syncCommitteeSize"`
+	GenesisTime                  time.Time    `json:"genesisTime"`
+	Forks                        ForkSchedule `json:"forks"`
+}
+
+// SpecOverrides replaces values of the chain spec, zero values leave the spec untouched.
+type SpecOverrides struct {
+	SlotsPerEpoch  uint64
+	SecondsPerSlot uint64
+	GenesisTime    int64
+}
+
+func MainnetChainSpec() ChainSpec {
+	return ChainSpec{
+		SlotsPerEpoch:                32,
+		SecondsPerSlot:               12,
+		EpochsPerSyncCommitteePeriod: 256,
+		SyncCommitteeSize:            512,
+		GenesisTime:                  time.Unix(1606824023, 0),
+		Forks: ForkSchedule{
+			Altair:    74240,
+			Bellatrix: 144896,
+			Capella:   194048,
+			Deneb:     269568,
+			Electra:   364032,
+		},
+	}
+}
+
+// ForkAtSlot returns the name of the fork active at the slot.
+func (s ChainSpec) ForkAtSlot(slot uint64) string {
+	return s.Forks.ForkAt(slot / s.SlotsPerEpoch)
+}
+
+func (s ChainSpec) SlotDuration() time.Duration {
+	return time.Duration(s.SecondsPerSlot) * time.Second
+}
+
+// SyncPeriod holds the slot boundaries of the sync committee period of a slot and the first slot
+// of the next period.
+type SyncPeriod struct {
+	CurrentPeriod    string `json:"currentPeriod"`
+	CurrentStartSlot string `json:"currentStartSlot"`
+	CurrentEndSlot   string `json:"currentEndSlot"`
+	NextStartSlot    string `json:"nextStartSlot"`
+}
+
+// SyncPeriodOf returns the sync committee period the slot belongs to.
+func (s ChainSpec) SyncPeriodOf(slot uint64) SyncPeriod {
+	slotsPerPeriod := s.SlotsPerEpoch * s.EpochsPerSyncCommitteePeriod
+	period := slot / slotsPerPeriod
+	start := period * slotsPerPeriod
+	return SyncPeriod{
+		CurrentPeriod:    strconv.FormatUint(period, 10),
+		CurrentStartSlot: strconv.FormatUint(start, 10),
+		CurrentEndSlot:   strconv.FormatUint(start+slotsPerPeriod-1, 10),
+		NextStartSlot:    strconv.FormatUint(start+slotsPerPeriod, 10),
+	}
+}
+
+func (s ChainSpec) withOverrides(overrides SpecOverrides) ChainSpec {
+	if overrides.SlotsPerEpoch != 0 {
+		s.SlotsPerEpoch = overrides.SlotsPerEpoch
+	}
+	if overrides.SecondsPerSlot != 0 {
+		s.SecondsPerSlot = overrides.SecondsPerSlot
+	}
+	if overrides.GenesisTime != 0 {
+		s.GenesisTime = time.Unix(overrides.GenesisTime, 0)
+	}
+	return s
+}
+
+type specResponse struct {
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+type genesisResponse struct {
+	Data struct {
+		GenesisTime BeaconUint64 `json:"genesis_time"`
+	} `json:"data"`
+}
+
+func specUint(spec specResponse, key string, target *uint64) error {
+	raw, ok := spec.Data[key]
+	if !ok {
+		return fmt.Errorf("spec is missing %s", key)
+	}
+	var value BeaconUint64
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("can not decode %s: %w", key, err)
+	}
+	*target = uint64(value)
+	return nil
+}
+
+func (c *Web3Client) fetchSpec(ctx context.Context) (ChainSpec, error) {
+	spec := MainnetChainSpec()
+	var response specResponse
+	if err := c.sendAPIRequest(ctx, c.BaseUrl.String()+SpecPath, "chain spec", &response); err != nil {
+		return spec, err
+	}
+	for key, target := range map[string]*uint64{
+		"SLOTS_PER_EPOCH":                  &spec.SlotsPerEpoch,
+		"SECONDS_PER_SLOT":                 &spec.SecondsPerSlot,
+		"EPOCHS_PER_SYNC_COMMITTEE_PERIOD": &spec.EpochsPerSyncCommitteePeriod,
+		"SYNC_COMMITTEE_SIZE":              &spec.SyncCommitteeSize,
+	} {
+		if err := specUint(response, key, target); err != nil {
+			return MainnetChainSpec(), err
+		}
+	}
+	// a fork the beacon node does not list is unknown to it, so it is not scheduled
+	for key, target := range map[string]*uint64{
+		"ALTAIR_FORK_EPOCH":    &spec.Forks.Altair,
+		"BELLATRIX_FORK_EPOCH": &spec.Forks.Bellatrix,
+		"CAPELLA_FORK_EPOCH":   &spec.Forks.Capella,
+		"DENEB_FORK_EPOCH":     &spec.Forks.Deneb,
+		"ELECTRA_FORK_EPOCH":   &spec.Forks.Electra,
+	} {
+		*target = FarFutureEpoch
+		if _, ok := response.Data[key]; !ok {
+			continue
+		}
+		if err := specUint(response, key, target); err != nil {
+			return MainnetChainSpec(), err
+		}
+	}
+	var genesis genesisResponse
+	if err := c.sendAPIRequest(ctx, c.BaseUrl.String()+GenesisPath, "genesis", &genesis); err != nil {
+		return MainnetChainSpec(), err
+	}
+	spec.GenesisTime = time.Unix(int64(genesis.Data.GenesisTime), 0)
+	return spec, nil
+}
+
+// LoadSpec fetches the chain spec and genesis time from the beacon node and keeps them on the
+// client. When the beacon node can not provide them the mainnet defaults are used and the error
+// is returned for logging. Overrides are applied in both cases. It must be called before the
+// client serves requests.
+func (c *Web3Client) LoadSpec(ctx context.Context, overrides SpecOverrides) error {
+	spec, err := c.fetchSpec(ctx)
+	c.spec = spec.withOverrides(overrides)
+	return err
+}
+
+func (c *Web3Client) Spec() ChainSpec {
+	return c.spec
+}
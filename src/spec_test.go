@@ -0,0 +1,222 @@
+package main_test
+
+import (
+	"context"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func setupSpecServer(specStatusCode int) *httptest.Server {
+	r := mux.NewRouter()
+	r.HandleFunc("/eth/v1/config/spec", func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(specStatusCode)
+		if specStatusCode != http.StatusOK {
+			_, _ = rw.Write([]byte(`{"code": 500, "message": "internal error"}`))
+			return
+		}
+		_, _ = rw.Write([]byte(`{"data": {
+			"CONFIG_NAME": "holesky",
+			"SLOTS_PER_EPOCH": "8",
+			"SECONDS_PER_SLOT": "6",
+			"EPOCHS_PER_SYNC_COMMITTEE_PERIOD": "8",
+			"SYNC_COMMITTEE_SIZE": "32",
+			"ALTAIR_FORK_EPOCH": "0",
+			"BELLATRIX_FORK_EPOCH": "0",
+			"CAPELLA_FORK_EPOCH": "256",
+			"DENEB_FORK_EPOCH": "29696",
+			"BLOB_SCHEDULE": []
+		}}`))
+	})
+	r.HandleFunc("/eth/v1/beacon/genesis", func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`{"data": {"genesis_time": "1695902400"}}`))
+	})
+	return httptest.NewServer(r)
+}
+
+func TestLoadSpec(t *testing.T) {
+	server := setupSpecServer(http.StatusOK)
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100)
+	if err := client.LoadSpec(context.Background(), src.SpecOverrides{}); err != nil {
+		t.Fatal(err)
+	}
+	spec := client.Spec()
+	if spec.SlotsPerEpoch != 8 || spec.SecondsPerSlot != 6 {
+		t.Errorf("Expected 8 slots per epoch and 6 seconds per slot, but got %d and %d", spec.SlotsPerEpoch, spec.SecondsPerSlot)
+	}
+	if spec.EpochsPerSyncCommitteePeriod != 8 || spec.SyncCommitteeSize != 32 {
+		t.Errorf("Unexpected sync committee parameters %+v", spec)
+	}
+	if !spec.GenesisTime.Equal(time.Unix(1695902400, 0)) {
+		t.Errorf("Expected genesis time from the beacon node, but got %s", spec.GenesisTime)
+	}
+	expectedForks := src.ForkSchedule{Capella: 256, Deneb: 29696, Electra: src.FarFutureEpoch}
+	if spec.Forks != expectedForks {
+		t.Errorf("Expected fork schedule %+v, but got %+v", expectedForks, spec.Forks)
+	}
+}
+
+func TestLoadSpecOverrides(t *testing.T) {
+	server := setupSpecServer(http.StatusOK)
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100)
+	err := client.LoadSpec(context.Background(), src.SpecOverrides{SlotsPerEpoch: 16, GenesisTime: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := client.Spec()
+	if spec.SlotsPerEpoch != 16 || spec.SecondsPerSlot != 6 || spec.GenesisTime.Unix() != 1000 {
+		t.Errorf("Expected overrides to win over the beacon node spec, but got %+v", spec)
+	}
+}
+
+func TestLoadSpecFallsBackToMainnet(t *testing.T) {
+	server := setupSpecServer(http.StatusInternalServerError)
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100)
+	if err := client.LoadSpec(context.Background(), src.SpecOverrides{}); err == nil {
+		t.Error("Expected the spec error to be reported")
+	}
+	if client.Spec() != src.MainnetChainSpec() {
+		t.Errorf("Expected mainnet defaults, but got %+v", client.Spec())
+	}
+}
+
+func TestSlotAtTime(t *testing.T) {
+	parsedUrl, _ := url.Parse("http://127.0.0.1:1")
+	client := src.NewWeb3Client(parsedUrl, 100)
+	tests := []struct {
+		timestamp int64
+		slot      uint64
+	}{
+		{1606824023, 0},
+		{1606824034, 0},
+		{1606824035, 1},
+		{1663224179, 4700013},
+	}
+	for _, test := range tests {
+		slot, err := client.SlotAtTime(time.Unix(test.timestamp, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slot != test.slot {
+			t.Errorf("Expected slot %d at %d, but got %d", test.slot, test.timestamp, slot)
+		}
+	}
+	if _, err := client.SlotAtTime(time.Unix(1606824022, 0)); err == nil {
+		t.Error("Expected a timestamp before genesis to be rejected")
+	}
+	if _, err := client.SlotAtTime(time.Now().Add(48 * time.Hour)); err == nil {
+		t.Error("Expected a timestamp far in the future to be rejected")
+	}
+}
+
+func TestSlotAtTimeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parsedUrl, _ := url.Parse("http://127.0.0.1:1")
+	client := src.NewWeb3Client(parsedUrl, 100)
+	router := gin.New()
+	router.GET("/slot/:slotId/graffiti", src.GetGraffitiHandler(client))
+	router.GET("/slot/at", src.GetSlotAtTimeHandler(client))
+
+	recorder := performRequest(router, "/slot/at?ts=1663224179")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"slot":"4700013"}` {
+		t.Errorf("Expected slot 4700013, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	for _, ts := range []string{"abc", "1"} {
+		if recorder := performRequest(router, "/slot/at?ts="+ts); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for ts %s, but got %d", ts, recorder.Code)
+		}
+	}
+}
+
+func TestSyncPeriodOf(t *testing.T) {
+	spec := src.MainnetChainSpec()
+	tests := []struct {
+		slot     uint64
+		expected src.SyncPeriod
+	}{
+		{0, src.SyncPeriod{CurrentPeriod: "0", CurrentStartSlot: "0", CurrentEndSlot: "8191", NextStartSlot: "8192"}},
+		{8191, src.SyncPeriod{CurrentPeriod: "0", CurrentStartSlot: "0", CurrentEndSlot: "8191", NextStartSlot: "8192"}},
+		{8192, src.SyncPeriod{CurrentPeriod: "1", CurrentStartSlot: "8192", CurrentEndSlot: "16383", NextStartSlot: "16384"}},
+		{4700013, src.SyncPeriod{CurrentPeriod: "573", CurrentStartSlot: "4694016", CurrentEndSlot: "4702207", NextStartSlot: "4702208"}},
+	}
+	for _, test := range tests {
+		if period := spec.SyncPeriodOf(test.slot); period != test.expected {
+			t.Errorf("Expected %+v for slot %d, but got %+v", test.expected, test.slot, period)
+		}
+	}
+}
+
+func TestSyncPeriodHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parsedUrl, _ := url.Parse("http://127.0.0.1:1")
+	client := src.NewWeb3Client(parsedUrl, 100)
+	router := gin.New()
+	router.GET("/syncperiod", src.GetSyncPeriodHandler(client))
+
+	recorder := performRequest(router, "/syncperiod?slot=4702207")
+	expected := `{"currentPeriod":"573","currentStartSlot":"4694016","currentEndSlot":"4702207","nextStartSlot":"4702208"}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+	if recorder := performRequest(router, "/syncperiod"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the period of the current slot, but got %d", recorder.Code)
+	}
+	if recorder := performRequest(router, "/syncperiod?slot=abc"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid slot, but got %d", recorder.Code)
+	}
+}
+
+func TestForkAtSlot(t *testing.T) {
+	spec := src.MainnetChainSpec()
+	tests := []struct {
+		slot uint64
+		fork string
+	}{
+		{0, src.ForkPhase0},
+		{2375679, src.ForkPhase0},
+		{2375680, src.ForkAltair},
+		{4636671, src.ForkAltair},
+		{4636672, src.ForkBellatrix},
+		{6209535, src.ForkBellatrix},
+		{6209536, src.ForkCapella},
+		{8626175, src.ForkCapella},
+		{8626176, src.ForkDeneb},
+		{11649023, src.ForkDeneb},
+		{11649024, src.ForkElectra},
+	}
+	for _, test := range tests {
+		if fork := spec.ForkAtSlot(test.slot); fork != test.fork {
+			t.Errorf("Expected slot %d to be in %s, but got %s", test.slot, test.fork, fork)
+		}
+	}
+	if fork := (src.ForkSchedule{Altair: 1, Bellatrix: 2, Capella: src.FarFutureEpoch, Deneb: src.FarFutureEpoch,
+		Electra: src.FarFutureEpoch}).ForkAt(1 << 40); fork != src.ForkBellatrix {
+		t.Errorf("Expected unscheduled forks to never activate, but got %s", fork)
+	}
+}
+
+func TestForkHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parsedUrl, _ := url.Parse("http://127.0.0.1:1")
+	router := gin.New()
+	router.GET("/slot/:slotId/fork", src.GetForkHandler(src.NewWeb3Client(parsedUrl, 100)))
+
+	recorder := performRequest(router, "/slot/6209536/fork")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"fork":"capella","slot":"6209536"}` {
+		t.Errorf("Expected slot 6209536 to be in capella, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := performRequest(router, "/slot/abc/fork"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid slot, but got %d", recorder.Code)
+	}
+}
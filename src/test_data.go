@@ -1,5 +1,10 @@
 package main
 
+import (
+	"fmt"
+	"strings"
+)
+
 type TestData struct {
 	HeadersResponse                string
 	HeadersStatusCode              int
@@ -11,17 +16,229 @@ type TestData struct {
 	SyncCommitteesStatusCode       int
 	SyncCommitteesDetailResponse   string
 	SyncCommitteesDetailStatusCode int
+	FinalityCheckpointsResponse    string
+	FinalityCheckpointsStatusCode  int
+	BlockRewardsResponse           string
+	BlockRewardsStatusCode         int
+}
+
+const logsBloom = "0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002000000080000000000000000200000000000000000000020000000000000000000000000000000000000800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000020001000000400000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000800000000000000000010200000000000000000000000000000000000000000000000000000020000"
+
+const blockDetailResponse = `{
+	"data":{
+		"message":{
+			"body":{
+				"execution_payload": {
+					"block_hash": "1111",
+					"block_number": "15537394"
+				}
+			}
+		}
+	}
+}`
+
+const dynamicFeeTransaction = `{
+	"type": "0x2",
+	"chainId": "0x1",
+	"nonce": "0x1",
+	"gas": "0x1",
+	"maxPriorityFeePerGas": "0x1",
+	"maxFeePerGas": "0x1",
+	"value": "0x0",
+	"input": "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+	"r": "0x0",
+	"s": "0x0",
+	"v": "0x0"
+}`
+
+func blockResponse(baseFee string, gasUsed string, transactions ...string) string {
+	transactionsRoot := "0x0000000000000000000000000000000000000000000000000000000000000000"
+	if len(transactions) == 0 {
+		// go-ethereum rejects an empty transaction list unless the root is the empty trie root
+		transactionsRoot = "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"
+	}
+	return `{
+		"jsonrpc": "2.0",
+		"id": 1,
+		"result": {
+			"baseFeePerGas": "` + baseFee + `",
+			"gasUsed": "` + gasUsed + `",
+			"parentHash": "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"sha3Uncles": "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
+			"stateRoot": "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"transactionsRoot": "` + transactionsRoot + `",
+			"receiptsRoot": "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"difficulty": "0x0",
+			"number": "0x0",
+			"gasLimit": "0x11",
+			"timestamp": "0x111",
+			"extraData": "0x0000000000000000000000000000000000000000000000000000000000000001",
+			"uncles": [],
+			"logsBloom": "` + logsBloom + `",
+			"transactions": [` + strings.Join(transactions, ",") + `]
+		}
+	}`
+}
+
+var blockHashResponse = blockResponse("0x1", "0x2", dynamicFeeTransaction)
+
+// numberedTransactions returns count dynamic fee transactions that only differ in their nonce.
+func numberedTransactions(count int) []string {
+	transactions := make([]string, count)
+	for i := range transactions {
+		transactions[i] = strings.Replace(dynamicFeeTransaction, `"nonce": "0x1"`, fmt.Sprintf(`"nonce": "0x%x"`, i+1), 1)
+	}
+	return transactions
 }
 
+// builtBlockResponse is a block with a hash, fee recipient and extra data. Its transactions are
+// sent by from and pay to, so each pair of addresses adds one transaction.
+func builtBlockResponse(miner string, extraData string, fromTo ...string) string {
+	var transactions []string
+	for i := 0; i+1 < len(fromTo); i += 2 {
+		transactions = append(transactions, strings.Replace(dynamicFeeTransaction, `"type": "0x2",`,
+			`"type": "0x2", "from": "`+fromTo[i]+`", "to": "`+fromTo[i+1]+`",`, 1))
+	}
+	return strings.Replace(blockResponse("0x1", "0x2", transactions...),
+		`"extraData": "0x0000000000000000000000000000000000000000000000000000000000000001",`,
+		`"hash": "0x0000000000000000000000000000000000000000000000000000000000001111", "miner": "`+miner+`", "extraData": "`+extraData+`",`, 1)
+}
+
+func transactionReceiptResponse(gasUsed string, effectiveGasPrice string) string {
+	return `{
+		"jsonrpc": "2.0",
+		"id": 1,
+		"result": {
+			"gasUsed": "` + gasUsed + `",
+			"cumulativeGasUsed": "0x1",
+			"effectiveGasPrice": "` + effectiveGasPrice + `",
+			"type": "0x2",
+			"logs": [],
+			"transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000001",
+			"logsBloom": "` + logsBloom + `"
+		}
+	}`
+}
+
+// receiptWithLogsResponse is a receipt carrying logCount logs of dataSize bytes each, as large
+// contract interactions do.
+func receiptWithLogsResponse(gasUsed string, effectiveGasPrice string, logCount int, dataSize int) string {
+	log := `{
+		"address": "0x0000000000000000000000000000000000000001",
+		"topics": ["0x0000000000000000000000000000000000000000000000000000000000000001"],
+		"data": "0x` + strings.Repeat("ff", dataSize) + `",
+		"transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000001"
+	}`
+	logs := make([]string, logCount)
+	for i := range logs {
+		logs[i] = log
+	}
+	return strings.Replace(transactionReceiptResponse(gasUsed, effectiveGasPrice), `"logs": []`,
+		`"logs": [`+strings.Join(logs, ",")+`]`, 1)
+}
+
+// legacyTransaction pays a gas price of 4 wei.
+const legacyTransaction = `{
+	"type": "0x0",
+	"nonce": "0x2",
+	"gas": "0x2",
+	"gasPrice": "0x4",
+	"value": "0x0",
+	"input": "0x",
+	"r": "0x0",
+	"s": "0x0",
+	"v": "0x0"
+}`
+
 var AllTestData = map[string]TestData{
 	"mev": {
+		HeadersResponse:            `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:          200,
+		BlocksStatusCode:           200,
+		BlocksResponse:             blockDetailResponse,
+		BlockHashResponse:          blockHashResponse,
+		TransactionReceiptResponse: transactionReceiptResponse("0x1", "0x4"),
+	},
+	"zeroReward": {
+		HeadersResponse:               `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:             200,
+		BlocksStatusCode:              200,
+		BlocksResponse:                blockDetailResponse,
+		BlockHashResponse:             blockHashResponse,
+		TransactionReceiptResponse:    transactionReceiptResponse("0x2", "0x1"),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+	},
+	"vanilla": {
+		HeadersResponse:            `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:          200,
+		BlocksStatusCode:           200,
+		BlocksResponse:             blockDetailResponse,
+		BlockHashResponse:          blockHashResponse,
+		TransactionReceiptResponse: transactionReceiptResponse("0x3", "0x1"),
+	},
+	"detailedDeep": {
+		HeadersResponse:               `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:             200,
+		BlocksStatusCode:              200,
+		BlocksResponse:                blockDetailResponse,
+		BlockHashResponse:             blockHashResponse,
+		TransactionReceiptResponse:    transactionReceiptResponse("0x1", "0x4"),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+	},
+	"manyTransactions": {
 		HeadersResponse:   `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
 		HeadersStatusCode: 200,
 		BlocksStatusCode:  200,
+		BlocksResponse:    blockDetailResponse,
+		BlockHashResponse: blockResponse("0x1", "0x2",
+			`{"type": "0x0", "nonce": "0x1", "gas": "0x2", "gasPrice": "0x3", "value": "0x5", "input": "0x", "r": "0x0", "s": "0x0", "v": "0x0"}`,
+			`{"type": "0x1", "chainId": "0x1", "nonce": "0x2", "gas": "0x1", "gasPrice": "0x2", "value": "0x0", "input": "0x", "accessList": [], "r": "0x0", "s": "0x0", "v": "0x0"}`,
+			`{"type": "0x2", "chainId": "0x1", "nonce": "0x3", "gas": "0x2", "maxPriorityFeePerGas": "0x1", "maxFeePerGas": "0x4", "value": "0x0", "input": "0x", "accessList": [], "r": "0x0", "s": "0x0", "v": "0x0"}`,
+			`{"type": "0x2", "chainId": "0x1", "nonce": "0x4", "gas": "0x2", "maxPriorityFeePerGas": "0x1", "maxFeePerGas": "0x4", "value": "0x0", "input": "0x", "accessList": [], "r": "0x0", "s": "0x0", "v": "0x0"}`,
+		),
+		TransactionReceiptResponse:    transactionReceiptResponse("0x1", "0x4"),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+	},
+	"detailedWithConsensus": {
+		HeadersResponse:               `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:             200,
+		BlocksStatusCode:              200,
+		BlocksResponse:                blockDetailResponse,
+		BlockHashResponse:             blockHashResponse,
+		TransactionReceiptResponse:    transactionReceiptResponse("0x1", "0x4"),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+		BlockRewardsResponse:          `{"data":{"proposer_index":"1","total":"3","attestations":"2","sync_aggregate":"1","proposer_slashings":"0","attester_slashings":"0"}}`,
+		BlockRewardsStatusCode:        200,
+	},
+	"detailedHead": {
+		HeadersResponse:               `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:             200,
+		BlocksStatusCode:              200,
+		BlocksResponse:                blockDetailResponse,
+		BlockHashResponse:             blockHashResponse,
+		TransactionReceiptResponse:    transactionReceiptResponse("0x1", "0x4"),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146875","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+	},
+	"rewardOverflow": {
+		HeadersResponse:            `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:          200,
+		BlocksStatusCode:           200,
+		BlocksResponse:             blockDetailResponse,
+		BlockHashResponse:          blockHashResponse,
+		TransactionReceiptResponse: transactionReceiptResponse("0x1", "0x10000000000000000"),
+	},
+	"graffiti": {
+		BlocksStatusCode: 200,
 		BlocksResponse: `{
 			"data":{
 				"message":{
 					"body":{
+						"graffiti": "0x4c69676874686f757365072f76342e352e300000000000000000000000000000",
 						"execution_payload": {
 							"block_hash": "1111"
 						}
@@ -29,118 +246,121 @@ var AllTestData = map[string]TestData{
 				}
 			}
 		}`,
-		BlockHashResponse: `{
-			"jsonrpc": "2.0",
-			"id": 1,
-			"result": {
-				"baseFeePerGas": "0x1",
-				"gasUsed": "0x2",
-				"parentHash": "0x0000000000000000000000000000000000000000000000000000000000000000",
-				"sha3Uncles": "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
-				"stateRoot": "0x0000000000000000000000000000000000000000000000000000000000000000",
-				"transactionsRoot": "0x0000000000000000000000000000000000000000000000000000000000000000",
-				"receiptsRoot": "0x0000000000000000000000000000000000000000000000000000000000000000",
-				"difficulty": "0x0",
-				"number": "0x0",
-				"gasLimit": "0x11",
-				"timestamp": "0x111",
-				"extraData": "0x0000000000000000000000000000000000000000000000000000000000000001",
-				"uncles": [],
-				"logsBloom": "0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002000000080000000000000000200000000000000000000020000000000000000000000000000000000000800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000020001000000400000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000800000000000000000010200000000000000000000000000000000000000000000000000000020000",
-				"transactions": [
-					{
-						"type": "0x2",
-						"chainId": "0x1",
-						"nonce": "0x1",
-						"gas": "0x1",
-						"maxPriorityFeePerGas": "0x1",
-						"maxFeePerGas": "0x1",
-						"value": "0x0",
-						"input": "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
-						"r": "0x0",
-						"s": "0x0",
-						"v": "0x0"
-					}
-				]
-			}
-		}`,
-		TransactionReceiptResponse: `{
-			"jsonrpc": "2.0", 
-			"id": 1, 
-			"result": {
-				"gasUsed": "0x1", 
-				"cumulativeGasUsed": "0x1", 
-				"effectiveGasPrice": "0x4", 
-				"type": "0x2",
-				"logs": [],
-				"transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000001",
-				"logsBloom": "0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002000000080000000000000000200000000000000000000020000000000000000000000000000000000000800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000020001000000400000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000800000000000000000010200000000000000000000000000000000000000000000000000000020000"
-			}
-		}`,
 	},
-	"vanilla": {
+	"missingReceipts": {
 		HeadersResponse:   `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
 		HeadersStatusCode: 200,
 		BlocksStatusCode:  200,
-		BlocksResponse: `{
-			"data":{
-				"message":{
-					"body":{
-						"execution_payload": {
-							"block_hash": "1111"
-						}
-					}
-				}
-			}
-		}`,
-		BlockHashResponse: `{
-			"jsonrpc": "2.0",
-			"id": 1,
-			"result": {
-				"baseFeePerGas": "0x1",
-				"gasUsed": "0x2",
-				"parentHash": "0x0000000000000000000000000000000000000000000000000000000000000000",
-				"sha3Uncles": "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
-				"stateRoot": "0x0000000000000000000000000000000000000000000000000000000000000000",
-				"transactionsRoot": "0x0000000000000000000000000000000000000000000000000000000000000000",
-				"receiptsRoot": "0x0000000000000000000000000000000000000000000000000000000000000000",
-				"difficulty": "0x0",
-				"number": "0x0",
-				"gasLimit": "0x11",
-				"timestamp": "0x111",
-				"extraData": "0x0000000000000000000000000000000000000000000000000000000000000001",
-				"uncles": [],
-				"logsBloom": "0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002000000080000000000000000200000000000000000000020000000000000000000000000000000000000800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000020001000000400000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000800000000000000000010200000000000000000000000000000000000000000000000000000020000",
-				"transactions": [
-					{
-						"type": "0x2",
-						"chainId": "0x1",
-						"nonce": "0x1",
-						"gas": "0x1",
-						"maxPriorityFeePerGas": "0x1",
-						"maxFeePerGas": "0x1",
-						"value": "0x0",
-						"input": "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
-						"r": "0x0",
-						"s": "0x0",
-						"v": "0x0"
-					}
-				]
-			}
-		}`,
-		TransactionReceiptResponse: `{
-			"jsonrpc": "2.0", 
-			"id": 1, 
-			"result": {
-				"gasUsed": "0x3", 
-				"cumulativeGasUsed": "0x1", 
-				"effectiveGasPrice": "0x1", 
-				"type": "0x2",
-				"logs": [],
-				"transactionHash": "0x0000000000000000000000000000000000000000000000000000000000000001",
-				"logsBloom": "0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002000000080000000000000000200000000000000000000020000000000000000000000000000000000000800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000020001000000400000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000800000000000000000010200000000000000000000000000000000000000000000000000000020000"
-			}
-		}`,
+		BlocksResponse:    blockDetailResponse,
+		BlockHashResponse: blockResponse("0x1", "0x2",
+			`{"type": "0x0", "nonce": "0x1", "gas": "0x2", "gasPrice": "0x3", "value": "0x5", "input": "0x", "r": "0x0", "s": "0x0", "v": "0x0"}`,
+			`{"type": "0x1", "chainId": "0x1", "nonce": "0x2", "gas": "0x1", "gasPrice": "0x2", "value": "0x0", "input": "0x", "accessList": [], "r": "0x0", "s": "0x0", "v": "0x0"}`,
+			`{"type": "0x2", "chainId": "0x1", "nonce": "0x3", "gas": "0x2", "maxPriorityFeePerGas": "0x1", "maxFeePerGas": "0x4", "value": "0x0", "input": "0x", "accessList": [], "r": "0x0", "s": "0x0", "v": "0x0"}`,
+		),
+		TransactionReceiptResponse: `{"jsonrpc": "2.0", "id": 1, "result": null}`,
+	},
+	"builtByRelay": {
+		HeadersResponse:               `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:             200,
+		BlocksStatusCode:              200,
+		BlocksResponse:                blockDetailResponse,
+		TransactionReceiptResponse:    transactionReceiptResponse("0x1", "0x1"),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+		BlockHashResponse: builtBlockResponse("0x00000000000000000000000000000000000000b1", "0x",
+			"0x00000000000000000000000000000000000000a1", "0x00000000000000000000000000000000000000a2",
+			"0x00000000000000000000000000000000000000b1", "0x00000000000000000000000000000000000000f1"),
+	},
+	"builtLocally": {
+		HeadersResponse:               `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:             200,
+		BlocksStatusCode:              200,
+		BlocksResponse:                blockDetailResponse,
+		TransactionReceiptResponse:    transactionReceiptResponse("0x1", "0x1"),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+		BlockHashResponse: builtBlockResponse("0x00000000000000000000000000000000000000f1", "0x676574682f76312e31332e31352f6c696e7578",
+			"0x00000000000000000000000000000000000000a1", "0x00000000000000000000000000000000000000a2"),
+	},
+	"coinbaseTransfer": {
+		HeadersResponse:               `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:             200,
+		BlocksStatusCode:              200,
+		BlocksResponse:                blockDetailResponse,
+		TransactionReceiptResponse:    transactionReceiptResponse("0x1", "0x1"),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+		// a searcher pays 7 wei to the fee recipient and a withdrawal credits it with 1 gwei
+		BlockHashResponse: strings.Replace(strings.Replace(
+			builtBlockResponse("0x00000000000000000000000000000000000000f1", "0x",
+				"0x00000000000000000000000000000000000000a1", "0x00000000000000000000000000000000000000f1"),
+			`"value": "0x0"`, `"value": "0x7"`, 1),
+			`"uncles": [],`,
+			`"uncles": [], "withdrawalsRoot": "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"withdrawals": [{"index": "0x0", "validatorIndex": "0x1", "address": "0x00000000000000000000000000000000000000f1", "amount": "0x1"}],`, 1),
+	},
+	"slashings": {
+		HeadersResponse:   `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode: 200,
+		BlocksStatusCode:  200,
+		// the block includes one proposer slashing and two attester slashings
+		BlocksResponse: `{"data":{"message":{"proposer_index":"4242","body":{
+			"proposer_slashings":[{"signed_header_1":{},"signed_header_2":{}}],
+			"attester_slashings":[{"attestation_1":{},"attestation_2":{}},{"attestation_1":{},"attestation_2":{}}],
+			"execution_payload":{"block_hash":"1111","block_number":"15537394"}}}}}`,
+		BlockHashResponse:             blockResponse("0x1", "0x2", dynamicFeeTransaction),
+		TransactionReceiptResponse:    transactionReceiptResponse("0x1", "0x2"),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+	},
+	"lowBaseFee": {
+		HeadersResponse:   `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode: 200,
+		BlocksStatusCode:  200,
+		BlocksResponse:    blockDetailResponse,
+		// a testnet block with a base fee of 7 wei and a usual tip of 1.5 gwei
+		BlockHashResponse:          blockResponse("0x7", "0x2", dynamicFeeTransaction),
+		TransactionReceiptResponse: transactionReceiptResponse("0x2", "0x59682f07"),
+	},
+	"hugeBlock": {
+		HeadersResponse:               `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:             200,
+		BlocksStatusCode:              200,
+		BlocksResponse:                blockDetailResponse,
+		BlockHashResponse:             blockResponse("0x1", "0x2", numberedTransactions(50)...),
+		TransactionReceiptResponse:    transactionReceiptResponse("0x1", "0x4"),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+	},
+	"largeBlock": {
+		HeadersResponse:               `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode:             200,
+		BlocksStatusCode:              200,
+		BlocksResponse:                blockDetailResponse,
+		BlockHashResponse:             blockResponse("0x1", "0x2", numberedTransactions(1000)...),
+		TransactionReceiptResponse:    receiptWithLogsResponse("0x1", "0x4", 8, 1024),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+	},
+	"receiptWithoutGasPrice": {
+		HeadersResponse:   `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode: 200,
+		BlocksStatusCode:  200,
+		BlocksResponse:    blockDetailResponse,
+		BlockHashResponse: blockResponse("0x1", "0x4", legacyTransaction, strings.NewReplacer(
+			`"maxPriorityFeePerGas": "0x1"`, `"maxPriorityFeePerGas": "0x2"`,
+			`"maxFeePerGas": "0x1"`, `"maxFeePerGas": "0x5"`).Replace(dynamicFeeTransaction)),
+		TransactionReceiptResponse: strings.Replace(transactionReceiptResponse("0x2", ""),
+			`"effectiveGasPrice": "",`, "", 1),
+		FinalityCheckpointsResponse:   `{"data":{"finalized":{"epoch":"146876","root":"0x01"}}}`,
+		FinalityCheckpointsStatusCode: 200,
+	},
+	"emptyBlock": {
+		HeadersResponse:   `{"data":[{"header":{"message":{"slot":"4700015"}}}]}`,
+		HeadersStatusCode: 200,
+		BlocksStatusCode:  200,
+		BlocksResponse:    blockDetailResponse,
+		BlockHashResponse: blockResponse("0x1", "0x0"),
 	},
 	"rewardMissingSlot": {
 		HeadersResponse:   `{"data":[{"header":{"message":{"slot":"4700012"}}}]}`,
@@ -156,10 +376,13 @@ var AllTestData = map[string]TestData{
 	"syncFutureSlot": {
 		SyncCommitteesStatusCode: 404,
 	},
+	"syncUpstreamError": {
+		SyncCommitteesStatusCode: 500,
+	},
 	"syncDuties": {
 		SyncCommitteesResponse:         `{"data": {"validators": ["1"]}}`,
 		SyncCommitteesStatusCode:       200,
 		SyncCommitteesDetailStatusCode: 200,
-		SyncCommitteesDetailResponse:   `{"data": [{"validator": {"pubkey": "0x0000000000000000000000000000000000000000000000000000000000000001"}}]}`,
+		SyncCommitteesDetailResponse:   `{"data": [{"index": "1", "validator": {"pubkey": "0x0000000000000000000000000000000000000000000000000000000000000001"}}]}`,
 	},
 }
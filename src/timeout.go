@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+const DefaultRequestTimeout = 10 * time.Second
+const DefaultSyncDutiesTimeout = 30 * time.Second // resolving up to 512 validators takes several upstream calls
+const DefaultBatchTimeout = 60 * time.Second      // a batch computes up to MaxSlotRange rewards
+
+// RouteTimeouts holds the deadline of each route. Routes without their own value use Default,
+// and a zero Default leaves requests without a deadline.
+type RouteTimeouts struct {
+	Default     time.Duration
+	BlockReward time.Duration
+	SyncDuties  time.Duration
+	Batch       time.Duration
+}
+
+func DefaultRouteTimeouts() RouteTimeouts {
+	return RouteTimeouts{
+		Default:     DefaultRequestTimeout,
+		BlockReward: DefaultRequestTimeout,
+		SyncDuties:  DefaultSyncDutiesTimeout,
+		Batch:       DefaultBatchTimeout,
+	}
+}
+
+func (t RouteTimeouts) orDefault(timeout time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	return t.Default
+}
+
+// TimeoutMiddleware bounds the request context with the timeout, so upstream calls made while
+// handling the request are cancelled once it passes.
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,52 @@
+package main_test
+
+import (
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// setupSlowRouter registers the routes against an upstream that answers after delay.
+func setupSlowRouter(testKey string, delay time.Duration, timeouts src.RouteTimeouts) (*gin.Engine, func()) {
+	gin.SetMode(gin.TestMode)
+	upstream := setupServer(testKey)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		time.Sleep(delay)
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	src.RegisterRoutes(router, src.NewWeb3Client(parsedUrl, 1000), timeouts, false)
+	return router, func() {
+		server.Close()
+		upstream.Close()
+	}
+}
+
+func TestRouteTimeouts(t *testing.T) {
+	short, long := 50*time.Millisecond, 5*time.Second
+	tests := []struct {
+		testKey  string
+		path     string
+		timeouts src.RouteTimeouts
+		status   int
+	}{
+		{"mev", "/blockreward/4700013", src.RouteTimeouts{Default: long, BlockReward: short}, http.StatusGatewayTimeout},
+		{"mev", "/blockreward/4700013", src.RouteTimeouts{Default: short, BlockReward: long}, http.StatusOK},
+		{"syncDuties", "/syncduties/4700013", src.RouteTimeouts{Default: long, SyncDuties: short}, http.StatusGatewayTimeout},
+		{"syncDuties", "/syncduties/4700013", src.RouteTimeouts{Default: short, BlockReward: short, SyncDuties: long}, http.StatusOK},
+		{"graffiti", "/slot/4700013/graffiti", src.RouteTimeouts{Default: short, BlockReward: long, SyncDuties: long}, http.StatusGatewayTimeout},
+	}
+	for _, test := range tests {
+		router, closeServer := setupSlowRouter(test.testKey, 100*time.Millisecond, test.timeouts)
+		recorder := performRequest(router, test.path)
+		closeServer()
+		if recorder.Code != test.status {
+			t.Errorf("%s with %+v: expected status %d, but got %d", test.path, test.timeouts, test.status, recorder.Code)
+		}
+	}
+}
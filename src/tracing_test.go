@@ -0,0 +1,76 @@
+package main_test
+
+import (
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracingSpansForBlockReward(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previousProvider)
+
+	server := setupServer("mev")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(src.TracingMiddleware())
+	router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(client))
+
+	recorder := performRequest(router, "/blockreward/4700013")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", recorder.Code)
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	handlerSpan, ok := spans["GET /blockreward/:slotId"]
+	if !ok {
+		t.Fatalf("Expected a handler span, but got %v", spans)
+	}
+	for _, name := range []string{"compute block reward", "current slot id", "beacon block detail", "eth_getBlockByHash", "eth_getTransactionReceipt"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("Expected a %s span", name)
+			continue
+		}
+		if span.SpanContext.TraceID() != handlerSpan.SpanContext.TraceID() {
+			t.Errorf("Expected %s span to be in the request trace", name)
+		}
+	}
+	if value, ok := spanAttribute(handlerSpan, "slot.id"); !ok || value.AsString() != "4700013" {
+		t.Errorf("Expected slot.id attribute on handler span, but got %v", value)
+	}
+	if value, ok := spanAttribute(handlerSpan, "http.status_code"); !ok || value.AsInt64() != http.StatusOK {
+		t.Errorf("Expected http.status_code attribute on handler span, but got %v", value)
+	}
+	blockDetailSpan := spans["beacon block detail"]
+	if value, ok := spanAttribute(blockDetailSpan, "upstream.endpoint"); !ok || value.AsString() != "/eth/v2/beacon/blocks/4700013" {
+		t.Errorf("Expected upstream.endpoint attribute on beacon span, but got %v", value)
+	}
+	if value, ok := spanAttribute(blockDetailSpan, "http.status_code"); !ok || value.AsInt64() != http.StatusOK {
+		t.Errorf("Expected http.status_code attribute on beacon span, but got %v", value)
+	}
+}
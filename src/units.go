@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// MaxPrecision is the largest number of decimals an amount can be formatted with.
+const MaxPrecision = 36
+
+// unitDecimals maps the supported units to their number of decimals in wei.
+var unitDecimals = map[string]int{
+	"wei":  0,
+	"gwei": 9,
+	"eth":  18,
+}
+
+// UnitDecimals returns the number of decimals of the unit in wei, false for unknown units.
+func UnitDecimals(unit string) (int, bool) {
+	decimals, ok := unitDecimals[unit]
+	return decimals, ok
+}
+
+// FormatWeiRatio formats num/den wei in the unit with precision decimals. The division is done
+// on exact rationals and the last decimal is rounded to nearest, halves away from zero, so large
+// wei amounts do not pick up float rounding errors.
+func FormatWeiRatio(num *big.Int, den *big.Int, unit string, precision int) (string, error) {
+	decimals, ok := UnitDecimals(unit)
+	if !ok {
+		return "", fmt.Errorf("unit %q is not supported", unit)
+	}
+	if den.Sign() == 0 {
+		return "", fmt.Errorf("can not divide by zero")
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	ratio := new(big.Rat).SetFrac(num, new(big.Int).Mul(den, scale))
+	return ratio.FloatString(min(max(precision, 0), MaxPrecision)), nil
+}
+
+// UnitAmount is an amount together with its unit, so it can not be read in the wrong unit.
+type UnitAmount struct {
+	Value string `json:"value"`
+	Unit  string `json:"unit"`
+}
+
+// NewUnitAmount converts the wei amount to the unit with all the decimals of the unit, so no
+// precision is lost.
+func NewUnitAmount(wei *big.Int, unit string) (UnitAmount, error) {
+	decimals, ok := UnitDecimals(unit)
+	if !ok {
+		return UnitAmount{}, fmt.Errorf("unit %q is not supported", unit)
+	}
+	value, err := FormatWeiRatio(wei, big.NewInt(1), unit, decimals)
+	if err != nil {
+		return UnitAmount{}, err
+	}
+	return UnitAmount{Value: value, Unit: unit}, nil
+}
+
+// String returns the amount with its unit as suffix, e.g. "1.234567890 gwei".
+func (a UnitAmount) String() string {
+	return a.Value + " " + a.Unit
+}
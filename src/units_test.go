@@ -0,0 +1,63 @@
+package main_test
+
+import (
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"math/big"
+	"testing"
+)
+
+func TestFormatWeiRatioIsExact(t *testing.T) {
+	bigSum, _ := new(big.Int).SetString("55340232221128654842", 10)
+	tests := []struct {
+		num       *big.Int
+		den       int64
+		unit      string
+		precision int
+		expected  string
+	}{
+		// float64 adds 0.1 and 0.2 eth up to 0.30000000000000004
+		{big.NewInt(300000000000000000), 1, "eth", 18, "0.300000000000000000"},
+		// three rewards of 2^64-2 wei, which float64 can not hold
+		{bigSum, 3, "gwei", 9, "18446744073.709551614"},
+		{bigSum, 3, "wei", 0, "18446744073709551614"},
+		{big.NewInt(2), 3, "wei", 2, "0.67"},
+		{big.NewInt(5), 1, "gwei", 9, "0.000000005"},
+		{big.NewInt(5), 1, "gwei", 8, "0.00000001"},
+	}
+	for _, test := range tests {
+		formatted, err := src.FormatWeiRatio(test.num, big.NewInt(test.den), test.unit, test.precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if formatted != test.expected {
+			t.Errorf("Expected %s/%d %s at %d decimals to be %s, but got %s", test.num, test.den, test.unit, test.precision, test.expected, formatted)
+		}
+	}
+	if _, err := src.FormatWeiRatio(big.NewInt(1), big.NewInt(1), "finney", 2); err == nil {
+		t.Error("Expected an unknown unit to be rejected")
+	}
+}
+
+func TestUnitAmountSuffixesTheUnit(t *testing.T) {
+	reward := big.NewInt(1234567890)
+	tests := []struct {
+		unit     string
+		expected string
+	}{
+		{"wei", "1234567890 wei"},
+		{"gwei", "1.234567890 gwei"},
+		{"eth", "0.000000001234567890 eth"},
+	}
+	for _, test := range tests {
+		amount, err := src.NewUnitAmount(reward, test.unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if amount.Unit != test.unit || amount.String() != test.expected {
+			t.Errorf("Expected %s, but got %+v as %s", test.expected, amount, amount.String())
+		}
+	}
+	if _, err := src.NewUnitAmount(reward, "finney"); err == nil {
+		t.Error("Expected an unknown unit to be rejected")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"time"
+)
+
+type upstreamRequestNameKey struct{}
+
+// withUpstreamRequestName names the upstream requests sent with the context in the timing logs.
+func withUpstreamRequestName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, upstreamRequestNameKey{}, name)
+}
+
+// upstreamRequestName returns the name of the request, its kind when it was sent without one.
+func upstreamRequestName(req *http.Request) string {
+	if name, ok := req.Context().Value(upstreamRequestNameKey{}).(string); ok {
+		return name
+	}
+	return requestKind(req)
+}
+
+type timingLogTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip logs the name, host, status and duration of each upstream request at debug level.
+// Only the host of the url is logged, the path and query of an upstream may carry credentials.
+func (tlt *timingLogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := tlt.transport.RoundTrip(req)
+	event := log.Debug().
+		Str("requestName", upstreamRequestName(req)).
+		Str("kind", requestKind(req)).
+		Str("host", req.URL.Host).
+		Dur("elapsed", time.Since(start))
+	if err != nil {
+		event.Err(err).Msg("upstream request failed")
+		return resp, err
+	}
+	event.Int("status", resp.StatusCode).Msg("upstream request")
+	return resp, nil
+}
@@ -0,0 +1,83 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.String()
+}
+
+// captureLogs sends the global logs at level and above to the returned buffer for the rest of
+// the test.
+func captureLogs(t *testing.T, level zerolog.Level) *syncBuffer {
+	output := &syncBuffer{}
+	logger := log.Logger
+	log.Logger = zerolog.New(output).Level(level)
+	t.Cleanup(func() {
+		log.Logger = logger
+	})
+	return output
+}
+
+func TestUpstreamRequestsAreTimedAtDebugLevel(t *testing.T) {
+	server := setupServer("vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(strings.Replace(server.URL, "://", "://user:secret-key@", 1))
+	client := src.NewWeb3Client(parsedUrl, 1000)
+	output := captureLogs(t, zerolog.DebugLevel)
+
+	if _, _, err := client.GetBlockRewardAndStatusBySlot(context.Background(), "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	requestNames := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil || entry["message"] != "upstream request" {
+			continue
+		}
+		requestNames[entry["requestName"].(string)] = true
+		if _, ok := entry["status"].(float64); !ok || entry["level"] != "debug" || entry["host"] != parsedUrl.Host {
+			t.Errorf("Expected a debug entry with the host and status, but got %s", line)
+		}
+		if _, ok := entry["elapsed"].(float64); !ok {
+			t.Errorf("Expected the elapsed time in the entry, but got %s", line)
+		}
+	}
+	if !requestNames["beacon block detail"] || !requestNames["eth_getBlockByHash"] {
+		t.Errorf("Expected the beacon and execution requests to be timed, but got %v", requestNames)
+	}
+	if strings.Contains(output.String(), "secret-key") {
+		t.Errorf("Expected the upstream credentials to stay out of the logs, but got %s", output.String())
+	}
+
+	output = captureLogs(t, zerolog.InfoLevel)
+	if _, _, err := client.GetBlockRewardAndStatusBySlot(src.WithCacheBypass(context.Background()), "4700013"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output.String(), "upstream request") {
+		t.Errorf("Expected no timing logs above debug level, but got %s", output.String())
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+	"math/rand"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const DefaultValidatorBatchSize = 64
+
+// DefaultMaxURLLength keeps id list queries below the 8KB request line limit common to proxies
+// and web servers.
+const DefaultMaxURLLength = 8000
+
+type validatorInfo struct {
+	Index     BeaconUint64 `json:"index"`
+	Validator struct {
+		Pubkey string `json:"pubkey"`
+	} `json:"validator"`
+}
+
+type validatorsDetailResponse struct {
+	Data []validatorInfo `json:"data"`
+}
+
+func (c *Web3Client) validatorsEndpoint(slotId string, ids []string) string {
+	return c.BaseUrl.String() + StatePath + slotId + "/validators?" + url.Values{"id": ids}.Encode()
+}
+
+// nextValidatorBatch returns how many of the ids the next batch takes: at most batchSize, and
+// only as many as keep the lookup url within maxURLLength. A single id is always taken, even when
+// it exceeds the limit on its own.
+func (c *Web3Client) nextValidatorBatch(slotId string, ids []string, batchSize int) int {
+	length := len(c.validatorsEndpoint(slotId, nil))
+	n := 0
+	for n < min(batchSize, len(ids)) {
+		// every id is encoded as id=<escaped id>, separated by &
+		idLength := len("id=") + len(url.QueryEscape(ids[n]))
+		if n > 0 {
+			idLength++
+		}
+		if n > 0 && c.maxURLLength > 0 && length+idLength > c.maxURLLength {
+			break
+		}
+		length += idLength
+		n++
+	}
+	return n
+}
+
+// fetchValidators fetches one batch of validators at the slot.
+func (c *Web3Client) fetchValidators(ctx context.Context, slotId string, ids []string) ([]validatorInfo, error) {
+	endpoint := c.validatorsEndpoint(slotId, ids)
+	var response validatorsDetailResponse
+	err := c.sendAPIRequest(ctx, endpoint, "receive pubkeys of validators", &response)
+	return response.Data, err
+}
+
+// resolveValidators fetches the validators with the given ids (indexes or pubkeys) at the slot in
+// batches. Batches are cut short where their lookup url would exceed maxURLLength, so long ids
+// such as pubkeys stay within the limit whatever the batch size. The first batch settles the batch
+// size: when the beacon node rejects it for having too many ids, the batch size is halved and the
+// batch is retried. The remaining batches are then
+// fetched in parallel, up to the rate burst at a time, with their tokens reserved up front. Any
+// other failing batch fails the lookup, unless bestEffort is set: the ids of the batch are then
+// returned as failed and the remaining batches are still fetched. Missing and future slots, and
+// every batch failing, fail the lookup in both modes.
+func (c *Web3Client) resolveValidators(ctx context.Context, slotId string, ids []string, bestEffort bool) ([]validatorInfo, []string, error) {
+	batchSize := c.validatorBatchSize
+	if batchSize < 1 {
+		batchSize = DefaultValidatorBatchSize
+	}
+	var mu sync.Mutex
+	var validators []validatorInfo
+	var failed []string
+	var lastErr error
+	record := func(batch []string, data []validatorInfo, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil && (!bestEffort || errors.Is(err, ErrSlotMissing) || errors.Is(err, ErrFutureSlot)) {
+			return err
+		}
+		if err != nil {
+			log.Info().Err(err).Int("batchSize", len(batch)).Msg("can not resolve validator batch, skipping it")
+			failed = append(failed, batch...)
+			lastErr = err
+		}
+		validators = append(validators, data...)
+		return nil
+	}
+
+	start := 0
+	for start < len(ids) {
+		end := start + c.nextValidatorBatch(slotId, ids[start:], batchSize)
+		data, err := c.fetchValidators(ctx, slotId, ids[start:end])
+		var tooManyIds *tooManyIdsError
+		if errors.As(err, &tooManyIds) && batchSize > 1 {
+			batchSize /= 2
+			log.Info().Int("batchSize", batchSize).Msg("beacon node rejected validator batch, retrying with smaller batch")
+			continue
+		}
+		if err := record(ids[start:end], data, err); err != nil {
+			return nil, nil, err
+		}
+		start = end
+		break
+	}
+
+	var batches [][]string
+	for start < len(ids) {
+		end := start + c.nextValidatorBatch(slotId, ids[start:], batchSize)
+		batches = append(batches, ids[start:end])
+		start = end
+	}
+	if len(batches) > 0 {
+		ctx, err := c.reserveTokens(ctx, len(batches)*c.requestCosts.kindCost(RequestKindValidators))
+		if err != nil {
+			return nil, nil, err
+		}
+		group, ctx := errgroup.WithContext(ctx)
+		group.SetLimit(max(c.rateBurst, 1))
+		for _, batch := range batches {
+			group.Go(func() error {
+				data, err := c.fetchValidators(ctx, slotId, batch)
+				return record(batch, data, err)
+			})
+		}
+		if err := group.Wait(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(ids) > 0 && len(failed) == len(ids) {
+		return nil, nil, lastErr
+	}
+	return validators, failed, nil
+}
+
+// SyncDuties is a best-effort list of sync committee pubkeys in committee order. Missing lists
+// the indexes of the members whose pubkey could not be resolved.
+type SyncDuties struct {
+	Pubkeys []string `json:"pubkeys"`
+	Missing []string `json:"missing"`
+}
+
+// SyncSubcommitteeCount is the number of subcommittees the sync committee is split into for
+// aggregation, each gossiped on its own subnet.
+const SyncSubcommitteeCount = 4
+
+// SyncSubcommittee holds the pubkeys of one subcommittee in committee order.
+type SyncSubcommittee struct {
+	Subcommittee int      `json:"subcommittee"`
+	Pubkeys      []string `json:"pubkeys"`
+}
+
+// GroupSyncSubcommittees splits the committee by position into SyncSubcommitteeCount
+// subcommittees of equal size: the first quarter of the committee is subcommittee 0 and so on. The
+// position decides the subcommittee, so only a committee of exactly committeeSize members can be
+// split, a single dropped member would move every later one into the wrong subcommittee.
+func GroupSyncSubcommittees(pubKeys []string, committeeSize uint64) ([]SyncSubcommittee, error) {
+	if uint64(len(pubKeys)) != committeeSize {
+		return nil, fmt.Errorf("sync committee has %d of its %d members", len(pubKeys), committeeSize)
+	}
+	if len(pubKeys) == 0 || len(pubKeys)%SyncSubcommitteeCount != 0 {
+		return nil, fmt.Errorf("sync committee of %d members can not be split into %d subcommittees", len(pubKeys), SyncSubcommitteeCount)
+	}
+	size := len(pubKeys) / SyncSubcommitteeCount
+	subcommittees := make([]SyncSubcommittee, 0, SyncSubcommitteeCount)
+	for i := 0; i < SyncSubcommitteeCount; i++ {
+		subcommittees = append(subcommittees, SyncSubcommittee{Subcommittee: i, Pubkeys: pubKeys[i*size : (i+1)*size]})
+	}
+	return subcommittees, nil
+}
+
+// LimitSyncDuties returns the first limit pubkeys of the committee. With sample the pubkeys are
+// instead a random subset drawn with seed, so the same seed returns the same subset. The subset
+// keeps the committee order.
+func LimitSyncDuties(pubKeys []string, limit int, sample bool, seed int64) []string {
+	if limit >= len(pubKeys) {
+		return pubKeys
+	}
+	if !sample {
+		return pubKeys[:limit]
+	}
+	positions := rand.New(rand.NewSource(seed)).Perm(len(pubKeys))[:limit]
+	slices.Sort(positions)
+	sampled := make([]string, 0, limit)
+	for _, position := range positions {
+		sampled = append(sampled, pubKeys[position])
+	}
+	return sampled
+}
+
+// getPubKeysOfSyncCommittees returns the pubkeys of the validators in committee order, the beacon
+// node itself returns them ordered by validator index. Indexes without a pubkey are returned
+// separately.
+func (c *Web3Client) getPubKeysOfSyncCommittees(ctx context.Context, slotId string, validatorIndexes []string, bestEffort bool) (*SyncDuties, error) {
+	validators, _, err := c.resolveValidators(ctx, slotId, validatorIndexes, bestEffort)
+	if err != nil {
+		return nil, err
+	}
+	pubKeysByIndex := make(map[string]string, len(validators))
+	for _, info := range validators {
+		pubKeysByIndex[info.Index.String()] = info.Validator.Pubkey
+	}
+	duties := &SyncDuties{Missing: []string{}}
+	for _, validatorIndex := range validatorIndexes {
+		pubKey, ok := pubKeysByIndex[validatorIndex]
+		if !ok {
+			duties.Missing = append(duties.Missing, validatorIndex)
+			continue
+		}
+		duties.Pubkeys = append(duties.Pubkeys, pubKey)
+	}
+	return duties, nil
+}
+
+// ResolveValidatorIndexes maps the pubkeys to their validator indexes at the slot. Pubkeys the
+// beacon node does not know are returned separately instead of failing the lookup.
+func (c *Web3Client) ResolveValidatorIndexes(ctx context.Context, slotId string, pubKeys []string) (map[string]string, []string, error) {
+	validators, _, err := c.resolveValidators(ctx, slotId, pubKeys, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	indexesByPubKey := make(map[string]string, len(validators))
+	for _, info := range validators {
+		indexesByPubKey[strings.ToLower(info.Validator.Pubkey)] = info.Index.String()
+	}
+	indexes := make(map[string]string, len(pubKeys))
+	unknown := []string{}
+	for _, pubKey := range pubKeys {
+		index, ok := indexesByPubKey[strings.ToLower(pubKey)]
+		if !ok {
+			unknown = append(unknown, pubKey)
+			continue
+		}
+		indexes[pubKey] = index
+	}
+	return indexes, unknown, nil
+}
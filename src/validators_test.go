@@ -0,0 +1,459 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type validatorsRequestLog struct {
+	mu         sync.Mutex
+	batchSizes []int
+}
+
+func (l *validatorsRequestLog) record(size int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.batchSizes = append(l.batchSizes, size)
+}
+
+// setupValidatorsServer serves a sync committee of the given indexes and rejects validator requests
+// listing more than maxIds ids with a "too many ids" error.
+func setupValidatorsServer(committee []string, maxIds int, requests *validatorsRequestLog) *httptest.Server {
+	r := mux.NewRouter()
+	r.HandleFunc("/eth/v1/beacon/states/{slotId}/sync_committees", func(rw http.ResponseWriter, req *http.Request) {
+		response := map[string]interface{}{"data": map[string]interface{}{"validators": committee}}
+		_ = json.NewEncoder(rw).Encode(response)
+	})
+	r.HandleFunc("/eth/v1/beacon/states/{slotId}/validators", func(rw http.ResponseWriter, req *http.Request) {
+		ids := req.URL.Query()["id"]
+		requests.record(len(ids))
+		if len(ids) > maxIds {
+			rw.WriteHeader(http.StatusBadRequest)
+			_, _ = rw.Write([]byte(`{"code": 400, "message": "too many ids requested"}`))
+			return
+		}
+		var data []map[string]interface{}
+		for _, id := range ids {
+			data = append(data, map[string]interface{}{
+				"index":     id,
+				"validator": map[string]string{"pubkey": "0xpubkey" + id},
+			})
+		}
+		_ = json.NewEncoder(rw).Encode(map[string]interface{}{"data": data})
+	})
+	return httptest.NewServer(r)
+}
+
+func TestSyncDutiesAdaptsValidatorBatchSize(t *testing.T) {
+	var committee []string
+	for i := 20; i > 0; i-- {
+		committee = append(committee, strconv.Itoa(i))
+	}
+	requests := &validatorsRequestLog{}
+	server := setupValidatorsServer(committee, 5, requests)
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithValidatorBatchSize(16))
+	keys, err := client.GetSyncCommitteeDuties(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != len(committee) {
+		t.Fatalf("Expected %d keys, but got %d", len(committee), len(keys))
+	}
+	for i, index := range committee {
+		if keys[i] != "0xpubkey"+index {
+			t.Errorf("Expected key %d to be 0xpubkey%s, but got %s", i, index, keys[i])
+		}
+	}
+	// 16 and 8 are rejected, then the committee is fetched in batches of 4
+	expected := []int{16, 8, 4, 4, 4, 4, 4}
+	if len(requests.batchSizes) != len(expected) {
+		t.Fatalf("Expected batch sizes %v, but got %v", expected, requests.batchSizes)
+	}
+	for i := range expected {
+		if requests.batchSizes[i] != expected[i] {
+			t.Fatalf("Expected batch sizes %v, but got %v", expected, requests.batchSizes)
+		}
+	}
+}
+
+func TestValidatorBatchesStayWithinMaxURLLength(t *testing.T) {
+	var pubKeys []string
+	for i := 0; i < 10; i++ {
+		pubKeys = append(pubKeys, fmt.Sprintf("0x%096x", i))
+	}
+	requests := &validatorsRequestLog{}
+	var mu sync.Mutex
+	var urlLengths []int
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		ids := req.URL.Query()["id"]
+		requests.record(len(ids))
+		mu.Lock()
+		urlLengths = append(urlLengths, len("http://"+req.Host+req.RequestURI))
+		mu.Unlock()
+		var data []map[string]interface{}
+		for _, id := range ids {
+			data = append(data, map[string]interface{}{
+				"index":     strconv.Itoa(slices.Index(pubKeys, id)),
+				"validator": map[string]string{"pubkey": id},
+			})
+		}
+		_ = json.NewEncoder(rw).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	// the count limit allows a single batch, but only 3 pubkeys fit the url length
+	maxLength := len(server.URL+"/eth/v1/beacon/states/4700013/validators?") + 3*len("id="+pubKeys[0]) + 2
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithValidatorBatchSize(64), src.WithMaxURLLength(maxLength))
+
+	indexes, unknown, err := client.ResolveValidatorIndexes(context.Background(), "4700013", pubKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != len(pubKeys) || len(unknown) != 0 {
+		t.Errorf("Expected all %d pubkeys to resolve, but got %d and %d unknown", len(pubKeys), len(indexes), len(unknown))
+	}
+	slices.Sort(requests.batchSizes)
+	if !slices.Equal(requests.batchSizes, []int{1, 3, 3, 3}) {
+		t.Errorf("Expected batches of at most 3 pubkeys, but got %v", requests.batchSizes)
+	}
+	for _, length := range urlLengths {
+		if length > maxLength {
+			t.Errorf("Expected urls of at most %d characters, but got %d", maxLength, length)
+		}
+	}
+}
+
+func TestValidatorIndexesHandler(t *testing.T) {
+	knownPubKey := "0x" + strings.Repeat("ab", 48)
+	unknownPubKey := "0x" + strings.Repeat("cd", 48)
+	r := mux.NewRouter()
+	r.HandleFunc("/eth/v1/beacon/states/{slotId}/validators", func(rw http.ResponseWriter, req *http.Request) {
+		if mux.Vars(req)["slotId"] != "4700013" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var data []map[string]interface{}
+		for _, id := range req.URL.Query()["id"] {
+			if id == knownPubKey {
+				data = append(data, map[string]interface{}{
+					"index":     "42",
+					"validator": map[string]string{"pubkey": knownPubKey},
+				})
+			}
+		}
+		_ = json.NewEncoder(rw).Encode(map[string]interface{}{"data": data})
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 100)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/validators/indexes", src.GetValidatorIndexesHandler(client))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"pubkeys": []string{knownPubKey, unknownPubKey},
+		"slot":    "4700013",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/validators/indexes", bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response struct {
+		Indexes map[string]string `json:"indexes"`
+		Unknown []string          `json:"unknown"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Indexes) != 1 || response.Indexes[knownPubKey] != "42" {
+		t.Errorf("Expected only the known pubkey to resolve to 42, but got %v", response.Indexes)
+	}
+	if len(response.Unknown) != 1 || response.Unknown[0] != unknownPubKey {
+		t.Errorf("Expected the unknown pubkey to be flagged, but got %v", response.Unknown)
+	}
+}
+
+func TestValidatorIndexesHandlerRejectsInvalidPubKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	parsedUrl, _ := url.Parse("http://127.0.0.1:1")
+	router.POST("/validators/indexes", src.GetValidatorIndexesHandler(src.NewWeb3Client(parsedUrl, 100)))
+	req := httptest.NewRequest(http.MethodPost, "/validators/indexes", bytes.NewReader([]byte(`{"pubkeys": ["0x01"]}`)))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, but got %d", recorder.Code)
+	}
+}
+
+func TestSyncDutiesRejectsPartialContent(t *testing.T) {
+	r := mux.NewRouter()
+	r.HandleFunc("/eth/v1/beacon/states/{slotId}/sync_committees", func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`{"data": {"validators": ["1", "2"]}}`))
+	})
+	r.HandleFunc("/eth/v1/beacon/states/{slotId}/validators", func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusPartialContent)
+		_, _ = rw.Write([]byte(`{"data": [{"index": "1", "validator": {"pubkey": "0xpubkey1"}}]}`))
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000)
+	keys, err := client.GetSyncCommitteeDuties(context.Background(), "4700013")
+	var partialContentError *src.PartialContentError
+	if !errors.As(err, &partialContentError) {
+		t.Fatalf("Expected PartialContentError, but got %v with keys %v", err, keys)
+	}
+}
+
+func TestValidatorBatchesRunInParallelUpToBurst(t *testing.T) {
+	var committee []string
+	for i := 1; i <= 16; i++ {
+		committee = append(committee, strconv.Itoa(i))
+	}
+	upstream := setupValidatorsServer(committee, 16, &validatorsRequestLog{})
+	defer upstream.Close()
+	batches := &inFlightCounter{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !strings.HasSuffix(req.URL.Path, "/validators") {
+			upstream.Config.Handler.ServeHTTP(rw, req)
+			return
+		}
+		batches.track(func() {
+			time.Sleep(50 * time.Millisecond)
+			upstream.Config.Handler.ServeHTTP(rw, req)
+		})
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithValidatorBatchSize(2), src.WithRateBurst(4),
+		src.WithRequestCosts(src.RequestCosts{src.RequestKindValidators: 1}))
+
+	keys, err := client.GetSyncCommitteeDuties(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != len(committee) {
+		t.Fatalf("Expected %d keys, but got %d", len(committee), len(keys))
+	}
+	// the first batch runs alone, the other 7 run 4 at a time
+	if batches.peak != 4 {
+		t.Errorf("Expected 4 validator batches in flight at most, but got %d", batches.peak)
+	}
+}
+
+func TestSyncDutiesBestEffortSkipsFailedBatches(t *testing.T) {
+	committee := []string{"1", "2", "3", "4", "5"}
+	upstream := setupValidatorsServer(committee, 10, &validatorsRequestLog{})
+	defer upstream.Close()
+	// the batch holding validator 3 fails
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, "/validators") && slices.Contains(req.URL.Query()["id"], "3") {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		upstream.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithValidatorBatchSize(2))
+
+	if _, err := client.GetSyncCommitteeDuties(context.Background(), "4700013"); err == nil {
+		t.Error("Expected the strict lookup to fail")
+	}
+	duties, err := client.GetSyncCommitteeDutiesBestEffort(context.Background(), "4700013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(duties.Pubkeys, []string{"0xpubkey1", "0xpubkey2", "0xpubkey5"}) {
+		t.Errorf("Expected the pubkeys of the other batches, but got %v", duties.Pubkeys)
+	}
+	if !slices.Equal(duties.Missing, []string{"3", "4"}) {
+		t.Errorf("Expected validators 3 and 4 to be missing, but got %v", duties.Missing)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/syncduties/:slotId", src.GetSyncDutiesHandler(client))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/syncduties/4700013?partial=true", nil))
+	expected := `{"pubkeys":["0xpubkey1","0xpubkey2","0xpubkey5"],"missing":["3","4"]}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestSyncDutiesHandlerLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var committee []string
+	for i := 1; i <= 20; i++ {
+		committee = append(committee, strconv.Itoa(i))
+	}
+	server := setupValidatorsServer(committee, 64, &validatorsRequestLog{})
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/syncduties/:slotId", src.GetSyncDutiesHandler(src.NewWeb3Client(parsedUrl, 1000)))
+
+	recorder := performRequest(router, "/syncduties/4700013?limit=3")
+	expected := `["0xpubkey1","0xpubkey2","0xpubkey3"]`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("Expected the first 3 pubkeys %s, but got %d %s", expected, recorder.Code, recorder.Body.String())
+	}
+
+	sample := func(seed string) []string {
+		recorder := performRequest(router, "/syncduties/4700013?limit=5&sample=random&seed="+seed)
+		var pubKeys []string
+		if err := json.Unmarshal(recorder.Body.Bytes(), &pubKeys); recorder.Code != http.StatusOK || err != nil {
+			t.Fatalf("Expected a sample, but got %d %s", recorder.Code, recorder.Body.String())
+		}
+		if recorder.Header().Get("X-Sample-Seed") != seed {
+			t.Errorf("Expected the seed %s to be reported, but got %q", seed, recorder.Header().Get("X-Sample-Seed"))
+		}
+		return pubKeys
+	}
+	first, second, other := sample("42"), sample("42"), sample("7")
+	if len(first) != 5 || !slices.Equal(first, second) {
+		t.Errorf("Expected the same 5 pubkeys for the same seed, but got %v and %v", first, second)
+	}
+	if slices.Equal(first, other) {
+		t.Errorf("Expected another sample for another seed, but got %v twice", first)
+	}
+	if !slices.IsSortedFunc(first, func(a, b string) int {
+		return slices.Index(committee, strings.TrimPrefix(a, "0xpubkey")) - slices.Index(committee, strings.TrimPrefix(b, "0xpubkey"))
+	}) {
+		t.Errorf("Expected the sample to keep the committee order, but got %v", first)
+	}
+
+	for _, query := range []string{"limit=0", "limit=513", "limit=abc", "sample=random", "limit=3&sample=first", "limit=3&sample=random&seed=x"} {
+		if recorder := performRequest(router, "/syncduties/4700013?"+query); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, but got %d", query, recorder.Code)
+		}
+	}
+}
+
+func TestSyncDutiesHandlerSeedsSampleFromClock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var committee []string
+	for i := 1; i <= 20; i++ {
+		committee = append(committee, strconv.Itoa(i))
+	}
+	server := setupValidatorsServer(committee, 64, &validatorsRequestLog{})
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	clock := src.NewFakeClock(time.Now())
+	router := gin.New()
+	router.GET("/syncduties/:slotId", src.GetSyncDutiesHandler(src.NewWeb3Client(parsedUrl, 1000, src.WithClock(clock))))
+
+	recorder := performRequest(router, "/syncduties/4700013?limit=5&sample=random")
+	if seed := strconv.FormatInt(clock.Now().UnixNano(), 10); recorder.Code != http.StatusOK || recorder.Header().Get("X-Sample-Seed") != seed {
+		t.Errorf("Expected the seed %s of the client clock, but got %d %q", seed, recorder.Code, recorder.Header().Get("X-Sample-Seed"))
+	}
+}
+
+func TestSyncDutiesHandlerGroupsSubcommittees(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var committee []string
+	for i := 0; i < 512; i++ {
+		committee = append(committee, strconv.Itoa(i))
+	}
+	server := setupValidatorsServer(committee, 64, &validatorsRequestLog{})
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/syncduties/:slotId", src.GetSyncDutiesHandler(src.NewWeb3Client(parsedUrl, 1000, src.WithValidatorBatchSize(64))))
+
+	recorder := performRequest(router, "/syncduties/4700013?group=subcommittee")
+	var subcommittees []src.SyncSubcommittee
+	if err := json.Unmarshal(recorder.Body.Bytes(), &subcommittees); recorder.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected the subcommittees, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if len(subcommittees) != src.SyncSubcommitteeCount {
+		t.Fatalf("Expected %d subcommittees, but got %d", src.SyncSubcommitteeCount, len(subcommittees))
+	}
+	for i, subcommittee := range subcommittees {
+		if subcommittee.Subcommittee != i || len(subcommittee.Pubkeys) != 128 {
+			t.Fatalf("Expected subcommittee %d of 128 members, but got %d of %d", i, subcommittee.Subcommittee, len(subcommittee.Pubkeys))
+		}
+		for position, pubKey := range subcommittee.Pubkeys {
+			if expected := "0xpubkey" + strconv.Itoa(i*128+position); pubKey != expected {
+				t.Fatalf("Expected %s at position %d of subcommittee %d, but got %s", expected, position, i, pubKey)
+			}
+		}
+	}
+
+	for _, query := range []string{"group=committee", "group=subcommittee&partial=true", "group=subcommittee&limit=3"} {
+		if recorder := performRequest(router, "/syncduties/4700013?"+query); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, but got %d", query, recorder.Code)
+		}
+	}
+}
+
+func TestGroupSyncSubcommitteesRejectsUnevenCommittee(t *testing.T) {
+	if _, err := src.GroupSyncSubcommittees(make([]string, 510), 510); err == nil {
+		t.Errorf("Expected an error for a committee of 510 members")
+	}
+	// short by a multiple of the subcommittee count, every later member would move
+	if _, err := src.GroupSyncSubcommittees(make([]string, 508), 512); err == nil {
+		t.Errorf("Expected an error for a committee missing 4 of its 512 members")
+	}
+}
+
+func TestSyncDutiesHandlerDoesNotGroupUnresolvedMembers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var committee []string
+	for i := 0; i < 512; i++ {
+		committee = append(committee, strconv.Itoa(i))
+	}
+	validators := setupValidatorsServer(committee, 64, &validatorsRequestLog{})
+	defer validators.Close()
+	// the beacon node does not know validator 7
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, "/validators") {
+			query := req.URL.Query()
+			query["id"] = slices.DeleteFunc(query["id"], func(id string) bool { return id == "7" })
+			req.URL.RawQuery = query.Encode()
+		}
+		validators.Config.Handler.ServeHTTP(rw, req)
+	}))
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	router := gin.New()
+	router.GET("/syncduties/:slotId", src.GetSyncDutiesHandler(src.NewWeb3Client(parsedUrl, 1000, src.WithValidatorBatchSize(64))))
+
+	recorder := performRequest(router, "/syncduties/4700013?group=subcommittee")
+	var duties src.SyncDuties
+	if err := json.Unmarshal(recorder.Body.Bytes(), &duties); recorder.Code != http.StatusPartialContent || err != nil {
+		t.Fatalf("Expected the partial committee, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if !slices.Equal(duties.Missing, []string{"7"}) || len(duties.Pubkeys) != 511 {
+		t.Errorf("Expected validator 7 to be missing from 511 pubkeys, but got %v and %d pubkeys", duties.Missing, len(duties.Pubkeys))
+	}
+	// the plain lookup caches the committee without the unknown member, it is still not split
+	if recorder := performRequest(router, "/syncduties/4700013"); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the committee, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := performRequest(router, "/syncduties/4700013?group=subcommittee"); recorder.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502 for the cached committee of 511 members, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
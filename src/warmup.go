@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"strconv"
+)
+
+// WarmUp computes the rewards of the last count slots up to the head, so that the first queries
+// for recent slots are served from the cache. Slots before the merge are left out. The rewards are
+// computed like a batch, batchConcurrency slots at a time and through the rate limiter, and the
+// number of slots with a reward is returned. Cancelling ctx stops the warmup.
+func (c *Web3Client) WarmUp(ctx context.Context, count uint64) (int, error) {
+	head, err := c.getCurrentSlotId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	to := head.Uint64()
+	if to < c.mergeSlot || count == 0 {
+		return 0, nil
+	}
+	from := to - min(count, to-c.mergeSlot+1) + 1
+	slotIds := make([]string, 0, to-from+1)
+	for slot := from; slot <= to; slot++ {
+		slotIds = append(slotIds, strconv.FormatUint(slot, 10))
+	}
+	warmed := 0
+	err = c.StreamBlockRewards(ctx, slotIds, func(reward SlotReward) error {
+		if reward.Error == "" {
+			warmed++
+		}
+		return ctx.Err()
+	})
+	return warmed, err
+}
+
+// prefetchNextReward computes the reward of the slot after slotId in the background when prefetch
+// is enabled, so a client polling sequential slots finds it in the cache. Slots past the clock and
+// slots already cached are skipped. The prefetch goes through the rate limiter like any request,
+// and only one runs at a time, so prefetching adds at most one computation next to the live ones.
+func (c *Web3Client) prefetchNextReward(slotId string) {
+	if !c.prefetch {
+		return
+	}
+	slot, err := c.parseSlotId(slotId)
+	if err != nil {
+		return
+	}
+	next := new(big.Int).Add(slot, big.NewInt(1))
+	if next.Cmp(new(big.Int).SetUint64(c.clockSlot(c.clock.Now()))) == 1 {
+		return
+	}
+	nextId := next.String()
+	if _, ok := c.cache.Get(context.Background(), rewardCacheKey(nextId)); ok {
+		return
+	}
+	if !c.prefetching.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer c.prefetching.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultRequestTimeout)
+		defer cancel()
+		if reward := c.sharedSlotReward(ctx, nextId); reward.Error != "" {
+			log.Debug().Str("slotId", nextId).Str("error", reward.Error).Msg("can not prefetch reward")
+		}
+	}()
+}
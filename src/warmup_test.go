@@ -0,0 +1,91 @@
+package main_test
+
+import (
+	"context"
+	src "github.com/bilbeyt/staking_facilities_assignment"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestWarmUpCachesRecentSlots(t *testing.T) {
+	server := setupRangeServer(t, "vanilla", "4700014")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	cache := src.NewMemoryCache()
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithCache(cache))
+
+	// the head is 4700015, so the last 2 slots are 4700014, which was missed, and 4700015
+	warmed, err := client.WarmUp(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warmed != 1 {
+		t.Errorf("Expected 1 warmed slot, but got %d", warmed)
+	}
+	for slot, cached := range map[string]bool{"4700013": false, "4700014": false, "4700015": true} {
+		if _, ok := cache.Get(context.Background(), "reward:"+slot); ok != cached {
+			t.Errorf("Expected slot %s to be cached %v, but got %v", slot, cached, ok)
+		}
+	}
+
+	// a count reaching past the merge stops at the merge slot
+	warmed, err = client.WarmUp(context.Background(), 100)
+	if err != nil || warmed != 2 {
+		t.Errorf("Expected the 2 slots with a block since the merge, but got %d %v", warmed, err)
+	}
+	if _, ok := cache.Get(context.Background(), "reward:4700013"); !ok {
+		t.Error("Expected the merge slot to be cached")
+	}
+}
+
+func TestPrefetchCachesNextSlot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupRangeServer(t, "vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	cache := src.NewMemoryCache()
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithCache(cache), src.WithPrefetch(true), src.WithRateBurst(100))
+	router := gin.New()
+	router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(client))
+
+	if recorder := performRequest(router, "/blockreward/4700013"); recorder.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("Expected the first slot to be computed, but got %d %s", recorder.Code, recorder.Header().Get("X-Cache"))
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := cache.Get(context.Background(), "reward:4700014"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the next slot to be prefetched")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	recorder := performRequest(router, "/blockreward/4700014")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("Expected the next slot to be a cache hit, but got %d %s", recorder.Code, recorder.Header().Get("X-Cache"))
+	}
+}
+
+func TestPrefetchSkipsFailedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := setupRangeServer(t, "vanilla")
+	defer server.Close()
+	parsedUrl, _ := url.Parse(server.URL)
+	cache := src.NewMemoryCache()
+	client := src.NewWeb3Client(parsedUrl, 1000, src.WithCache(cache), src.WithPrefetch(true), src.WithRateBurst(100))
+	router := gin.New()
+	router.GET("/blockreward/:slotId", src.GetBlockRewardHandler(client))
+
+	// the slot before the merge slot fails, its next slot must not be warmed
+	if recorder := performRequest(router, "/blockreward/4700012"); recorder.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, but got %d %s", recorder.Code, recorder.Body.String())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := cache.Get(context.Background(), "reward:4700013"); ok {
+		t.Error("Expected no prefetch after a failed request")
+	}
+}